@@ -1,8 +1,6 @@
 package buildings
 
 import (
-	"errors"
-	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -23,7 +21,7 @@ type Building interface {
 type BaseBuilding struct {
 	Name            string    `bson:"name"`
 	Level           int       `bson:"level"`
-	ConstuctionTime time.Time `bson:"constuctionTime,omitempty" json:"constuctionTime,omitempty"`
+	ConstuctionTime time.Time `bson:"constructionTime,omitempty" json:"constructionTime,omitempty"`
 	Queue           []Queue   `bson:"queue,omitempty" json:"queue,omitempty"`
 	Upkeep          int       `bson:"upkeep,omitempty" json:"upkeep,omitempty"`
 	LastUpdated     time.Time `bson:"lastUpdated,omitempty" json:"lastUpdated,omitempty"`     // Last time this building was updated
@@ -60,8 +58,8 @@ func (b BaseBuilding) GetQueue() []Queue {
 }
 
 type MineBuilding struct {
-	BaseBuilding
-	Production int `bson:"production"`
+	BaseBuilding `bson:",inline"`
+	Production   int `bson:"production"`
 }
 
 // Implement Building interface for MineBuilding
@@ -74,8 +72,8 @@ func (m MineBuilding) GetProduction() int {
 }
 
 type EnergyBuilding struct {
-	BaseBuilding
-	Production int `bson:"production"`
+	BaseBuilding `bson:",inline"`
+	Production   int `bson:"production"`
 }
 
 // Implement Building interface for EnergyBuilding
@@ -91,11 +89,29 @@ type Queue struct {
 	Action   string        `bson:"action"`
 	Start    bson.DateTime `bson:"start"`
 	Duration int           `bson:"duration"`
+	Project  string        `bson:"project,omitempty"` // Research project ID for "research" jobs
+}
+
+// ShipOrder is one batch in a shipyard's production queue. Orders are built
+// one after another; Start and End are fixed when the order is queued. The
+// paid resources are kept so a cancelled order can be refunded.
+type ShipOrder struct {
+	ID        bson.ObjectID `bson:"_id" json:"id"`
+	PlayerID  bson.ObjectID `bson:"playerId" json:"playerId"`
+	ShipType  string        `bson:"shipType" json:"shipType"`
+	Count     int           `bson:"count" json:"count"`
+	DeliverTo string        `bson:"deliverTo,omitempty" json:"deliverTo,omitempty"` // "fleet" (default) or "stack"
+	Metal     int64         `bson:"metal" json:"metal"`
+	Crystal   int64         `bson:"crystal" json:"crystal"`
+	Plasma    int64         `bson:"plasma" json:"plasma"`
+	Start     time.Time     `bson:"start" json:"start"`
+	End       time.Time     `bson:"end" json:"end"`
 }
 
 // Specialized types with interface implementation
 type ShipYard struct {
-	BaseBuilding
+	BaseBuilding `bson:",inline"`
+	Orders       []ShipOrder `bson:"orders,omitempty" json:"orders,omitempty"` // Production queue, oldest first
 }
 
 func (s ShipYard) GetType() string {
@@ -103,7 +119,7 @@ func (s ShipYard) GetType() string {
 }
 
 type ParticleAccelerator struct {
-	BaseBuilding
+	BaseBuilding `bson:",inline"`
 }
 
 func (p ParticleAccelerator) GetType() string {
@@ -111,7 +127,7 @@ func (p ParticleAccelerator) GetType() string {
 }
 
 type FusionReactor struct {
-	BaseBuilding
+	BaseBuilding `bson:",inline"`
 }
 
 func (f FusionReactor) GetType() string {
@@ -119,7 +135,7 @@ func (f FusionReactor) GetType() string {
 }
 
 type SolarFarm struct {
-	EnergyBuilding
+	EnergyBuilding `bson:",inline"`
 }
 
 func (s SolarFarm) GetType() string {
@@ -127,7 +143,7 @@ func (s SolarFarm) GetType() string {
 }
 
 type WindFarm struct {
-	EnergyBuilding
+	EnergyBuilding `bson:",inline"`
 }
 
 func (w WindFarm) GetType() string {
@@ -135,7 +151,7 @@ func (w WindFarm) GetType() string {
 }
 
 type HydroElectricDam struct {
-	EnergyBuilding
+	EnergyBuilding `bson:",inline"`
 }
 
 func (h HydroElectricDam) GetType() string {
@@ -143,7 +159,7 @@ func (h HydroElectricDam) GetType() string {
 }
 
 type Balloon struct {
-	EnergyBuilding
+	EnergyBuilding `bson:",inline"`
 }
 
 func (b Balloon) GetType() string {
@@ -151,7 +167,7 @@ func (b Balloon) GetType() string {
 }
 
 type CrystalMine struct {
-	MineBuilding
+	MineBuilding `bson:",inline"`
 }
 
 func (c CrystalMine) GetType() string {
@@ -159,141 +175,25 @@ func (c CrystalMine) GetType() string {
 }
 
 type MetalMine struct {
-	MineBuilding
+	MineBuilding `bson:",inline"`
 }
 
 func (m MetalMine) GetType() string {
 	return "metal_mine"
 }
 
-// MongoDB Helper Functions for Interface Polymorphism
+// MongoDB Helper Functions for Interface Polymorphism (see codec.go)
 
 // Helper function to create buildings from MongoDB data
 func CreateBuildingFromMongoDB(data bson.M) (Building, error) {
-	buildingType, ok := data["type"].(string)
-	if !ok {
-		return nil, errors.New("missing or invalid building type")
-	}
-
-	switch buildingType {
-	case "base":
-		var building BaseBuilding
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "solar_farm":
-		var building SolarFarm
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "wind_farm":
-		var building WindFarm
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "crystal_mine":
-		var building CrystalMine
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "metal_mine":
-		var building MetalMine
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "hydro_electric_dam":
-		var building HydroElectricDam
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "balloon":
-		var building Balloon
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "shipyard":
-		var building ShipYard
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "particle_accelerator":
-		var building ParticleAccelerator
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	case "fusion_reactor":
-		var building FusionReactor
-		dataBytes, err := bson.Marshal(data)
-		if err != nil {
-			return nil, err
-		}
-		if err := bson.Unmarshal(dataBytes, &building); err != nil {
-			return nil, err
-		}
-		return building, nil
-	default:
-		return nil, fmt.Errorf("unknown building type: %s", buildingType)
-	}
+	return DecodeBuilding(data)
 }
 
-// Helper function to convert Building interface to BSON document
-func BuildingToBSON(building Building) bson.M {
+// Helper function to convert Building interface to BSON document. A nil
+// building gives a nil document; see EncodeBuilding.
+func BuildingToBSON(building Building) (bson.M, error) {
 	if building == nil {
-		return nil
-	}
-
-	return bson.M{
-		"type":             building.GetType(),
-		"name":             building.GetName(),
-		"level":            building.GetLevel(),
-		"upkeep":           building.GetUpkeep(),
-		"production":       building.GetProduction(),
-		"constructionTime": building.GetConstructionTime(),
-		"queue":            building.GetQueue(),
+		return nil, nil
 	}
+	return EncodeBuilding(building)
 }
@@ -0,0 +1,228 @@
+package buildings
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Building codec
+//
+// Buildings are stored as flat documents: every field of the concrete struct
+// plus a "type" discriminator (GetType()) and a schema version "v". Decoding
+// looks the type up in a registry and unmarshals into a fresh value of the
+// registered Go type, so every field round-trips.
+//
+// Types outside this package register with RegisterBuildingType, passing a
+// zero value (or pointer) of their struct. Embedded BaseBuilding / Mine /
+// Energy structs must carry `bson:",inline"` to stay flat.
+//
+// Documents without "v" predate the codec and are migrated on read:
+//   - "constuctionTime" (old struct tag) becomes "constructionTime"
+//   - nested "basebuilding" / "minebuilding" / "energybuilding" documents,
+//     written before embedded structs were inlined, are flattened
+//
+// Keys already present at the top level win over migrated ones.
+
+// BuildingSchemaVersion is written to every encoded building as "v".
+const BuildingSchemaVersion = 2
+
+var (
+	ErrMissingBuildingType  = errors.New("missing or invalid building type")
+	ErrDuplicateBuilding    = errors.New("building type already registered")
+	ErrInvalidBuildingProto = errors.New("building prototype must be a struct or pointer to struct")
+)
+
+var buildingRegistry = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+}{types: make(map[string]reflect.Type)}
+
+func init() {
+	for _, proto := range []Building{
+		BaseBuilding{}, SolarFarm{}, WindFarm{}, HydroElectricDam{}, Balloon{},
+		CrystalMine{}, MetalMine{}, ShipYard{}, ParticleAccelerator{}, FusionReactor{},
+	} {
+		if err := RegisterBuildingType(proto); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterBuildingType makes proto's concrete type decodable under
+// proto.GetType(). A pointer prototype decodes to a pointer.
+func RegisterBuildingType(proto Building) error {
+	if proto == nil {
+		return ErrInvalidBuildingProto
+	}
+	t := reflect.TypeOf(proto)
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return ErrInvalidBuildingProto
+	}
+	name := proto.GetType()
+	buildingRegistry.Lock()
+	defer buildingRegistry.Unlock()
+	if _, ok := buildingRegistry.types[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateBuilding, name)
+	}
+	buildingRegistry.types[name] = t
+	return nil
+}
+
+// RegisteredBuildingTypes lists every registered type name, sorted.
+func RegisteredBuildingTypes() []string {
+	buildingRegistry.RLock()
+	defer buildingRegistry.RUnlock()
+	out := make([]string, 0, len(buildingRegistry.types))
+	for name := range buildingRegistry.types {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// newRegistered returns a zero building of a registered type.
+func newRegistered(name string) (Building, bool) {
+	buildingRegistry.RLock()
+	t, ok := buildingRegistry.types[name]
+	buildingRegistry.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if t.Kind() == reflect.Ptr {
+		return reflect.New(t.Elem()).Interface().(Building), true
+	}
+	return reflect.New(t).Elem().Interface().(Building), true
+}
+
+// EncodeBuilding converts a building to its stored document.
+func EncodeBuilding(building Building) (bson.M, error) {
+	raw, err := bson.Marshal(building)
+	if err != nil {
+		return nil, fmt.Errorf("encode building %s: %w", building.GetType(), err)
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("encode building %s: %w", building.GetType(), err)
+	}
+	doc["type"] = building.GetType()
+	doc["v"] = BuildingSchemaVersion
+	return doc, nil
+}
+
+// DecodeBuilding rebuilds a building from its stored document, migrating
+// documents written before the codec.
+func DecodeBuilding(data bson.M) (Building, error) {
+	name, ok := data["type"].(string)
+	if !ok {
+		return nil, ErrMissingBuildingType
+	}
+	proto, ok := newRegistered(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBuildingType, name)
+	}
+	raw, err := bson.Marshal(MigrateBuildingDoc(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode building %s: %w", name, err)
+	}
+	v := reflect.ValueOf(proto)
+	target := v
+	if v.Kind() != reflect.Ptr {
+		target = reflect.New(v.Type())
+	}
+	if err := bson.Unmarshal(raw, target.Interface()); err != nil {
+		return nil, fmt.Errorf("decode building %s: %w", name, err)
+	}
+	if v.Kind() != reflect.Ptr {
+		return target.Elem().Interface().(Building), nil
+	}
+	return target.Interface().(Building), nil
+}
+
+// MigrateBuildingDoc returns data upgraded to BuildingSchemaVersion. Current
+// documents are returned unchanged.
+func MigrateBuildingDoc(data bson.M) bson.M {
+	if v, ok := data["v"]; ok && toInt(v) >= BuildingSchemaVersion {
+		return data
+	}
+	out := bson.M{}
+	flattenLegacy(out, data)
+	if _, ok := out["constructionTime"]; !ok {
+		if ct, ok := out["constuctionTime"]; ok {
+			out["constructionTime"] = ct
+		}
+	}
+	delete(out, "constuctionTime")
+	out["v"] = BuildingSchemaVersion
+	return out
+}
+
+var legacyEmbedded = []string{"energybuilding", "minebuilding", "basebuilding"}
+
+// flattenLegacy copies src into dst, hoisting nested embedded documents.
+// Top-level keys are copied first so they take precedence.
+func flattenLegacy(dst bson.M, src bson.M) {
+	for k, v := range src {
+		if !isLegacyEmbedded(k) {
+			dst[k] = v
+		}
+	}
+	for _, k := range legacyEmbedded {
+		nested, ok := asM(src[k])
+		if !ok {
+			continue
+		}
+		inner := bson.M{}
+		flattenLegacy(inner, nested)
+		for ik, iv := range inner {
+			if _, exists := dst[ik]; !exists {
+				dst[ik] = iv
+			}
+		}
+	}
+}
+
+func isLegacyEmbedded(k string) bool {
+	for _, e := range legacyEmbedded {
+		if k == e {
+			return true
+		}
+	}
+	return false
+}
+
+func asM(v any) (bson.M, bool) {
+	switch d := v.(type) {
+	case bson.M:
+		return d, true
+	case bson.D:
+		m := make(bson.M, len(d))
+		for _, e := range d {
+			m[e.Key] = e.Value
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+func toInt(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package buildings
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type testDock struct {
+	BaseBuilding `bson:",inline"`
+	Berths       int `bson:"berths"`
+}
+
+func (testDock) GetType() string { return "test_dock" }
+
+func TestBuildingCodecRoundTrip(t *testing.T) {
+	at := time.Date(2025, 3, 4, 5, 6, 7, 8e6, time.UTC)
+	base := BaseBuilding{
+		Name:            "Alpha",
+		Level:           3,
+		ConstuctionTime: at,
+		Queue:           []Queue{NewUpgradeJob(at, time.Minute)},
+		Upkeep:          12,
+		LastUpdated:     at.Add(time.Second),
+		LastProcessed:   at.Add(2 * time.Second),
+	}
+	if err := RegisterBuildingType(testDock{}); err != nil && !errors.Is(err, ErrDuplicateBuilding) {
+		t.Fatal(err)
+	}
+	for _, name := range RegisteredBuildingTypes() {
+		proto, _ := newRegistered(name)
+		in := SetBase(proto, base)
+		switch v := in.(type) {
+		case MetalMine:
+			v.Production = 42
+			in = v
+		case SolarFarm:
+			v.Production = 17
+			in = v
+		case ShipYard:
+			v.Orders = []ShipOrder{{ID: bson.NewObjectID(), ShipType: "fighter", Count: 3, Start: at, End: at.Add(time.Hour)}}
+			in = v
+		case testDock:
+			v.Berths = 4
+			in = v
+		}
+		doc, err := EncodeBuilding(in)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", name, err)
+		}
+		out, err := DecodeBuilding(doc)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", name, err)
+		}
+		if !reflect.DeepEqual(in, out) {
+			t.Errorf("%s: round trip\n got %#v\nwant %#v", name, out, in)
+		}
+	}
+}
+
+func TestBuildingCodecMigratesLegacyDocs(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Written by marshalling the struct before embedded fields were inlined.
+	legacy := bson.M{
+		"type":       "metal_mine",
+		"production": 9,
+		"minebuilding": bson.D{
+			{Key: "basebuilding", Value: bson.D{
+				{Key: "name", Value: "Old"},
+				{Key: "level", Value: 2},
+				{Key: "constuctionTime", Value: at},
+			}},
+		},
+	}
+	b, err := DecodeBuilding(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mine := b.(MetalMine)
+	if mine.Name != "Old" || mine.Level != 2 || !mine.ConstuctionTime.Equal(at) || mine.Production != 9 {
+		t.Fatalf("migrated = %#v", mine)
+	}
+}
@@ -0,0 +1,99 @@
+package buildings
+
+import (
+	"math"
+	"time"
+)
+
+// Production
+//
+// Energy buildings and mines produce per hour from the tables in data.go:
+//
+//	energy = BaseEnergyOutput[kind] * PlanetSuitability[planet][kind] * (1+GrowthRate[planet][kind])^(level-1)
+//	mined  = BaseExtractionRate[mine] * ResourceSuitability[planet][resource] * (1+ExtractionGrowthRate[planet][mine])^(level-1)
+//
+// Fusion reactors turn hydrogen into plasma at FusionPlasmaPerLevel per level
+// per hour, burning HydrogenPerPlasma hydrogen for each unit; the planet's
+// hydrogen stock caps the output.
+//
+// Level 0 buildings (still under construction) and unknown planet types
+// produce nothing.
+
+// Planet types keyed in the data tables.
+const (
+	PlanetMercury = "Mercury"
+	PlanetVenus   = "Venus"
+	PlanetEarth   = "Earth"
+	PlanetMars    = "Mars"
+)
+
+const (
+	FusionPlasmaPerLevel = 5.0
+	HydrogenPerPlasma    = 4
+)
+
+// energyKinds maps energy building types to their data table key.
+var energyKinds = map[string]string{
+	"hydro_electric_dam": "Hydro",
+	"solar_farm":         "Solar",
+	"wind_farm":          "Wind",
+	"balloon":            "Balloon",
+}
+
+// mineKinds maps mine building types to their data table keys.
+var mineKinds = map[string]struct{ Mine, Resource string }{
+	"metal_mine":   {"MetalMine", "Metals"},
+	"crystal_mine": {"CrystalMine", "Crystals"},
+}
+
+// Output is an amount of produced resources. As a rate it is per hour.
+type Output struct {
+	Energy   float64 `bson:"energy,omitempty" json:"energy,omitempty"`
+	Metals   float64 `bson:"metals,omitempty" json:"metals,omitempty"`
+	Crystals float64 `bson:"crystals,omitempty" json:"crystals,omitempty"`
+	Plasma   float64 `bson:"plasma,omitempty" json:"plasma,omitempty"`
+}
+
+// Add returns o + other.
+func (o Output) Add(other Output) Output {
+	return Output{Energy: o.Energy + other.Energy, Metals: o.Metals + other.Metals, Crystals: o.Crystals + other.Crystals, Plasma: o.Plasma + other.Plasma}
+}
+
+// Scale returns o multiplied by f.
+func (o Output) Scale(f float64) Output {
+	return Output{Energy: o.Energy * f, Metals: o.Metals * f, Crystals: o.Crystals * f, Plasma: o.Plasma * f}
+}
+
+// ProductionRate returns the hourly output of building on a planet of the
+// given type.
+func ProductionRate(building Building, planetType string) Output {
+	if building == nil || building.GetLevel() < 1 {
+		return Output{}
+	}
+	growthSteps := float64(building.GetLevel() - 1)
+	if kind, ok := energyKinds[building.GetType()]; ok {
+		base := float64(BaseEnergyOutput[kind])
+		suit := PlanetSuitability[planetType][kind]
+		growth := GrowthRate[planetType][kind]
+		return Output{Energy: base * suit * math.Pow(1+growth, growthSteps)}
+	}
+	if kind, ok := mineKinds[building.GetType()]; ok {
+		base := float64(BaseExtractionRate[kind.Mine])
+		suit := ResourceSuitability[planetType][kind.Resource]
+		growth := ExtractionGrowthRate[planetType][kind.Mine]
+		rate := base * suit * math.Pow(1+growth, growthSteps)
+		if kind.Resource == "Metals" {
+			return Output{Metals: rate}
+		}
+		return Output{Crystals: rate}
+	}
+	if building.GetType() == "fusion_reactor" {
+		return Output{Plasma: FusionPlasmaPerLevel * float64(building.GetLevel())}
+	}
+	return Output{}
+}
+
+// Produced returns what rate yields over d.
+func Produced(rate Output, d time.Duration) Output {
+	return rate.Scale(d.Hours())
+}
@@ -0,0 +1,26 @@
+package buildings
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// QueueActionResearch marks a particle accelerator's research job. Project
+// holds the research project ID; Start and Duration work as for upgrades.
+const QueueActionResearch = "research"
+
+// ActiveResearch returns the building's running research job, if any.
+func ActiveResearch(building Building) (Queue, bool) {
+	for _, q := range building.GetQueue() {
+		if q.Action == QueueActionResearch {
+			return q, true
+		}
+	}
+	return Queue{}, false
+}
+
+// NewResearchJob creates the queue entry for a research project starting at now.
+func NewResearchJob(now time.Time, project string, d time.Duration) Queue {
+	return Queue{Action: QueueActionResearch, Start: bson.NewDateTimeFromTime(now), Duration: int(d / time.Second), Project: project}
+}
@@ -0,0 +1,204 @@
+package buildings
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Upgrades
+//
+// A building levels up through a single job in its Queue. The job's Action is
+// QueueActionUpgrade, Start is when it began and Duration its length in
+// seconds; the building reaches Level+1 once Start+Duration has passed. A new
+// building is created at level 0 with a job towards level 1.
+//
+// Costs and times grow geometrically with the target level:
+//
+//	cost(level) = BaseCost * Growth^(level-1)
+
+const QueueActionUpgrade = "upgrade"
+
+// MaxLevel is the highest level any building can reach.
+const MaxLevel = 20
+
+// UpgradeCurve holds a building type's level-1 price and per-level growth.
+type UpgradeCurve struct {
+	Metal   int64
+	Crystal int64
+	Seconds int
+	Growth  float64
+}
+
+// UpgradeCurves is keyed by GetType().
+var UpgradeCurves = map[string]UpgradeCurve{
+	"solar_farm":           {Metal: 60, Crystal: 40, Seconds: 120, Growth: 1.5},
+	"wind_farm":            {Metal: 50, Crystal: 30, Seconds: 100, Growth: 1.5},
+	"hydro_electric_dam":   {Metal: 90, Crystal: 50, Seconds: 180, Growth: 1.5},
+	"balloon":              {Metal: 40, Crystal: 40, Seconds: 90, Growth: 1.5},
+	"metal_mine":           {Metal: 60, Crystal: 15, Seconds: 120, Growth: 1.5},
+	"crystal_mine":         {Metal: 50, Crystal: 25, Seconds: 150, Growth: 1.6},
+	"shipyard":             {Metal: 400, Crystal: 200, Seconds: 600, Growth: 1.8},
+	"particle_accelerator": {Metal: 600, Crystal: 800, Seconds: 900, Growth: 1.8},
+	"fusion_reactor":       {Metal: 800, Crystal: 500, Seconds: 900, Growth: 1.8},
+}
+
+var (
+	ErrUnknownBuildingType = errors.New("unknown building type")
+	ErrMaxLevel            = errors.New("building is at max level")
+)
+
+// UpgradeCost returns the price and duration of raising a building of the
+// given type to toLevel.
+func UpgradeCost(buildingType string, toLevel int) (metal, crystal int64, d time.Duration, err error) {
+	c, ok := UpgradeCurves[buildingType]
+	if !ok {
+		return 0, 0, 0, ErrUnknownBuildingType
+	}
+	if toLevel < 1 || toLevel > MaxLevel {
+		return 0, 0, 0, ErrMaxLevel
+	}
+	f := math.Pow(c.Growth, float64(toLevel-1))
+	metal = int64(math.Round(float64(c.Metal) * f))
+	crystal = int64(math.Round(float64(c.Crystal) * f))
+	d = time.Duration(math.Round(float64(c.Seconds)*f)) * time.Second
+	return metal, crystal, d, nil
+}
+
+// NewBuilding returns a level 0 building of any registered type.
+func NewBuilding(buildingType, name string) (Building, error) {
+	proto, ok := newRegistered(buildingType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBuildingType, buildingType)
+	}
+	return SetBase(proto, BaseBuilding{Name: name}), nil
+}
+
+// Base returns the BaseBuilding embedded in any building.
+func Base(building Building) BaseBuilding {
+	switch v := building.(type) {
+	case BaseBuilding:
+		return v
+	case MineBuilding:
+		return v.BaseBuilding
+	case EnergyBuilding:
+		return v.BaseBuilding
+	case SolarFarm:
+		return v.BaseBuilding
+	case WindFarm:
+		return v.BaseBuilding
+	case HydroElectricDam:
+		return v.BaseBuilding
+	case Balloon:
+		return v.BaseBuilding
+	case MetalMine:
+		return v.BaseBuilding
+	case CrystalMine:
+		return v.BaseBuilding
+	case ShipYard:
+		return v.BaseBuilding
+	case ParticleAccelerator:
+		return v.BaseBuilding
+	case FusionReactor:
+		return v.BaseBuilding
+	}
+	// Types registered from outside the package.
+	if f := baseField(reflect.Indirect(reflect.ValueOf(building))); f.IsValid() {
+		return f.Interface().(BaseBuilding)
+	}
+	return BaseBuilding{}
+}
+
+// SetBase returns a copy of building with its BaseBuilding replaced. Buildings
+// are stored by value, so this is how shared fields (Level, Queue, ...) change.
+func SetBase(building Building, base BaseBuilding) Building {
+	switch v := building.(type) {
+	case BaseBuilding:
+		return base
+	case MineBuilding:
+		v.BaseBuilding = base
+		return v
+	case EnergyBuilding:
+		v.BaseBuilding = base
+		return v
+	case SolarFarm:
+		v.BaseBuilding = base
+		return v
+	case WindFarm:
+		v.BaseBuilding = base
+		return v
+	case HydroElectricDam:
+		v.BaseBuilding = base
+		return v
+	case Balloon:
+		v.BaseBuilding = base
+		return v
+	case MetalMine:
+		v.BaseBuilding = base
+		return v
+	case CrystalMine:
+		v.BaseBuilding = base
+		return v
+	case ShipYard:
+		v.BaseBuilding = base
+		return v
+	case ParticleAccelerator:
+		v.BaseBuilding = base
+		return v
+	case FusionReactor:
+		v.BaseBuilding = base
+		return v
+	}
+	// Types registered from outside the package: pointers are updated in
+	// place, values are copied.
+	rv := reflect.ValueOf(building)
+	if rv.Kind() == reflect.Ptr {
+		if f := baseField(rv.Elem()); f.IsValid() && f.CanSet() {
+			f.Set(reflect.ValueOf(base))
+		}
+		return building
+	}
+	cp := reflect.New(rv.Type()).Elem()
+	cp.Set(rv)
+	if f := baseField(cp); f.IsValid() && f.CanSet() {
+		f.Set(reflect.ValueOf(base))
+		return cp.Interface().(Building)
+	}
+	return building
+}
+
+// baseField finds the (possibly promoted) BaseBuilding field of a struct.
+func baseField(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	f := v.FieldByName("BaseBuilding")
+	if f.IsValid() && f.Type() == reflect.TypeOf(BaseBuilding{}) {
+		return f
+	}
+	return reflect.Value{}
+}
+
+// ActiveUpgrade returns the building's running upgrade job, if any.
+func ActiveUpgrade(building Building) (Queue, bool) {
+	for _, q := range building.GetQueue() {
+		if q.Action == QueueActionUpgrade {
+			return q, true
+		}
+	}
+	return Queue{}, false
+}
+
+// NewUpgradeJob creates the queue entry for an upgrade starting at now.
+func NewUpgradeJob(now time.Time, d time.Duration) Queue {
+	return Queue{Action: QueueActionUpgrade, Start: bson.NewDateTimeFromTime(now), Duration: int(d / time.Second)}
+}
+
+// End returns when a queue job finishes.
+func (q Queue) End() time.Time {
+	return q.Start.Time().Add(time.Duration(q.Duration) * time.Second)
+}
@@ -0,0 +1,80 @@
+package buildings
+
+// Upkeep and brownouts
+//
+// Buildings other than power plants draw energy every hour. When a player's
+// energy runs out, buildings are shed in priority order, least essential
+// first. A planet's brownout level says how far down the list it is:
+//
+//	1 BrownoutResearch  particle accelerators offline
+//	2 BrownoutShipyard  shipyards offline (no new orders, deliveries held)
+//	3 BrownoutReactor   fusion reactors offline
+//	4 BrownoutMining    mines run at BrownoutMineFactor output and upkeep
+//
+// Every level includes the ones before it.
+
+const (
+	BrownoutNone = iota
+	BrownoutResearch
+	BrownoutShipyard
+	BrownoutReactor
+	BrownoutMining
+)
+
+// BrownoutMineFactor scales mine output and upkeep at BrownoutMining.
+const BrownoutMineFactor = 0.5
+
+// BaseUpkeep is the hourly energy upkeep per building level, by GetType().
+var BaseUpkeep = map[string]int{
+	"metal_mine":           5,
+	"crystal_mine":         6,
+	"shipyard":             20,
+	"particle_accelerator": 30,
+	"fusion_reactor":       15,
+}
+
+// brownoutTiers maps building types to the brownout level that sheds them.
+var brownoutTiers = map[string]int{
+	"particle_accelerator": BrownoutResearch,
+	"shipyard":             BrownoutShipyard,
+	"fusion_reactor":       BrownoutReactor,
+	"metal_mine":           BrownoutMining,
+	"crystal_mine":         BrownoutMining,
+}
+
+// BrownoutTier returns the brownout level at which a building type is shed,
+// or BrownoutNone for buildings that are never shed.
+func BrownoutTier(buildingType string) int {
+	return brownoutTiers[buildingType]
+}
+
+// UpkeepFor returns the table upkeep of a building type at a level.
+func UpkeepFor(buildingType string, level int) int {
+	return BaseUpkeep[buildingType] * level
+}
+
+// UpkeepRate returns a building's hourly upkeep: its stored Upkeep, or the
+// table value for its level when none is stored.
+func UpkeepRate(building Building) int {
+	if building == nil {
+		return 0
+	}
+	if u := building.GetUpkeep(); u > 0 {
+		return u
+	}
+	return UpkeepFor(building.GetType(), building.GetLevel())
+}
+
+// PowerFactor is how much of its output and upkeep a building of the given
+// type keeps under a brownout level: 1 when unaffected, 0 when offline.
+func PowerFactor(buildingType string, brownout int) float64 {
+	tier := BrownoutTier(buildingType)
+	switch {
+	case tier == BrownoutNone || brownout < tier:
+		return 1
+	case tier == BrownoutMining:
+		return BrownoutMineFactor
+	default:
+		return 0
+	}
+}
@@ -85,15 +85,19 @@ func PopulateStackBioForPath(stack *ships.ShipStack, path ships.BioTreePath, now
 			rn.WithPassive(passive)
 		}
 		for _, ce := range bn.ComplexEffects {
-			if ce.PrimaryEffect != nil && ce.Duration > 0 {
+			invisible := grantsInvisibility(ce)
+			if (ce.PrimaryEffect != nil || invisible) && ce.Duration > 0 {
 				dur := time.Duration(ce.Duration) * time.Second
 				cd := time.Duration(ce.Cooldown) * time.Second
-				rn.WithTriggered(*ce.PrimaryEffect, dur, cd)
+				rn.WithTriggered(triggeredMods(ce), dur, cd)
+			}
+			if invisible {
+				rn.WithInvisibility()
 			}
 			
 			// Store trigger information for event-driven activation
 			if ce.Trigger != "" {
-				_ = ce.Trigger // Placeholder for future trigger system integration
+				rn.WithTrigger(string(ce.Trigger))
 			}
 		}
 	}
@@ -138,19 +142,22 @@ func PopulateStackBioFromPath(stack *ships.ShipStack, now time.Time) {
 
 		// Map ComplexEffects with trigger information for event-driven activation
 		for _, ce := range bn.ComplexEffects {
-			if ce.PrimaryEffect != nil && ce.Duration > 0 {
+			invisible := grantsInvisibility(ce)
+			if (ce.PrimaryEffect != nil || invisible) && ce.Duration > 0 {
 				// Interpret tree durations in seconds for now
 				dur := time.Duration(ce.Duration) * time.Second
 				cd := time.Duration(ce.Cooldown) * time.Second
-				rn.WithTriggered(*ce.PrimaryEffect, dur, cd)
+				rn.WithTriggered(triggeredMods(ce), dur, cd)
+			}
+			// StatusInvisible cloaks the stack for the triggered stage
+			if invisible {
+				rn.WithInvisibility()
 			}
 			
 			// Store trigger information for event-driven activation
-			// This allows the bio machine to respond to specific game events
+			// (BioMachine.OnTrigger starts the triggered stage on that event)
 			if ce.Trigger != "" {
-				// Store trigger info in the node's metadata for event handling
-				// The actual trigger logic will be implemented in the bio machine
-				_ = ce.Trigger // Placeholder for future trigger system integration
+				rn.WithTrigger(string(ce.Trigger))
 			}
 		}
 
@@ -158,6 +165,24 @@ func PopulateStackBioFromPath(stack *ships.ShipStack, now time.Time) {
 	}
 }
 
+// grantsInvisibility reports whether the effect applies StatusInvisible.
+func grantsInvisibility(ce ComplexEffect) bool {
+	for _, se := range ce.StatusEffects {
+		if se.EffectType == StatusInvisible {
+			return true
+		}
+	}
+	return false
+}
+
+// triggeredMods returns the effect's primary mods, or none for status-only effects.
+func triggeredMods(ce ComplexEffect) ships.StatMods {
+	if ce.PrimaryEffect == nil {
+		return ships.StatMods{}
+	}
+	return *ce.PrimaryEffect
+}
+
 // isZeroMods is a local copy to avoid exporting internals from ships.
 func isZeroMods(m ships.StatMods) bool {
 	if m.Damage.LaserPct != 0 || m.Damage.NuclearPct != 0 || m.Damage.AntimatterPct != 0 {
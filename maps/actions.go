@@ -0,0 +1,212 @@
+package maps
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Queue action types.
+const (
+	ActionShipAttack           = "ship_attack"
+	ActionShipConstruction     = "ship_construction"
+	ActionBuildingConstruction = "building_construction"
+	ActionShipAbility          = "ship_ability"
+	ActionFormationChange      = "formation_change"
+	ActionGemSocketChange      = "gem_socket_change"
+	ActionRoleSwitch           = "role_switch"
+	ActionResearch             = "research"
+	ActionAnchor               = "anchor"
+	ActionEmbark               = "embark"
+)
+
+// AttackOrder is the payload of a ship_attack action. The attacking stack is
+// Queue.SourceID and the defender is Queue.TargetID.
+type AttackOrder struct {
+	TargetType string `bson:"targetType" json:"targetType"` // "stack"
+}
+
+// ShipBuildOrder is the payload of a ship_construction action. Queue.SourceID
+// is the system whose planet hosts the shipyard. With Cancel set the queued
+// order OrderID is cancelled instead and the other fields are ignored.
+type ShipBuildOrder struct {
+	ShipType  string        `bson:"shipType" json:"shipType"`
+	Count     int           `bson:"count" json:"count"`
+	DeliverTo string        `bson:"deliverTo,omitempty" json:"deliverTo,omitempty"` // "fleet" (default) or "stack"
+	Cancel    bool          `bson:"cancel,omitempty" json:"cancel,omitempty"`
+	OrderID   bson.ObjectID `bson:"orderId,omitempty" json:"orderId,omitempty"` // Required when cancelling
+}
+
+// BuildingOrder is the payload of a building_construction action. Queue.SourceID
+// is the system whose planet owns the slot.
+type BuildingOrder struct {
+	Slot         string `bson:"slot" json:"slot"`
+	BuildingType string `bson:"buildingType,omitempty" json:"buildingType,omitempty"` // Required when the slot is empty
+	Cancel       bool   `bson:"cancel,omitempty" json:"cancel,omitempty"`
+}
+
+// ResearchOrder is the payload of a research action. Queue.SourceID is the
+// system whose planet hosts the particle accelerator.
+type ResearchOrder struct {
+	Project string `bson:"project,omitempty" json:"project,omitempty"` // Required unless cancelling
+	Cancel  bool   `bson:"cancel,omitempty" json:"cancel,omitempty"`
+}
+
+// AnchorOrder is the payload of an anchor action. Queue.SourceID is the stack
+// and, unless releasing, Queue.TargetID the orbitable to anchor onto.
+type AnchorOrder struct {
+	TargetType string `bson:"targetType,omitempty" json:"targetType,omitempty"` // "asteroid", "nebula", "system" or "salvage"; required unless releasing
+	Release    bool   `bson:"release,omitempty" json:"release,omitempty"`
+}
+
+// AbilityCast is the payload of a ship_ability action. Queue.SourceID is the
+// casting stack; Queue.TargetID / TargetX / TargetY are used by targeted abilities.
+type AbilityCast struct {
+	ShipType string `bson:"shipType" json:"shipType"`
+	Ability  string `bson:"ability" json:"ability"`
+}
+
+// FormationChange is the payload of a formation_change action on the
+// Queue.SourceID stack.
+type FormationChange struct {
+	Formation string `bson:"formation" json:"formation"`
+}
+
+// GemSocketChange is the payload of a gem_socket_change action. Sockets lists
+// GemCatalog IDs in socket order and replaces the ship type's loadout.
+type GemSocketChange struct {
+	ShipType string   `bson:"shipType" json:"shipType"`
+	Sockets  []string `bson:"sockets" json:"sockets"`
+}
+
+// RoleSwitch is the payload of a role_switch action on the Queue.SourceID stack.
+type RoleSwitch struct {
+	Role string `bson:"role" json:"role"`
+}
+
+// EmbarkOrder is the payload of an embark action: Count ships of ShipType
+// board the carriers of the Queue.SourceID stack.
+type EmbarkOrder struct {
+	ShipType string `bson:"shipType" json:"shipType"`
+	Count    int    `bson:"count" json:"count"`
+}
+
+// Response payloads. Each answers the action type of the same name.
+
+// AttackResult is the response payload of a ship_attack action.
+type AttackResult struct {
+	BattleID string `bson:"battleId" json:"battleId"`
+}
+
+// ShipBuildResult is the response payload of a ship_construction action.
+type ShipBuildResult struct {
+	OrderID   bson.ObjectID `bson:"orderId" json:"orderId"`
+	Start     time.Time     `bson:"start" json:"start"`
+	Cancelled bool          `bson:"cancelled" json:"cancelled"`
+}
+
+// BuildingResult is the response payload of a building_construction action.
+type BuildingResult struct {
+	Slot      string `bson:"slot" json:"slot"`
+	Cancelled bool   `bson:"cancelled" json:"cancelled"`
+}
+
+// ResearchResult is the response payload of a research action.
+type ResearchResult struct {
+	Project   string `bson:"project" json:"project"`
+	Cancelled bool   `bson:"cancelled" json:"cancelled"`
+}
+
+// AnchorResult is the response payload of an anchor action.
+type AnchorResult struct {
+	Release bool `bson:"release" json:"release"`
+}
+
+func (*AttackResult) ActionType() string    { return ActionShipAttack }
+func (*ShipBuildResult) ActionType() string { return ActionShipConstruction }
+func (*BuildingResult) ActionType() string  { return ActionBuildingConstruction }
+func (*ResearchResult) ActionType() string  { return ActionResearch }
+func (*AnchorResult) ActionType() string    { return ActionAnchor }
+
+// UnmarshalBSON decodes the payload into the struct registered for Type.
+func (q *Queue) UnmarshalBSON(data []byte) error {
+	type plain Queue
+	var doc struct {
+		Fields  plain    `bson:",inline"`
+		Payload bson.Raw `bson:"payload,omitempty"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*q = Queue(doc.Fields)
+	if len(doc.Payload) == 0 {
+		return nil
+	}
+	p, err := NewPayload(q.Type)
+	if err != nil {
+		return err
+	}
+	if err := bson.Unmarshal(doc.Payload, p); err != nil {
+		return fmt.Errorf("decode %s payload: %w", q.Type, err)
+	}
+	q.Payload = p
+	return nil
+}
+
+// UnmarshalJSON decodes a client request, picking the payload struct by Type.
+func (q *Queue) UnmarshalJSON(data []byte) error {
+	type plain Queue
+	var doc struct {
+		plain
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*q = Queue(doc.plain)
+	if len(doc.Payload) == 0 || string(doc.Payload) == "null" {
+		return nil
+	}
+	p, err := NewPayload(q.Type)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(doc.Payload, p); err != nil {
+		return fmt.Errorf("decode %s payload: %w", q.Type, err)
+	}
+	q.Payload = p
+	return nil
+}
+
+// UnmarshalBSON decodes the payload into the result struct registered for Type.
+func (r *ResponseQueue) UnmarshalBSON(data []byte) error {
+	type plain ResponseQueue
+	var doc struct {
+		Fields  plain    `bson:",inline"`
+		Payload bson.Raw `bson:"payload,omitempty"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*r = ResponseQueue(doc.Fields)
+	if len(doc.Payload) == 0 {
+		return nil
+	}
+	p, err := NewResultPayload(r.Type)
+	if err != nil {
+		return err
+	}
+	if err := bson.Unmarshal(doc.Payload, p); err != nil {
+		return fmt.Errorf("decode %s result: %w", r.Type, err)
+	}
+	r.Payload = p
+	return nil
+}
+
+// unexpectedPayload reports a queue item whose payload is not the struct its
+// handler expects.
+func unexpectedPayload(q *Queue) error {
+	return &PayloadError{Type: q.Type, Message: fmt.Sprintf("unexpected payload %T", q.Payload)}
+}
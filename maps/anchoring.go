@@ -0,0 +1,260 @@
+package maps
+
+import (
+	"context"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Anchor tick events. SubjectID is the stack and RelatedID the orbitable.
+// When an anchor holds on something the stack could not start gathering
+// from, Detail of TickEventAnchorHeld says why.
+const (
+	TickEventAnchorHeld     = "anchor_held"
+	TickEventAnchorReleased = "anchor_released"
+)
+
+// OrbitableSource is an optional GameState extension for loading asteroids
+// and nebulas. Without it stacks can only anchor onto systems.
+type OrbitableSource interface {
+	Asteroid(ctx context.Context, id bson.ObjectID) (*orbitables.Asteroid, error)
+	Nebula(ctx context.Context, id bson.ObjectID) (*orbitables.Nebula, error)
+}
+
+// AnchorHandler starts anchoring the source stack onto the target orbitable,
+// or starts releasing it.
+type AnchorHandler struct{}
+
+func (AnchorHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	order, ok := q.Payload.(*AnchorOrder)
+	if !ok {
+		return unexpectedPayload(q)
+	}
+	s, err := loadOwnedStack(ctx, gs, q)
+	if err != nil {
+		return err
+	}
+	probe := *s
+	if s.Anchor != nil {
+		anchor := *s.Anchor
+		probe.Anchor = &anchor
+	}
+	_, err = applyAnchorOrder(ctx, gs, &probe, q.TargetID, *order, now)
+	return err
+}
+
+func (AnchorHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	order, ok := q.Payload.(*AnchorOrder)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	s, err := gs.Stack(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	var target bson.ObjectID
+	if s.Anchor != nil {
+		target = s.Anchor.TargetID
+	}
+	end, err := applyAnchorOrder(ctx, gs, s, q.TargetID, *order, now)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	if err := gs.SaveStack(ctx, s); err != nil {
+		return ActionResult{}, err
+	}
+	if !order.Release {
+		target = q.TargetID
+	}
+	return ActionResult{
+		Payload:      &AnchorResult{Release: order.Release},
+		TargetX:      s.PositionX,
+		TargetY:      s.PositionY,
+		EndTime:      end,
+		Participants: []bson.ObjectID{s.ID, target},
+	}, nil
+}
+
+// applyAnchorOrder runs the order against s and returns when the new stage
+// completes. Validate runs it on a copy of the stack. Only stacks in Economic
+// role may anchor onto salvage.
+func applyAnchorOrder(ctx context.Context, gs GameState, s *ships.ShipStack, targetID bson.ObjectID, order AnchorOrder, now time.Time) (time.Time, error) {
+	if order.Release {
+		return s.StartRelease(now)
+	}
+	d, err := anchorTarget(ctx, gs, order.TargetType, targetID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if d.kind == orbitables.SalvageTargetType && !s.InRole(ships.RoleEconomic, now) {
+		return time.Time{}, orbitables.ErrNotEconomic
+	}
+	return s.StartAnchoring(targetID, d.kind, d.x, d.y, d.radius, now)
+}
+
+// anchorTarget loads the target orbitable's disc.
+func anchorTarget(ctx context.Context, gs GameState, targetType string, id bson.ObjectID) (orbitableDisc, error) {
+	if targetType == "system" {
+		sys, err := gs.System(ctx, id)
+		if err != nil {
+			return orbitableDisc{}, err
+		}
+		return orbitableDisc{sys.ID, "system", sys.X, sys.Y, sys.CollisionRadius}, nil
+	}
+	if targetType == orbitables.SalvageTargetType {
+		src, ok := gs.(SalvageSource)
+		if !ok {
+			return orbitableDisc{}, ErrInvalidTarget
+		}
+		f, err := src.SalvageField(ctx, id)
+		if err != nil {
+			return orbitableDisc{}, err
+		}
+		return orbitableDisc{f.ID, orbitables.SalvageTargetType, f.X, f.Y, f.CollisionRadius}, nil
+	}
+	src, ok := gs.(OrbitableSource)
+	if !ok {
+		return orbitableDisc{}, ErrInvalidTarget
+	}
+	switch targetType {
+	case "asteroid":
+		a, err := src.Asteroid(ctx, id)
+		if err != nil {
+			return orbitableDisc{}, err
+		}
+		return orbitableDisc{a.ID, "asteroid", a.X, a.Y, a.CollisionRadius}, nil
+	case "nebula":
+		n, err := src.Nebula(ctx, id)
+		if err != nil {
+			return orbitableDisc{}, err
+		}
+		return orbitableDisc{n.ID, "nebula", n.X, n.Y, n.CollisionRadius}, nil
+	}
+	return orbitableDisc{}, ErrInvalidTarget
+}
+
+// advanceAnchor completes a stack's anchor or release timer. A stack whose
+// anchor holds on an asteroid, nebula or salvage field starts gathering from
+// it; a completed release takes it off the deposit's MiningFleets.
+func advanceAnchor(w *WorldState, s *ships.ShipStack, to time.Time) (TickEvent, bool) {
+	if s.Anchor == nil {
+		return TickEvent{}, false
+	}
+	target, kind, at := s.Anchor.TargetID, s.Anchor.TargetType, s.Anchor.EndsAt
+	switch s.AdvanceAnchor(to) {
+	case ships.AnchorStageHeld:
+		ev := TickEvent{Phase: PhaseMovement, Kind: TickEventAnchorHeld, SubjectID: s.ID, RelatedID: target, At: at}
+		if err := startGathering(w, s, at); err != nil {
+			ev.Detail = err.Error()
+		}
+		return ev, true
+	case ships.AnchorStageReleasing:
+		stopMining(w, s, kind, target)
+		return TickEvent{Phase: PhaseMovement, Kind: TickEventAnchorReleased, SubjectID: s.ID, RelatedID: target, At: at}, true
+	}
+	return TickEvent{}, false
+}
+
+// startGathering sets a stack whose anchor just held gathering from its
+// target. Mining a deposit is recorded on the owner's PlayerGameState.
+func startGathering(w *WorldState, s *ships.ShipStack, at time.Time) error {
+	target := s.Anchor.TargetID
+	var err error
+	switch s.Anchor.TargetType {
+	case "asteroid":
+		a := findAsteroid(w, target)
+		if a == nil {
+			return ErrInvalidTarget
+		}
+		err = a.StartMining(s, at)
+	case "nebula":
+		n := findNebula(w, target)
+		if n == nil {
+			return ErrInvalidTarget
+		}
+		err = n.StartMining(s, at)
+	case orbitables.SalvageTargetType:
+		for _, f := range w.Salvage {
+			if f.ID == target {
+				return f.StartHarvest(s, at)
+			}
+		}
+		return ErrInvalidTarget
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ps := playerState(w, s.PlayerID); ps != nil {
+		ps.AddMiningOperation(target)
+	}
+	return nil
+}
+
+// stopMining takes a released stack off the deposit it mined. The owner's
+// mining operation ends when none of their stacks is left on it.
+func stopMining(w *WorldState, s *ships.ShipStack, kind string, target bson.ObjectID) {
+	var fleets []bson.ObjectID
+	switch kind {
+	case "asteroid":
+		a := findAsteroid(w, target)
+		if a == nil {
+			return
+		}
+		a.StopMining(s.ID)
+		fleets = a.ResourceExtraction.MiningFleets
+	case "nebula":
+		n := findNebula(w, target)
+		if n == nil {
+			return
+		}
+		n.StopMining(s.ID)
+		fleets = n.ResourceExtraction.MiningFleets
+	default:
+		return
+	}
+	ps := playerState(w, s.PlayerID)
+	if ps == nil {
+		return
+	}
+	for _, id := range fleets {
+		for _, other := range w.Stacks {
+			if other.ID == id && other.PlayerID == s.PlayerID {
+				return
+			}
+		}
+	}
+	ps.RemoveMiningOperation(target)
+}
+
+func findAsteroid(w *WorldState, id bson.ObjectID) *orbitables.Asteroid {
+	for _, a := range w.Asteroids {
+		if a.ID == id {
+			return a
+		}
+	}
+	return nil
+}
+
+func findNebula(w *WorldState, id bson.ObjectID) *orbitables.Nebula {
+	for _, n := range w.Nebulas {
+		if n.ID == id {
+			return n
+		}
+	}
+	return nil
+}
+
+func playerState(w *WorldState, playerID bson.ObjectID) *players.PlayerGameState {
+	for _, ps := range w.Players {
+		if ps.PlayerID == playerID {
+			return ps
+		}
+	}
+	return nil
+}
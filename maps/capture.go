@@ -0,0 +1,151 @@
+package maps
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/essences"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Capture tick events. SubjectID is the system and RelatedID the capturing
+// stack; for TickEventSystemCaptured Detail is the previous controller's
+// player ID (hex). The captured stack is folded into the system and removed
+// from WorldState.Stacks; callers delete its document.
+const (
+	TickEventGarrisonRound     = "garrison_round"
+	TickEventGarrisonDestroyed = "garrison_destroyed"
+	TickEventCaptureStarted    = "capture_started"
+	TickEventSystemCaptured    = "system_captured"
+)
+
+// CaptureSystem completes the stack's capture of sys and moves the system
+// between the two players' ColonizedSystems. Everything is checked before
+// anything changes, so on error neither player nor the system is touched.
+// The previous owner's stacks fire their on_system_lost bio nodes.
+func CaptureSystem(sys *orbitables.System, stack *ships.ShipStack, attacker, defender *players.PlayerGameState, defenderStacks []*ships.ShipStack, now time.Time) error {
+	if err := checkTerritoryOwner(attacker, stack.PlayerID, sys.MapID); err != nil {
+		return err
+	}
+	if err := sys.CheckCapture(stack, now); err != nil {
+		return err
+	}
+	if err := checkTerritoryOwner(defender, sys.DefendingFleet.PlayerID, sys.MapID); err != nil {
+		return err
+	}
+	if _, err := sys.CompleteCapture(stack, now); err != nil {
+		return err
+	}
+	attacker.RemoveActiveStack(stack.ID)
+	attacker.AddColonizedSystem(sys.ID)
+	defender.RemoveColonizedSystem(sys.ID)
+	for _, s := range defenderStacks {
+		if s.PlayerID == defender.PlayerID && s.Bio != nil {
+			s.Bio.OnTrigger(string(essences.TriggerOnSystemLost), now)
+		}
+	}
+	return nil
+}
+
+// startCaptureOnArrival starts a capture when a stack ends its move in an
+// enemy system whose garrison has fallen.
+func startCaptureOnArrival(systems []*orbitables.System, s *ships.ShipStack, systemID bson.ObjectID, at time.Time) (TickEvent, bool) {
+	for _, sys := range systems {
+		if sys.ID != systemID || !sys.GarrisonDestroyed() || sys.Capture != nil {
+			continue
+		}
+		if _, err := sys.StartCapture(s, at); err != nil {
+			return TickEvent{}, false
+		}
+		return TickEvent{Phase: PhaseMovement, Kind: TickEventCaptureStarted, SubjectID: sys.ID, RelatedID: s.ID, At: at}, true
+	}
+	return TickEvent{}, false
+}
+
+// garrisonRounds lets every enemy stack idle inside a colonized system fight
+// its garrison, one round per interval boundary in (from, to], in stack ID
+// order. The stack that destroys a garrison starts capturing the system.
+func garrisonRounds(w *WorldState, from, to time.Time, interval time.Duration) []TickEvent {
+	rounds := to.UnixNano()/int64(interval) - from.UnixNano()/int64(interval)
+	if rounds <= 0 {
+		return nil
+	}
+	var events []TickEvent
+	for _, sys := range w.Systems {
+		for _, s := range w.Stacks {
+			for k := int64(0); k < rounds; k++ {
+				result, err := sys.GarrisonRound(s, to)
+				if err != nil {
+					break
+				}
+				events = append(events, TickEvent{Phase: PhaseCombat, Kind: TickEventGarrisonRound, SubjectID: sys.ID, RelatedID: s.ID, At: to})
+				wreckage := result.Salvage
+				if c, ev, ok := spillCargo(s, to); ok {
+					wreckage = wreckage.Plus(c)
+					events = append(events, ev)
+				}
+				if ev, ok := dropSalvage(w, s, wreckage, to); ok {
+					events = append(events, ev)
+				}
+				if !sys.GarrisonDestroyed() {
+					continue
+				}
+				events = append(events, TickEvent{Phase: PhaseCombat, Kind: TickEventGarrisonDestroyed, SubjectID: sys.ID, RelatedID: s.ID, At: to})
+				if _, err := sys.StartCapture(s, to); err == nil {
+					events = append(events, TickEvent{Phase: PhaseCombat, Kind: TickEventCaptureStarted, SubjectID: sys.ID, RelatedID: s.ID, At: to})
+				}
+				break
+			}
+		}
+	}
+	return events
+}
+
+// resolveCaptures completes captures whose hold timer ran out by to. A
+// capture that can no longer succeed (the stack left, moved or died, the
+// garrison was rebuilt or the system lost its DefendingFleet) is dropped.
+func resolveCaptures(w *WorldState, to time.Time) []TickEvent {
+	stacks := make(map[bson.ObjectID]*ships.ShipStack, len(w.Stacks))
+	for _, s := range w.Stacks {
+		stacks[s.ID] = s
+	}
+	states := make(map[bson.ObjectID]*players.PlayerGameState, len(w.Players))
+	for _, p := range w.Players {
+		states[p.PlayerID] = p
+	}
+
+	var events []TickEvent
+	for _, sys := range w.Systems {
+		if sys.Capture == nil || to.Before(sys.Capture.EndsAt) {
+			continue
+		}
+		s := stacks[sys.Capture.StackID]
+		if s == nil || sys.DefendingFleet == nil {
+			sys.CancelCapture()
+			continue
+		}
+		at := sys.Capture.EndsAt
+		previous := sys.DefendingFleet.PlayerID
+		if err := CaptureSystem(sys, s, states[s.PlayerID], states[previous], w.Stacks, at); err != nil {
+			if !errors.Is(err, ErrNotOwner) {
+				sys.CancelCapture()
+			}
+			continue
+		}
+		delete(stacks, s.ID)
+		events = append(events, TickEvent{Phase: PhaseCombat, Kind: TickEventSystemCaptured, SubjectID: sys.ID, RelatedID: s.ID, Detail: previous.Hex(), At: at})
+	}
+	if len(events) > 0 {
+		kept := w.Stacks[:0]
+		for _, s := range w.Stacks {
+			if stacks[s.ID] != nil {
+				kept = append(kept, s)
+			}
+		}
+		w.Stacks = kept
+	}
+	return events
+}
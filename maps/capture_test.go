@@ -0,0 +1,140 @@
+package maps
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/essences"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSystemCaptureAfterGarrisonFalls(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	attacker := &players.PlayerGameState{PlayerID: bson.NewObjectID()}
+	defender := &players.PlayerGameState{PlayerID: bson.NewObjectID()}
+	sys := &orbitables.System{
+		ID:              bson.NewObjectID(),
+		CollisionRadius: 50,
+		Planet:          &orbitables.Planet{},
+		Colonization:    &orbitables.Colonization{IsColonized: true, ColonizedBy: defender.PlayerID},
+		DefendingFleet:  &orbitables.DefendingFleet{PlayerID: defender.PlayerID},
+	}
+	sys.Planet.SetBuilding(orbitables.SlotFusionReactor, b.FusionReactor{BaseBuilding: b.BaseBuilding{Level: 4, Queue: []b.Queue{{Action: b.QueueActionResearch}}}})
+	defender.AddColonizedSystem(sys.ID)
+
+	raider := &ships.ShipStack{
+		ID:       bson.NewObjectID(),
+		PlayerID: attacker.PlayerID,
+		Ships:    map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 4}}},
+	}
+	attacker.AddActiveStack(raider.ID)
+	mourner := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: defender.PlayerID, PositionX: 5000}
+	mourner.EnsureBio(now).Node("grief").ForAllShips().
+		WithTriggered(ships.StatMods{SpeedDelta: 1}, time.Hour, time.Hour).
+		WithTrigger(string(essences.TriggerOnSystemLost))
+
+	ends, err := sys.StartCapture(raider, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CaptureSystem(sys, raider, attacker, defender, nil, now); !errors.Is(err, orbitables.ErrCaptureHolding) {
+		t.Fatalf("capturing before the hold ends: err = %v", err)
+	}
+	if !sys.ControlledBy(defender.PlayerID) || len(attacker.ColonizedSystems) != 0 {
+		t.Fatal("failed capture changed ownership")
+	}
+
+	w := &WorldState{Stacks: []*ships.ShipStack{raider, mourner}, Systems: []*orbitables.System{sys}, Players: []*players.PlayerGameState{attacker, defender}}
+	events := resolveCaptures(w, ends)
+	if len(events) != 1 || events[0].Kind != TickEventSystemCaptured || events[0].Detail != defender.PlayerID.Hex() {
+		t.Fatalf("events = %+v", events)
+	}
+	if !sys.ControlledBy(attacker.PlayerID) || sys.Colonization.ColonizedBy != defender.PlayerID || sys.Capture != nil {
+		t.Fatalf("system after capture: %+v / %+v", sys.Colonization, sys.DefendingFleet)
+	}
+	if got := sys.DefendingFleet.Ships[ships.Fighter][0].Count; got != 4 || len(w.Stacks) != 1 {
+		t.Fatalf("garrison %d fighters, %d stacks left", got, len(w.Stacks))
+	}
+	reactor, _ := sys.Planet.Building(orbitables.SlotFusionReactor)
+	if base := b.Base(reactor); base.Level != 3 || len(base.Queue) != 0 {
+		t.Fatalf("reactor after capture: level %d, queue %v", base.Level, base.Queue)
+	}
+	if len(attacker.ColonizedSystems) != 1 || len(attacker.ActiveStacks) != 0 || len(defender.ColonizedSystems) != 0 {
+		t.Fatalf("players after capture: %+v / %+v", attacker, defender)
+	}
+	if n := mourner.Bio.Nodes["grief"]; n.Stage != ships.BioStageTriggered {
+		t.Fatalf("on_system_lost did not fire: stage %s", n.Stage)
+	}
+}
+
+func TestResolveCapturesDropsCaptureOfAbandonedSystem(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	attacker := &players.PlayerGameState{PlayerID: bson.NewObjectID()}
+	defender := bson.NewObjectID()
+	sys := &orbitables.System{
+		ID:              bson.NewObjectID(),
+		CollisionRadius: 50,
+		Colonization:    &orbitables.Colonization{IsColonized: true, ColonizedBy: defender},
+		DefendingFleet:  &orbitables.DefendingFleet{PlayerID: defender},
+	}
+	raider := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: attacker.PlayerID,
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 4}}}}
+	ends, err := sys.StartCapture(raider, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The system is abandoned while the capture holds.
+	sys.DefendingFleet = nil
+	sys.Colonization.IsColonized = false
+
+	w := &WorldState{Stacks: []*ships.ShipStack{raider}, Systems: []*orbitables.System{sys}, Players: []*players.PlayerGameState{attacker}}
+	if events := resolveCaptures(w, ends); len(events) != 0 {
+		t.Fatalf("events = %+v", events)
+	}
+	if sys.Capture != nil || len(w.Stacks) != 1 {
+		t.Fatalf("capture %+v left on an abandoned system", sys.Capture)
+	}
+}
+
+// A stack parked in an enemy system grinds down its garrison on the tick and
+// takes the system once the hold runs out.
+func TestTickCapturesSystemAfterGarrisonRounds(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	attacker := &players.PlayerGameState{PlayerID: bson.NewObjectID()}
+	defender := &players.PlayerGameState{PlayerID: bson.NewObjectID()}
+	sys := &orbitables.System{
+		ID:              bson.NewObjectID(),
+		X:               1000,
+		CollisionRadius: 50,
+		Colonization:    &orbitables.Colonization{IsColonized: true, ColonizedBy: defender.PlayerID},
+		DefendingFleet: &orbitables.DefendingFleet{PlayerID: defender.PlayerID,
+			Ships: map[ships.ShipType][]ships.HPBucket{ships.Scout: {{HP: ships.ShipBlueprints[ships.Scout].HP, Count: 1}}}},
+	}
+	defender.AddColonizedSystem(sys.ID)
+	raider := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: attacker.PlayerID, PositionX: 1000,
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Cruiser: {{HP: ships.ShipBlueprints[ships.Cruiser].HP, Count: 10}}}}
+	raider.EnsureFormationInitialized(start)
+	attacker.AddActiveStack(raider.ID)
+
+	w := &WorldState{LastTick: start, Stacks: []*ships.ShipStack{raider}, Systems: []*orbitables.System{sys},
+		Players: []*players.PlayerGameState{attacker, defender}}
+	e := NewTickEngine(DefaultTickStep)
+	kinds := map[string]int{}
+	for _, ev := range e.Advance(w, start.Add(DefaultCombatRoundInterval*4)) {
+		kinds[ev.Kind]++
+	}
+	if kinds[TickEventGarrisonRound] == 0 || kinds[TickEventGarrisonDestroyed] != 1 || kinds[TickEventCaptureStarted] != 1 || sys.Capture == nil {
+		t.Fatalf("events %v, capture %+v, garrison %v", kinds, sys.Capture, sys.DefendingFleet.Ships)
+	}
+	for _, ev := range e.Advance(w, sys.Capture.EndsAt.Add(DefaultTickStep)) {
+		kinds[ev.Kind]++
+	}
+	if kinds[TickEventSystemCaptured] != 1 || !sys.ControlledBy(attacker.PlayerID) || len(w.Stacks) != 0 {
+		t.Fatalf("events %v, controller %v", kinds, sys.DefendingFleet.PlayerID)
+	}
+}
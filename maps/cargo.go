@@ -0,0 +1,50 @@
+package maps
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Cargo tick events. SubjectID is the stack and Detail the amounts; for
+// TickEventCargoUnloaded RelatedID is the system.
+const (
+	TickEventCargoUnloaded = "cargo_unloaded"
+	TickEventCargoSpilled  = "cargo_spilled"
+)
+
+// unloadAtSystem empties a stack's hold into the system it arrived at when
+// its owner controls the system.
+func unloadAtSystem(systems []*orbitables.System, s *ships.ShipStack, systemID bson.ObjectID, at time.Time) (TickEvent, bool) {
+	if s.CargoUsed() == 0 {
+		return TickEvent{}, false
+	}
+	for _, sys := range systems {
+		if sys.ID != systemID {
+			continue
+		}
+		c, err := sys.UnloadCargo(s)
+		if err != nil {
+			return TickEvent{}, false
+		}
+		return TickEvent{Phase: PhaseMovement, Kind: TickEventCargoUnloaded, SubjectID: s.ID, RelatedID: sys.ID, Detail: cargoDetail(c), At: at}, true
+	}
+	return TickEvent{}, false
+}
+
+// spillCargo drops what a stack's hold can no longer carry after losses and
+// returns it so it can join the battle's salvage.
+func spillCargo(s *ships.ShipStack, at time.Time) (ships.Cargo, TickEvent, bool) {
+	c := s.SettleCargo(at)
+	if c.Total() == 0 {
+		return c, TickEvent{}, false
+	}
+	return c, TickEvent{Phase: PhaseCombat, Kind: TickEventCargoSpilled, SubjectID: s.ID, Detail: cargoDetail(c), At: at}, true
+}
+
+func cargoDetail(c ships.Cargo) string {
+	return fmt.Sprintf("metal:%d,crystal:%d,hydrogen:%d", c.Metals, c.Crystals, c.Hydrogen)
+}
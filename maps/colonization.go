@@ -0,0 +1,66 @@
+package maps
+
+import (
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Colonization keeps a system, the stack moving in or out of it and the
+// owner's PlayerGameState in step. Callers persist all three and, after
+// ColonizeSystem or ReinforceSystem, delete the stack's document: its ships
+// now live in the system's DefendingFleet.
+
+// ColonizeSystem embeds the stack into an unclaimed system.
+func ColonizeSystem(sys *orbitables.System, stack *ships.ShipStack, owner *players.PlayerGameState, now time.Time) error {
+	if err := checkTerritoryOwner(owner, stack.PlayerID, sys.MapID); err != nil {
+		return err
+	}
+	if err := sys.Colonize(stack, now); err != nil {
+		return err
+	}
+	owner.RemoveActiveStack(stack.ID)
+	owner.AddColonizedSystem(sys.ID)
+	return nil
+}
+
+// ReinforceSystem folds the stack into a system its player already holds.
+func ReinforceSystem(sys *orbitables.System, stack *ships.ShipStack, owner *players.PlayerGameState, now time.Time) error {
+	if err := checkTerritoryOwner(owner, stack.PlayerID, sys.MapID); err != nil {
+		return err
+	}
+	if err := sys.Reinforce(stack, now); err != nil {
+		return err
+	}
+	owner.RemoveActiveStack(stack.ID)
+	return nil
+}
+
+// DepartSystem carves ships out of the system's DefendingFleet into a new
+// free stack (see System.Depart). When the whole fleet leaves, the system
+// drops out of the player's ColonizedSystems.
+func DepartSystem(sys *orbitables.System, owner *players.PlayerGameState, take map[ships.ShipType]int, stackID bson.ObjectID, now time.Time) (*ships.ShipStack, error) {
+	if err := checkTerritoryOwner(owner, owner.PlayerID, sys.MapID); err != nil {
+		return nil, err
+	}
+	stack, err := sys.Depart(owner.PlayerID, take, stackID, now)
+	if err != nil {
+		return nil, err
+	}
+	owner.AddActiveStack(stack.ID)
+	stack.Research = append([]string(nil), owner.Research...)
+	if sys.DefendingFleet == nil {
+		owner.RemoveColonizedSystem(sys.ID)
+	}
+	return stack, nil
+}
+
+func checkTerritoryOwner(owner *players.PlayerGameState, playerID, mapID bson.ObjectID) error {
+	if owner == nil || owner.PlayerID != playerID || (!mapID.IsZero() && owner.MapID != mapID) {
+		return ErrNotOwner
+	}
+	return nil
+}
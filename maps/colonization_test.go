@@ -0,0 +1,64 @@
+package maps
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestColonizeReinforceDepart(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := &players.PlayerGameState{PlayerID: bson.NewObjectID()}
+	sys := &orbitables.System{ID: bson.NewObjectID(), X: 100, Y: 100, CollisionRadius: 50, Planet: &orbitables.Planet{}}
+	stack := func(x float64, fighters int) *ships.ShipStack {
+		s := &ships.ShipStack{
+			ID:        bson.NewObjectID(),
+			PlayerID:  owner.PlayerID,
+			PositionX: x,
+			PositionY: 100,
+			Ships:     map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: fighters}}},
+			Cargo:     &ships.Cargo{Metals: 30},
+		}
+		owner.AddActiveStack(s.ID)
+		return s
+	}
+	first, second := stack(120, 5), stack(500, 3)
+
+	if err := ReinforceSystem(sys, first, owner, now); !errors.Is(err, orbitables.ErrNotSystemOwner) {
+		t.Fatalf("reinforcing an unclaimed system: err = %v", err)
+	}
+	if err := ColonizeSystem(sys, first, owner, now); err != nil {
+		t.Fatal(err)
+	}
+	if !sys.ControlledBy(owner.PlayerID) || sys.Planet.Metals != 30 || len(owner.ActiveStacks) != 1 || len(owner.ColonizedSystems) != 1 {
+		t.Fatalf("after colonizing: fleet %+v, metals %d, player %+v", sys.DefendingFleet, sys.Planet.Metals, owner)
+	}
+
+	// The second stack is outside the system: nothing changes.
+	if err := ReinforceSystem(sys, second, owner, now); !errors.Is(err, orbitables.ErrNotAtSystem) {
+		t.Fatalf("reinforcing from afar: err = %v", err)
+	}
+	second.PositionX = 100
+	if err := ReinforceSystem(sys, second, owner, now); err != nil {
+		t.Fatal(err)
+	}
+	if got := sys.DefendingFleet.Ships[ships.Fighter][0].Count; got != 8 || len(owner.ActiveStacks) != 0 {
+		t.Fatalf("after reinforcing: %d fighters, active %v", got, owner.ActiveStacks)
+	}
+
+	out, err := DepartSystem(sys, owner, map[ships.ShipType]int{ships.Fighter: 6}, bson.NewObjectID(), now)
+	if err != nil || out.Ships[ships.Fighter][0].Count != 6 || !sys.ControlledBy(owner.PlayerID) {
+		t.Fatalf("partial departure: stack %+v, err %v", out, err)
+	}
+	if _, err := DepartSystem(sys, owner, nil, bson.NewObjectID(), now); err != nil {
+		t.Fatal(err)
+	}
+	if sys.DefendingFleet != nil || sys.Colonization.IsColonized || len(owner.ColonizedSystems) != 0 || len(owner.ActiveStacks) != 2 {
+		t.Fatalf("after abandoning: system %+v, player %+v", sys, owner)
+	}
+}
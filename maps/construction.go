@@ -0,0 +1,109 @@
+package maps
+
+import (
+	"context"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Construction tick events. TickEventBuildingUpgraded is emitted when a
+// planet slot's upgrade job completes: SubjectID is the system, RelatedID the
+// planet and Detail the slot. TickEventConstructionFailed reports a system
+// whose planet could not be advanced (e.g. a building that fails to decode);
+// Detail is the error and the system is skipped for the rest of that phase.
+const (
+	TickEventBuildingUpgraded   = "building_upgraded"
+	TickEventConstructionFailed = "construction_failed"
+)
+
+// BuildingConstructionHandler starts or cancels an upgrade job in a planet
+// slot of the source system.
+type BuildingConstructionHandler struct{}
+
+func (BuildingConstructionHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	order, ok := q.Payload.(*BuildingOrder)
+	if !ok {
+		return unexpectedPayload(q)
+	}
+	sys, err := gs.System(ctx, q.SourceID)
+	if err != nil {
+		return err
+	}
+	// Dry run on a copy: slot setters swap documents rather than editing them.
+	_, err = applyBuildingOrder(sys, q.PlayerID, *order, now)
+	return err
+}
+
+func (BuildingConstructionHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	order, ok := q.Payload.(*BuildingOrder)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	sys, err := gs.System(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	planet, err := applyBuildingOrder(sys, q.PlayerID, *order, now)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	sys.Planet = planet
+	if err := gs.SaveSystem(ctx, sys); err != nil {
+		return ActionResult{}, err
+	}
+	res := ActionResult{
+		Payload:      &BuildingResult{Slot: order.Slot, Cancelled: order.Cancel},
+		TargetX:      sys.X,
+		TargetY:      sys.Y,
+		Participants: []bson.ObjectID{sys.ID},
+	}
+	if !order.Cancel {
+		if building, _ := planet.Building(order.Slot); building != nil {
+			res.EndTime = building.GetConstructionTime()
+		}
+	}
+	return res, nil
+}
+
+// applyBuildingOrder runs the order against a copy of the system's planet and
+// returns the updated copy.
+func applyBuildingOrder(sys *orbitables.System, playerID bson.ObjectID, order BuildingOrder, now time.Time) (*orbitables.Planet, error) {
+	if !sys.ControlledBy(playerID) {
+		return nil, orbitables.ErrNotSystemOwner
+	}
+	if sys.Planet == nil {
+		return nil, orbitables.ErrNoPlanet
+	}
+	planet := *sys.Planet
+	if order.Cancel {
+		return &planet, planet.CancelUpgrade(order.Slot, now)
+	}
+	_, err := planet.StartUpgrade(order.Slot, order.BuildingType, now)
+	return &planet, err
+}
+
+// constructionPhase completes building upgrades and research, then delivers
+// shipyard orders.
+func (e *TickEngine) constructionPhase(w *WorldState, from, to time.Time) []TickEvent {
+	var events []TickEvent
+	for _, sys := range w.Systems {
+		if sys.Planet == nil {
+			continue
+		}
+		slots, err := sys.Planet.CompleteUpgrades(to)
+		for _, slot := range slots {
+			events = append(events, TickEvent{Phase: PhaseConstruction, Kind: TickEventBuildingUpgraded, SubjectID: sys.ID, RelatedID: sys.Planet.ID, Detail: slot, At: to})
+		}
+		if err != nil {
+			events = append(events, constructionFailed(sys, err, to))
+		}
+	}
+	events = append(events, e.researchPhase(w, from, to)...)
+	return append(events, e.shipyardPhase(w, from, to)...)
+}
+
+func constructionFailed(sys *orbitables.System, err error, at time.Time) TickEvent {
+	return TickEvent{Phase: PhaseConstruction, Kind: TickEventConstructionFailed, SubjectID: sys.ID, RelatedID: sys.Planet.ID, Detail: err.Error(), At: at}
+}
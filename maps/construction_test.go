@@ -0,0 +1,29 @@
+package maps
+
+import (
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestConstructionPhaseReportsFailures(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := bson.NewObjectID()
+	planet := &orbitables.Planet{ID: bson.NewObjectID(), Type: b.PlanetEarth, LastProduced: now}
+	planet.NorthPole = &bson.M{"type": "no_such_building"}
+	sys := &orbitables.System{
+		ID:             bson.NewObjectID(),
+		Colonization:   &orbitables.Colonization{IsColonized: true, ColonizedBy: owner},
+		DefendingFleet: &orbitables.DefendingFleet{PlayerID: owner},
+		Planet:         planet,
+	}
+	w := &WorldState{Systems: []*orbitables.System{sys}}
+
+	events := NewTickEngine(time.Minute).constructionPhase(w, now, now.Add(time.Minute))
+	if len(events) != 1 || events[0].Kind != TickEventConstructionFailed || events[0].SubjectID != sys.ID || events[0].Detail == "" {
+		t.Fatalf("events = %+v, want one construction failure", events)
+	}
+}
@@ -0,0 +1,142 @@
+package maps
+
+import (
+	"strconv"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Energy ledger
+//
+// Each step the energy phase builds one ledger per player from the planets of
+// the systems they control. If the player's reserve cannot absorb the step's
+// deficit, the lowest brownout level that balances the books is applied to all
+// of their planets (see buildings/upkeep.go for the shedding order); once the
+// reserve can carry the full load again the brownout lifts. Production is then
+// accrued per planet, with energy net of upkeep credited to the player.
+
+// TickEventBrownout is emitted when a player's brownout level changes.
+// SubjectID is the player and Detail the new level.
+const TickEventBrownout = "brownout"
+
+// LedgerEntry is one building's hourly contribution to a player's energy.
+type LedgerEntry struct {
+	SystemID     bson.ObjectID
+	Slot         string
+	BuildingType string
+	Production   float64
+	Upkeep       float64
+}
+
+// EnergyLedger sums hourly energy production and upkeep across a player's
+// planets, before any brownout.
+type EnergyLedger struct {
+	PlayerID   bson.ObjectID
+	Production float64
+	Upkeep     float64
+	Entries    []LedgerEntry
+}
+
+// BuildEnergyLedger collects the buildings of every system playerID controls.
+func BuildEnergyLedger(playerID bson.ObjectID, systems []*orbitables.System) (EnergyLedger, error) {
+	l := EnergyLedger{PlayerID: playerID}
+	for _, sys := range systems {
+		if sys.Planet == nil || !sys.ControlledBy(playerID) {
+			continue
+		}
+		for _, slot := range orbitables.Slots {
+			building, err := sys.Planet.Building(slot)
+			if err != nil {
+				return l, err
+			}
+			if building == nil || building.GetLevel() < 1 {
+				continue
+			}
+			entry := LedgerEntry{
+				SystemID:     sys.ID,
+				Slot:         slot,
+				BuildingType: building.GetType(),
+				Production:   b.ProductionRate(building, sys.Planet.Type).Energy,
+				Upkeep:       float64(b.UpkeepRate(building)),
+			}
+			l.Production += entry.Production
+			l.Upkeep += entry.Upkeep
+			l.Entries = append(l.Entries, entry)
+		}
+	}
+	return l, nil
+}
+
+// UpkeepAt returns the hourly upkeep still drawn under a brownout level.
+func (l EnergyLedger) UpkeepAt(brownout int) float64 {
+	total := 0.0
+	for _, e := range l.Entries {
+		total += e.Upkeep * b.PowerFactor(e.BuildingType, brownout)
+	}
+	return total
+}
+
+// NetAt returns the hourly energy balance under a brownout level.
+func (l EnergyLedger) NetAt(brownout int) float64 {
+	return l.Production - l.UpkeepAt(brownout)
+}
+
+// BrownoutFor picks the lowest brownout level at which reserve covers the
+// balance over d. If even the deepest level runs a deficit, it is returned.
+func (l EnergyLedger) BrownoutFor(reserve int64, d time.Duration) int {
+	for level := b.BrownoutNone; level < b.BrownoutMining; level++ {
+		if float64(reserve)+l.NetAt(level)*d.Hours() >= 0 {
+			return level
+		}
+	}
+	return b.BrownoutMining
+}
+
+// energyPhase settles each player's ledger, applies brownouts and accrues
+// planet production. Metals and crystals stay on the planet.
+func (e *TickEngine) energyPhase(w *WorldState, from, to time.Time) []TickEvent {
+	var events []TickEvent
+	byPlayer := make(map[bson.ObjectID]*players.PlayerGameState, len(w.Players))
+	for _, ps := range w.Players {
+		byPlayer[ps.PlayerID] = ps
+		ledger, err := BuildEnergyLedger(ps.PlayerID, w.Systems)
+		if err != nil {
+			continue
+		}
+		level := ledger.BrownoutFor(ps.Energy, to.Sub(from))
+		if level != ps.Brownout {
+			events = append(events, TickEvent{Phase: PhaseEnergy, Kind: TickEventBrownout, SubjectID: ps.PlayerID, Detail: strconv.Itoa(level), At: to})
+		}
+		ps.Brownout = level
+		ps.EnergyProduction = int64(ledger.Production)
+		ps.EnergyUpkeep = int64(ledger.UpkeepAt(level))
+		for _, sys := range w.Systems {
+			if sys.Planet != nil && sys.ControlledBy(ps.PlayerID) {
+				sys.Planet.Brownout = level
+			}
+		}
+	}
+
+	for _, sys := range w.Systems {
+		if sys.Planet == nil || sys.DefendingFleet == nil || sys.Colonization == nil || !sys.Colonization.IsColonized {
+			continue
+		}
+		out, err := sys.Planet.AccrueProduction(to)
+		if err != nil {
+			continue
+		}
+		if ps := byPlayer[sys.DefendingFleet.PlayerID]; ps != nil {
+			ps.Energy += int64(out.Energy)
+		}
+	}
+	for _, ps := range w.Players {
+		if ps.Energy < 0 {
+			ps.Energy = 0
+		}
+	}
+	return events
+}
@@ -0,0 +1,51 @@
+package maps
+
+import (
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestEnergyPhaseBrownoutPriority(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := bson.NewObjectID()
+	planet := &orbitables.Planet{Type: b.PlanetEarth, LastProduced: now}
+	level := func(n int) b.BaseBuilding { return b.BaseBuilding{Level: n} }
+	// 100 energy/h against 20 (shipyard) + 30 (accelerator) + 60 (reactor lvl 4).
+	planet.SetBuilding(orbitables.SlotNorthPole, b.HydroElectricDam{EnergyBuilding: b.EnergyBuilding{BaseBuilding: level(1)}})
+	planet.SetBuilding(orbitables.SlotShipYard, b.ShipYard{BaseBuilding: level(1)})
+	planet.SetBuilding(orbitables.SlotParticleAccelerator, b.ParticleAccelerator{BaseBuilding: level(1)})
+	planet.SetBuilding(orbitables.SlotFusionReactor, b.FusionReactor{BaseBuilding: level(4)})
+	sys := &orbitables.System{
+		ID:             bson.NewObjectID(),
+		Colonization:   &orbitables.Colonization{IsColonized: true, ColonizedBy: owner},
+		DefendingFleet: &orbitables.DefendingFleet{PlayerID: owner},
+		Planet:         planet,
+	}
+	ps := &players.PlayerGameState{PlayerID: owner}
+	w := &WorldState{Systems: []*orbitables.System{sys}, Players: []*players.PlayerGameState{ps}}
+
+	e := NewTickEngine(time.Minute)
+	events := e.energyPhase(w, now, now.Add(time.Minute))
+	// With no reserve, shedding the accelerator is enough to balance 110/h.
+	if ps.Brownout != b.BrownoutResearch || planet.Brownout != b.BrownoutResearch {
+		t.Fatalf("brownout = %d (planet %d), want %d", ps.Brownout, planet.Brownout, b.BrownoutResearch)
+	}
+	if len(events) != 1 || events[0].Kind != TickEventBrownout {
+		t.Fatalf("events = %+v", events)
+	}
+	if ps.EnergyProduction != 100 || ps.EnergyUpkeep != 80 {
+		t.Fatalf("production %d, upkeep %d", ps.EnergyProduction, ps.EnergyUpkeep)
+	}
+
+	// A reserve that covers the full load lifts the brownout.
+	ps.Energy = 1000
+	e.energyPhase(w, now.Add(time.Minute), now.Add(2*time.Minute))
+	if ps.Brownout != b.BrownoutNone || planet.Brownout != b.BrownoutNone {
+		t.Fatalf("brownout with reserve = %d", ps.Brownout)
+	}
+}
@@ -49,6 +49,7 @@ type ShipVisibility struct {
 	Stacks        []bson.ObjectID `bson:"visibleShips"`     // References ships that are visible to the player
 	Nebulas       []bson.ObjectID `bson:"visibleNebulas"`   // References nebulas that are visible to the player
 	Asteroids     []bson.ObjectID `bson:"visibleAsteroids"` // References asteroids that are visible to the player
+	Phantoms      []bson.ObjectID `bson:"visiblePhantoms,omitempty"` // References decoy contacts shown as stacks (see ships.PhantomContact)
 	LastUpdated   time.Time       `bson:"lastUpdated"`      // Timestamp of last visibility update
 	LastProcessed time.Time       `bson:"lastProcessed"`    // Last time this visibility was processed
 	Version       int64           `bson:"version"`          // For optimistic locking
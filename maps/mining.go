@@ -0,0 +1,62 @@
+package maps
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/essences"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Mining tick events. For TickEventResourceExtracted SubjectID is the stack,
+// RelatedID the deposit and Detail "<resource>:<amount>"; for
+// TickEventDepositExhausted SubjectID is the deposit.
+const (
+	TickEventResourceExtracted = "resource_extracted"
+	TickEventDepositExhausted  = "deposit_exhausted"
+)
+
+// miningPhase extracts from every asteroid and nebula, loads the yield into
+// the mining stacks' holds and fires their on_resource_extract bio nodes, then
+// harvests and decays salvage fields.
+func (e *TickEngine) miningPhase(w *WorldState, from, to time.Time) []TickEvent {
+	byID := make(map[bson.ObjectID]*ships.ShipStack, len(w.Stacks))
+	for _, s := range w.Stacks {
+		byID[s.ID] = s
+	}
+	var events []TickEvent
+	settle := func(depositID bson.ObjectID, res *orbitables.ResourceExtraction, yields []orbitables.MiningYield, wasExhausted bool) {
+		for _, y := range yields {
+			s := byID[y.StackID]
+			loadGathered(s, y.Resource, y.Amount, to)
+			if s.Bio != nil {
+				s.Bio.OnTrigger(string(essences.TriggerOnResourceExtract), to)
+			}
+			events = append(events, TickEvent{Phase: PhaseMining, Kind: TickEventResourceExtracted, SubjectID: s.ID, RelatedID: depositID, Detail: y.Resource + ":" + strconv.FormatInt(y.Amount, 10), At: to})
+		}
+		if res.Exhausted && !wasExhausted {
+			events = append(events, TickEvent{Phase: PhaseMining, Kind: TickEventDepositExhausted, SubjectID: depositID, At: to})
+		}
+	}
+	for _, a := range w.Asteroids {
+		was := a.ResourceExtraction.Exhausted
+		settle(a.ID, &a.ResourceExtraction, a.Mine(w.Stacks, to), was)
+	}
+	for _, n := range w.Nebulas {
+		was := n.ResourceExtraction.Exhausted
+		settle(n.ID, &n.ResourceExtraction, n.Mine(w.Stacks, to), was)
+	}
+	return append(events, harvestSalvage(w, byID, to)...)
+}
+
+// loadGathered stores a gathered amount in the stack's hold and stops it
+// once the hold is full.
+func loadGathered(s *ships.ShipStack, resource string, amount int64, at time.Time) {
+	s.LoadCargo(resource, amount, at)
+	s.Gathering.Record(resource, amount, at)
+	if s.CargoFree(at) == 0 {
+		s.Gathering.Stage = ships.GatheringStageCargoFull
+	}
+}
@@ -0,0 +1,119 @@
+package maps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/essences"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMiningPhaseSharesDepletion(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rock := &orbitables.Asteroid{ID: bson.NewObjectID()}
+	miner := func(t ships.ShipType, count int) *ships.ShipStack {
+		s := &ships.ShipStack{
+			ID:        bson.NewObjectID(),
+			Ships:     map[ships.ShipType][]ships.HPBucket{t: {{HP: 100, Count: count}}},
+			Gathering: &ships.GatheringState{IsGathering: true, TargetID: rock.ID, TargetType: "asteroid"},
+		}
+		s.SetAnchored(t, true)
+		return s
+	}
+	drones, fighters := miner(ships.Drone, 4), miner(ships.Fighter, 4) // 4 and 1 Drone equivalents
+	drones.EnsureBio(now).Node("ore_sense").ForAllShips().
+		WithTriggered(ships.StatMods{SpeedDelta: 1}, time.Minute, time.Minute).
+		WithTrigger(string(essences.TriggerOnResourceExtract))
+	rock.ResourceExtraction = orbitables.ResourceExtraction{
+		ResourceType:   "metal",
+		MiningFleets:   []bson.ObjectID{drones.ID, fighters.ID},
+		ExtractionRate: 10,
+		RemainingRes:   30,
+		LastExtracted:  now,
+	}
+	w := &WorldState{Stacks: []*ships.ShipStack{drones, fighters}, Asteroids: []*orbitables.Asteroid{rock}}
+
+	e := NewTickEngine(time.Minute)
+	events := e.miningPhase(w, now, now.Add(time.Minute))
+	// They ask for 40 + 10 but only 30 remain: split 4:1.
+	if drones.Gathering.MetalsGathered != 24 || fighters.Gathering.MetalsGathered != 6 {
+		t.Fatalf("gathered %d / %d, want 24 / 6", drones.Gathering.MetalsGathered, fighters.Gathering.MetalsGathered)
+	}
+	if res := rock.ResourceExtraction; !res.Exhausted || res.RemainingRes != 0 || res.IsBeingMined {
+		t.Fatalf("deposit after depletion: %+v", res)
+	}
+	if len(events) != 3 || events[2].Kind != TickEventDepositExhausted {
+		t.Fatalf("events = %+v", events)
+	}
+	if n := drones.Bio.Nodes["ore_sense"]; n.Stage != ships.BioStageTriggered {
+		t.Fatalf("extract trigger did not fire: stage %s", n.Stage)
+	}
+
+	if events := e.miningPhase(w, now.Add(time.Minute), now.Add(2*time.Minute)); len(events) != 0 {
+		t.Fatalf("exhausted deposit still mined: %+v", events)
+	}
+}
+
+func TestAnchorJoinsAndLeavesMining(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := bson.NewObjectID()
+	rock := &orbitables.Asteroid{ID: bson.NewObjectID(), X: 100, Y: 100, CollisionRadius: 40,
+		ResourceExtraction: orbitables.ResourceExtraction{ResourceType: "metal", ExtractionRate: 10, RemainingRes: 1000, LastExtracted: now.Add(ships.AnchorSetDuration)}}
+	newMiner := func() *ships.ShipStack {
+		return &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: owner, PositionX: 110, PositionY: 100,
+			Ships: map[ships.ShipType][]ships.HPBucket{ships.Drone: {{HP: 100, Count: 2}}}}
+	}
+	a, b := newMiner(), newMiner()
+	ps := &players.PlayerGameState{PlayerID: owner}
+	w := &WorldState{Stacks: []*ships.ShipStack{a, b}, Asteroids: []*orbitables.Asteroid{rock}, Players: []*players.PlayerGameState{ps}}
+	e := NewTickEngine(time.Minute)
+
+	for _, s := range w.Stacks {
+		if _, err := s.StartAnchoring(rock.ID, "asteroid", rock.X, rock.Y, rock.CollisionRadius, now); err != nil {
+			t.Fatalf("anchor: %v", err)
+		}
+	}
+	held := now.Add(ships.AnchorSetDuration)
+	for _, ev := range e.movementPhase(w, now, held) {
+		if ev.Kind == TickEventAnchorHeld && ev.Detail != "" {
+			t.Fatalf("anchor held without mining: %s", ev.Detail)
+		}
+	}
+	if got := rock.ResourceExtraction.MiningFleets; len(got) != 2 {
+		t.Fatalf("mining fleets = %v, want both stacks", got)
+	}
+	if g := a.Gathering; g == nil || !g.IsGathering || g.TargetID != rock.ID || g.TargetType != "asteroid" {
+		t.Fatalf("gathering = %+v", a.Gathering)
+	}
+	if len(ps.MiningOperations) != 1 || ps.MiningOperations[0] != rock.ID {
+		t.Fatalf("mining operations = %v", ps.MiningOperations)
+	}
+	e.miningPhase(w, held, held.Add(time.Minute))
+	if a.CargoUsed() != 20 || b.CargoUsed() != 20 {
+		t.Fatalf("mined %d / %d, want 20 each", a.CargoUsed(), b.CargoUsed())
+	}
+
+	// Releasing one stack keeps the operation; releasing both ends it.
+	at := held.Add(time.Minute)
+	if _, err := a.StartRelease(at); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	e.movementPhase(w, at, at.Add(ships.AnchorReleaseDuration))
+	if got := rock.ResourceExtraction.MiningFleets; len(got) != 1 || got[0] != b.ID {
+		t.Fatalf("mining fleets after one release = %v", got)
+	}
+	if a.Gathering.IsGathering || len(ps.MiningOperations) != 1 {
+		t.Fatalf("after one release: gathering %v, operations %v", a.Gathering.IsGathering, ps.MiningOperations)
+	}
+	at = at.Add(ships.AnchorReleaseDuration)
+	if _, err := b.StartRelease(at); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	e.movementPhase(w, at, at.Add(ships.AnchorReleaseDuration))
+	if len(rock.ResourceExtraction.MiningFleets) != 0 || len(ps.MiningOperations) != 0 {
+		t.Fatalf("after both released: fleets %v, operations %v", rock.ResourceExtraction.MiningFleets, ps.MiningOperations)
+	}
+}
@@ -0,0 +1,117 @@
+package maps
+
+import (
+	"math"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ArrivalEvent is emitted when a finished leg leaves a stack inside an
+// orbitable's CollisionRadius. At is the moment the stack crossed the edge.
+type ArrivalEvent struct {
+	StackID    bson.ObjectID
+	PlayerID   bson.ObjectID
+	TargetID   bson.ObjectID
+	TargetType string // "system", "asteroid", "nebula"
+	X          float64
+	Y          float64
+	At         time.Time
+}
+
+// MovementEngine advances stacks along their Movement legs and detects
+// arrivals at the map's orbitables.
+type MovementEngine struct {
+	Systems   []*orbitables.System
+	Asteroids []*orbitables.Asteroid
+	Nebulas   []*orbitables.Nebula
+}
+
+type orbitableDisc struct {
+	id     bson.ObjectID
+	kind   string
+	x, y   float64
+	radius float64
+}
+
+// Advance moves the stack to its position at now and returns arrival events
+// for every leg completed on the way, in order.
+func (me *MovementEngine) Advance(stack *ships.ShipStack, now time.Time) []ArrivalEvent {
+	var events []ArrivalEvent
+	for _, c := range stack.AdvanceMovement(now) {
+		if ev, ok := me.arrivalFor(stack, c); ok {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// arrivalFor picks the orbitable the leg ended in. The leg's own TargetID
+// wins; otherwise the closest disc containing the end point is used.
+func (me *MovementEngine) arrivalFor(stack *ships.ShipStack, c ships.CompletedLeg) (ArrivalEvent, bool) {
+	var best *orbitableDisc
+	bestDist := math.MaxFloat64
+	for _, d := range me.discs() {
+		dist := math.Hypot(c.X-d.x, c.Y-d.y)
+		if dist > d.radius {
+			continue
+		}
+		if !c.Leg.TargetID.IsZero() && d.id == c.Leg.TargetID {
+			d := d
+			best = &d
+			break
+		}
+		if dist < bestDist {
+			d := d
+			best, bestDist = &d, dist
+		}
+	}
+	if best == nil {
+		return ArrivalEvent{}, false
+	}
+	return ArrivalEvent{
+		StackID:    stack.ID,
+		PlayerID:   stack.PlayerID,
+		TargetID:   best.id,
+		TargetType: best.kind,
+		X:          c.X,
+		Y:          c.Y,
+		At:         entryTime(c.Leg, best.x, best.y, best.radius),
+	}, true
+}
+
+func (me *MovementEngine) discs() []orbitableDisc {
+	out := make([]orbitableDisc, 0, len(me.Systems)+len(me.Asteroids)+len(me.Nebulas))
+	for _, s := range me.Systems {
+		out = append(out, orbitableDisc{s.ID, "system", s.X, s.Y, s.CollisionRadius})
+	}
+	for _, a := range me.Asteroids {
+		out = append(out, orbitableDisc{a.ID, "asteroid", a.X, a.Y, a.CollisionRadius})
+	}
+	for _, n := range me.Nebulas {
+		out = append(out, orbitableDisc{n.ID, "nebula", n.X, n.Y, n.CollisionRadius})
+	}
+	return out
+}
+
+// entryTime returns when the leg first entered the disc (cx, cy, r). Legs
+// that start inside the disc report their StartTime.
+func entryTime(leg *ships.MovementState, cx, cy, r float64) time.Time {
+	dx, dy := leg.TargetX-leg.StartX, leg.TargetY-leg.StartY
+	fx, fy := leg.StartX-cx, leg.StartY-cy
+	a := dx*dx + dy*dy
+	c := fx*fx + fy*fy - r*r
+	if a == 0 || c <= 0 {
+		return leg.StartTime
+	}
+	b := 2 * (fx*dx + fy*dy)
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return leg.EndTime
+	}
+	u := (-b - math.Sqrt(disc)) / (2 * a)
+	u = math.Max(0, math.Min(1, u))
+	return leg.StartTime.Add(time.Duration(u * float64(leg.EndTime.Sub(leg.StartTime))))
+}
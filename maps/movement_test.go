@@ -0,0 +1,49 @@
+package maps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMovementEngineArrivals(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	sys := &orbitables.System{ID: bson.NewObjectID(), X: 1000, CollisionRadius: 100}
+	rock := &orbitables.Asteroid{ID: bson.NewObjectID(), X: 1000, Y: 1000, CollisionRadius: 50}
+	me := &MovementEngine{Systems: []*orbitables.System{sys}, Asteroids: []*orbitables.Asteroid{rock}}
+
+	s := &ships.ShipStack{
+		ID:       bson.NewObjectID(),
+		PlayerID: bson.NewObjectID(),
+		Ships:    map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 2}}},
+	}
+	toSystem, _ := s.QueueMove(1000, 0, sys.ID, "system", now)
+	toRock, _ := s.QueueMove(1000, 1000, bson.NilObjectID, "", now)
+	s.QueueMove(3000, 3000, bson.NilObjectID, "", now)
+
+	if events := me.Advance(s, toSystem.EndTime.Add(-time.Second)); len(events) != 0 {
+		t.Fatalf("arrival before the leg ended: %+v", events)
+	}
+	events := me.Advance(s, now.Add(24*time.Hour))
+	if len(events) != 2 {
+		t.Fatalf("events = %+v", events)
+	}
+	enter := func(leg *ships.MovementState, frac float64) time.Time {
+		return leg.StartTime.Add(time.Duration(frac * float64(leg.EndTime.Sub(leg.StartTime))))
+	}
+	if ev := events[0]; ev.TargetID != sys.ID || ev.TargetType != "system" || !ev.At.Equal(enter(toSystem, 0.9)) {
+		t.Fatalf("system arrival = %+v, want entry at %v", ev, enter(toSystem, 0.9))
+	}
+	if ev := events[1]; ev.TargetID != rock.ID || ev.TargetType != "asteroid" || ev.X != 1000 || ev.Y != 1000 {
+		t.Fatalf("asteroid arrival = %+v", ev)
+	}
+	if d := events[1].At.Sub(enter(toRock, 0.95)); d < -time.Millisecond || d > time.Millisecond {
+		t.Fatalf("asteroid entered at %v, want %v", events[1].At, enter(toRock, 0.95))
+	}
+	if s.PositionX != 3000 || s.Movement != nil {
+		t.Fatalf("stack at (%.0f, %.0f) with legs %v", s.PositionX, s.PositionY, s.Movement)
+	}
+}
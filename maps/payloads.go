@@ -0,0 +1,288 @@
+package maps
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Payload is a typed queue payload. Validate checks the payload on its own;
+// rules that need game state belong to the ActionHandler.
+type Payload interface {
+	Validate() error
+}
+
+var ErrUnknownActionType = errors.New("unknown action type")
+
+// PayloadError describes an invalid payload field.
+type PayloadError struct {
+	Type    string
+	Field   string
+	Message string
+}
+
+func (e *PayloadError) Error() string {
+	if e.Field == "" {
+		return e.Type + ": " + e.Message
+	}
+	return e.Type + "." + e.Field + ": " + e.Message
+}
+
+// payloadRegistry maps each queue Type to a factory for its payload struct.
+var payloadRegistry = map[string]func() Payload{
+	ActionShipAttack:           func() Payload { return &AttackOrder{} },
+	ActionShipConstruction:     func() Payload { return &ShipBuildOrder{} },
+	ActionBuildingConstruction: func() Payload { return &BuildingOrder{} },
+	ActionShipAbility:          func() Payload { return &AbilityCast{} },
+	ActionFormationChange:      func() Payload { return &FormationChange{} },
+	ActionGemSocketChange:      func() Payload { return &GemSocketChange{} },
+	ActionRoleSwitch:           func() Payload { return &RoleSwitch{} },
+	ActionResearch:             func() Payload { return &ResearchOrder{} },
+	ActionAnchor:               func() Payload { return &AnchorOrder{} },
+	ActionEmbark:               func() Payload { return &EmbarkOrder{} },
+}
+
+// ResultPayload is a typed response payload. ActionType names the action it
+// answers.
+type ResultPayload interface {
+	ActionType() string
+}
+
+// resultRegistry maps each queue Type to a factory for its response payload.
+// Actions whose responses carry no payload are absent.
+var resultRegistry = map[string]func() ResultPayload{
+	ActionShipAttack:           func() ResultPayload { return &AttackResult{} },
+	ActionShipConstruction:     func() ResultPayload { return &ShipBuildResult{} },
+	ActionBuildingConstruction: func() ResultPayload { return &BuildingResult{} },
+	ActionResearch:             func() ResultPayload { return &ResearchResult{} },
+	ActionAnchor:               func() ResultPayload { return &AnchorResult{} },
+}
+
+// RegisterResult adds or replaces the response payload schema for an action
+// type. factory must return a pointer to a fresh result struct.
+func RegisterResult(actionType string, factory func() ResultPayload) {
+	resultRegistry[actionType] = factory
+}
+
+// NewResultPayload returns an empty response payload for actionType.
+func NewResultPayload(actionType string) (ResultPayload, error) {
+	f, ok := resultRegistry[actionType]
+	if !ok {
+		return nil, fmt.Errorf("%w: no result payload for %q", ErrUnknownActionType, actionType)
+	}
+	return f(), nil
+}
+
+// RegisterPayload adds or replaces the payload schema for an action type.
+// factory must return a pointer to a fresh payload struct.
+func RegisterPayload(actionType string, factory func() Payload) {
+	payloadRegistry[actionType] = factory
+}
+
+// IsKnownAction reports whether actionType has a registered payload schema.
+func IsKnownAction(actionType string) bool {
+	_, ok := payloadRegistry[actionType]
+	return ok
+}
+
+// NewPayload returns an empty payload for actionType.
+func NewPayload(actionType string) (Payload, error) {
+	f, ok := payloadRegistry[actionType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownActionType, actionType)
+	}
+	return f(), nil
+}
+
+// DecodeActionPayload decodes and validates a raw BSON payload for actionType.
+func DecodeActionPayload(actionType string, raw bson.Raw) (Payload, error) {
+	p, err := NewPayload(actionType)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > 0 {
+		if err := bson.Unmarshal(raw, p); err != nil {
+			return nil, fmt.Errorf("decode %s payload: %w", actionType, err)
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// TypedPayload checks that the queue item's payload is the struct registered
+// for its Type and validates it. An item without a payload is checked as an
+// empty one.
+func (q *Queue) TypedPayload() (Payload, error) {
+	want, err := NewPayload(q.Type)
+	if err != nil {
+		return nil, err
+	}
+	p := q.Payload
+	if p == nil {
+		p = want
+	} else if reflect.TypeOf(p) != reflect.TypeOf(want) {
+		return nil, &PayloadError{Type: q.Type, Message: fmt.Sprintf("expected %T, got %T", want, p)}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// NewQueueItem builds a queue item for actionType, rejecting unknown types,
+// payloads of the wrong struct and payloads that fail validation.
+func NewQueueItem(mapID, playerID bson.ObjectID, actionType string, sourceID, targetID bson.ObjectID, payload Payload, now time.Time) (*Queue, error) {
+	want, err := NewPayload(actionType)
+	if err != nil {
+		return nil, err
+	}
+	if payload == nil {
+		return nil, &PayloadError{Type: actionType, Message: "payload is required"}
+	}
+	if reflect.TypeOf(payload) != reflect.TypeOf(want) {
+		return nil, &PayloadError{Type: actionType, Message: fmt.Sprintf("expected %T, got %T", want, payload)}
+	}
+	if err := payload.Validate(); err != nil {
+		return nil, err
+	}
+	q := &Queue{
+		ID:        bson.NewObjectID(),
+		PlayerID:  playerID,
+		MapID:     mapID,
+		Type:      actionType,
+		SourceID:  sourceID,
+		TargetID:  targetID,
+		StartTime: now,
+		CreatedAt: now,
+		Payload:   payload,
+	}
+	return q, nil
+}
+
+// ValidateQueueItem checks an already-built queue item (e.g. one decoded from
+// a client request) before it is stored.
+func ValidateQueueItem(q *Queue) error {
+	_, err := q.TypedPayload()
+	return err
+}
+
+func (o *AttackOrder) Validate() error {
+	if o.TargetType != "" && o.TargetType != "stack" {
+		return &PayloadError{Type: ActionShipAttack, Field: "targetType", Message: "unsupported target type " + o.TargetType}
+	}
+	return nil
+}
+
+func (o *ShipBuildOrder) Validate() error {
+	if o.Cancel {
+		if o.OrderID.IsZero() {
+			return &PayloadError{Type: ActionShipConstruction, Field: "orderId", Message: "is required to cancel"}
+		}
+		return nil
+	}
+	if _, ok := ships.ShipBlueprints[ships.ShipType(o.ShipType)]; !ok {
+		return &PayloadError{Type: ActionShipConstruction, Field: "shipType", Message: "unknown ship type " + o.ShipType}
+	}
+	if o.Count <= 0 {
+		return &PayloadError{Type: ActionShipConstruction, Field: "count", Message: "must be positive"}
+	}
+	switch o.DeliverTo {
+	case "", "fleet", "stack":
+	default:
+		return &PayloadError{Type: ActionShipConstruction, Field: "deliverTo", Message: "must be fleet or stack"}
+	}
+	return nil
+}
+
+func (o *BuildingOrder) Validate() error {
+	if o.Slot == "" {
+		return &PayloadError{Type: ActionBuildingConstruction, Field: "slot", Message: "is required"}
+	}
+	return nil
+}
+
+func (o *ResearchOrder) Validate() error {
+	if o.Cancel {
+		return nil
+	}
+	if _, ok := ships.ResearchCatalog[o.Project]; !ok {
+		return &PayloadError{Type: ActionResearch, Field: "project", Message: "unknown project " + o.Project}
+	}
+	return nil
+}
+
+func (o *AnchorOrder) Validate() error {
+	if o.Release {
+		return nil
+	}
+	switch o.TargetType {
+	case "asteroid", "nebula", "system", orbitables.SalvageTargetType:
+		return nil
+	}
+	return &PayloadError{Type: ActionAnchor, Field: "targetType", Message: "must be asteroid, nebula, system or salvage"}
+}
+
+func (o *AbilityCast) Validate() error {
+	if _, ok := ships.ShipBlueprints[ships.ShipType(o.ShipType)]; !ok {
+		return &PayloadError{Type: ActionShipAbility, Field: "shipType", Message: "unknown ship type " + o.ShipType}
+	}
+	if _, ok := ships.AbilitiesCatalog[ships.AbilityID(o.Ability)]; !ok {
+		return &PayloadError{Type: ActionShipAbility, Field: "ability", Message: "unknown ability " + o.Ability}
+	}
+	return nil
+}
+
+func (o *FormationChange) Validate() error {
+	if _, ok := ships.FormationCatalog[ships.FormationType(o.Formation)]; !ok {
+		return &PayloadError{Type: ActionFormationChange, Field: "formation", Message: "unknown formation " + o.Formation}
+	}
+	return nil
+}
+
+func (o *GemSocketChange) Validate() error {
+	_, err := o.loadout()
+	return err
+}
+
+// loadout resolves the socketed gem IDs into a loadout.
+func (o *GemSocketChange) loadout() (ships.ShipLoadout, error) {
+	if _, ok := ships.ShipBlueprints[ships.ShipType(o.ShipType)]; !ok {
+		return ships.ShipLoadout{}, &PayloadError{Type: ActionGemSocketChange, Field: "shipType", Message: "unknown ship type " + o.ShipType}
+	}
+	load := ships.ShipLoadout{Sockets: make([]ships.Gem, 0, len(o.Sockets))}
+	for _, id := range o.Sockets {
+		g, ok := ships.GemCatalog[ships.GemID(id)]
+		if !ok {
+			return ships.ShipLoadout{}, &PayloadError{Type: ActionGemSocketChange, Field: "sockets", Message: "unknown gem " + id}
+		}
+		load.Sockets = append(load.Sockets, g)
+	}
+	if msg := ships.ValidateLoadout(load, ships.ShipType(o.ShipType)); msg != "" {
+		return ships.ShipLoadout{}, &PayloadError{Type: ActionGemSocketChange, Field: "sockets", Message: msg}
+	}
+	return load, nil
+}
+
+func (o *EmbarkOrder) Validate() error {
+	if _, ok := ships.ShipBlueprints[ships.ShipType(o.ShipType)]; !ok {
+		return &PayloadError{Type: ActionEmbark, Field: "shipType", Message: "unknown ship type " + o.ShipType}
+	}
+	if o.Count <= 0 {
+		return &PayloadError{Type: ActionEmbark, Field: "count", Message: "must be positive"}
+	}
+	return nil
+}
+
+func (o *RoleSwitch) Validate() error {
+	if _, ok := ships.RoleModesCatalog[ships.RoleMode(o.Role)]; !ok {
+		return &PayloadError{Type: ActionRoleSwitch, Field: "role", Message: "unknown role " + o.Role}
+	}
+	return nil
+}
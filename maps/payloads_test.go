@@ -0,0 +1,218 @@
+package maps
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestNewQueueItem(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		actionType string
+		payload    Payload
+		field      string // expected PayloadError field; "-" for any non-payload error
+		wantErr    error
+	}{
+		{"valid", ActionShipConstruction, &ShipBuildOrder{ShipType: string(ships.Fighter), Count: 2}, "", nil},
+		{"unknown action", "warp_gate", &AttackOrder{}, "-", ErrUnknownActionType},
+		{"missing payload", ActionShipAttack, nil, "", nil},
+		{"wrong struct", ActionShipAttack, &AnchorOrder{}, "", nil},
+		{"invalid payload", ActionShipConstruction, &ShipBuildOrder{ShipType: string(ships.Fighter)}, "count", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := NewQueueItem(bson.NewObjectID(), bson.NewObjectID(), tc.actionType, bson.NewObjectID(), bson.NilObjectID, tc.payload, now)
+			if tc.name == "valid" {
+				if err != nil || q.Payload != tc.payload || q.Type != tc.actionType || !q.CreatedAt.Equal(now) {
+					t.Fatalf("NewQueueItem = %+v, %v", q, err)
+				}
+				if err := ValidateQueueItem(q); err != nil {
+					t.Fatalf("ValidateQueueItem: %v", err)
+				}
+				return
+			}
+			if q != nil || err == nil {
+				t.Fatalf("NewQueueItem = %+v, %v", q, err)
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			var pe *PayloadError
+			if !errors.As(err, &pe) || pe.Type != tc.actionType || pe.Field != tc.field {
+				t.Fatalf("err = %#v, want a %s payload error on %q", err, tc.actionType, tc.field)
+			}
+		})
+	}
+}
+
+func TestDecodeActionPayload(t *testing.T) {
+	raw, _ := bson.Marshal(ShipBuildOrder{ShipType: string(ships.Cruiser), Count: 3, DeliverTo: "stack"})
+	p, err := DecodeActionPayload(ActionShipConstruction, raw)
+	if o, ok := p.(*ShipBuildOrder); err != nil || !ok || o.Count != 3 || o.DeliverTo != "stack" {
+		t.Fatalf("DecodeActionPayload = %#v, %v", p, err)
+	}
+
+	bad, _ := bson.Marshal(ShipBuildOrder{ShipType: string(ships.Cruiser), Count: 3, DeliverTo: "orbit"})
+	var pe *PayloadError
+	if _, err := DecodeActionPayload(ActionShipConstruction, bad); !errors.As(err, &pe) || pe.Field != "deliverTo" {
+		t.Fatalf("invalid payload: err = %v", err)
+	}
+	if _, err := DecodeActionPayload("warp_gate", raw); !errors.Is(err, ErrUnknownActionType) {
+		t.Fatalf("unknown action: err = %v", err)
+	}
+	if _, err := DecodeActionPayload(ActionRoleSwitch, nil); !errors.As(err, &pe) || pe.Field != "role" {
+		t.Fatalf("empty payload: err = %v", err)
+	}
+}
+
+func TestPayloadValidate(t *testing.T) {
+	laser1, laser2 := "laser-1", "laser-2"
+	tests := []struct {
+		name    string
+		payload Payload
+		field   string // "" when valid
+	}{
+		{"attack", &AttackOrder{TargetType: "stack"}, ""},
+		{"attack on a system", &AttackOrder{TargetType: "system"}, "targetType"},
+		{"ship build", &ShipBuildOrder{ShipType: string(ships.Drone), Count: 1, DeliverTo: "fleet"}, ""},
+		{"ship build unknown type", &ShipBuildOrder{ShipType: "dreadnought", Count: 1}, "shipType"},
+		{"ship build cancel", &ShipBuildOrder{Cancel: true, OrderID: bson.NewObjectID()}, ""},
+		{"ship build cancel without order", &ShipBuildOrder{Cancel: true}, "orderId"},
+		{"building", &BuildingOrder{Slot: "fusionReactor"}, ""},
+		{"building without slot", &BuildingOrder{}, "slot"},
+		{"research", &ResearchOrder{Project: "fighter_afterburners"}, ""},
+		{"research cancel", &ResearchOrder{Cancel: true}, ""},
+		{"research unknown project", &ResearchOrder{Project: "time_travel"}, "project"},
+		{"anchor", &AnchorOrder{TargetType: "asteroid"}, ""},
+		{"anchor on salvage", &AnchorOrder{TargetType: orbitables.SalvageTargetType}, ""},
+		{"anchor release", &AnchorOrder{Release: true}, ""},
+		{"anchor on a stack", &AnchorOrder{TargetType: "stack"}, "targetType"},
+		{"ability", &AbilityCast{ShipType: string(ships.Scout), Ability: string(ships.AbilityPing)}, ""},
+		{"ability unknown", &AbilityCast{ShipType: string(ships.Scout), Ability: "Teleport"}, "ability"},
+		{"ability unknown ship", &AbilityCast{ShipType: "dreadnought", Ability: string(ships.AbilityPing)}, "shipType"},
+		{"formation", &FormationChange{Formation: string(ships.FormationBox)}, ""},
+		{"formation unknown", &FormationChange{Formation: "circle"}, "formation"},
+		{"gems", &GemSocketChange{ShipType: string(ships.Fighter), Sockets: []string{laser1, laser2}}, ""},
+		{"gems duplicate", &GemSocketChange{ShipType: string(ships.Fighter), Sockets: []string{laser1, laser1}}, "sockets"},
+		{"gems unknown", &GemSocketChange{ShipType: string(ships.Fighter), Sockets: []string{"opal-9"}}, "sockets"},
+		{"role", &RoleSwitch{Role: string(ships.RoleEconomic)}, ""},
+		{"role unknown", &RoleSwitch{Role: "pirate"}, "role"},
+		{"embark", &EmbarkOrder{ShipType: string(ships.Fighter), Count: 10}, ""},
+		{"embark nothing", &EmbarkOrder{ShipType: string(ships.Fighter)}, "count"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.payload.Validate()
+			if tc.field == "" {
+				if err != nil {
+					t.Fatalf("Validate = %v", err)
+				}
+				return
+			}
+			var pe *PayloadError
+			if !errors.As(err, &pe) || pe.Field != tc.field {
+				t.Fatalf("Validate = %v, want an error on %q", err, tc.field)
+			}
+		})
+	}
+}
+
+func TestEveryPayloadHasAHandler(t *testing.T) {
+	p := NewQueueProcessor(newMemoryState(), newMemoryStore())
+	for actionType := range payloadRegistry {
+		if _, ok := p.handlers[actionType]; !ok {
+			t.Errorf("%s has a payload but no handler", actionType)
+		}
+	}
+}
+
+func TestStackConfigHandlers(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	s := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(),
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 4}}}}
+	s.EnsureFormationInitialized(now)
+	s.SetAnchored(ships.Fighter, true)
+	gs := newMemoryState(s)
+	p := NewQueueProcessor(gs, newMemoryStore())
+	run := func(actionType string, payload Payload) *ResponseQueue {
+		t.Helper()
+		q, err := NewQueueItem(bson.NilObjectID, s.PlayerID, actionType, s.ID, bson.NilObjectID, payload, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := p.Process(ctx, q, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if r := run(ActionFormationChange, &FormationChange{Formation: string(ships.FormationBox)}); r.Status != ResponseOK || s.Formation.Type != ships.FormationBox || !r.EndTime.Equal(s.FormationReconfigUntil) {
+		t.Fatalf("formation change: %+v", r)
+	}
+	if r := run(ActionGemSocketChange, &GemSocketChange{ShipType: string(ships.Fighter), Sockets: []string{"laser-1"}}); r.Status != ResponseOK {
+		t.Fatalf("gem socket change: %+v", r)
+	}
+	if load := s.Loadouts[ships.Fighter]; len(load.Sockets) != 1 || load.Sockets[0].ID != "laser-1" || !load.Anchored {
+		t.Fatalf("loadout = %+v", load)
+	}
+	if r := run(ActionGemSocketChange, &GemSocketChange{ShipType: string(ships.Cruiser), Sockets: []string{"laser-1"}}); r.Status != ResponseRejected {
+		t.Fatalf("socketing an absent ship type: %+v", r)
+	}
+	r := run(ActionRoleSwitch, &RoleSwitch{Role: string(ships.RoleEconomic)})
+	if r.Status != ResponseOK || s.Role != ships.RoleEconomic || s.InRole(ships.RoleEconomic, now) || !s.InRole(ships.RoleEconomic, r.EndTime) {
+		t.Fatalf("role switch: %+v, role %s until %v", r, s.Role, s.ReconfigureUntil)
+	}
+
+	s.Battle = &ships.BattleState{IsInCombat: true}
+	if r := run(ActionFormationChange, &FormationChange{Formation: string(ships.FormationLine)}); r.Status != ResponseRejected || s.Formation.Type != ships.FormationBox {
+		t.Fatalf("formation change in combat: %+v", r)
+	}
+}
+
+func TestEmbarkAndHangarLaunch(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(),
+		Ships: map[ships.ShipType][]ships.HPBucket{
+			ships.Carrier: {{HP: ships.ShipBlueprints[ships.Carrier].HP, Count: 1}},
+			ships.Fighter: {{HP: ships.ShipBlueprints[ships.Fighter].HP, Count: 10}},
+			ships.Cruiser: {{HP: ships.ShipBlueprints[ships.Cruiser].HP, Count: 1}},
+		}}
+	s.EnsureFormationInitialized(now)
+	p := NewQueueProcessor(newMemoryState(s), newMemoryStore())
+	run := func(actionType string, payload Payload) *ResponseQueue {
+		t.Helper()
+		q, err := NewQueueItem(bson.NilObjectID, s.PlayerID, actionType, s.ID, bson.NilObjectID, payload, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := p.Process(ctx, q, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if r := run(ActionEmbark, &EmbarkOrder{ShipType: string(ships.Cruiser), Count: 1}); r.Status != ResponseRejected || s.HangarUsed() != 0 {
+		t.Fatalf("embarking a cruiser: %+v", r)
+	}
+	if r := run(ActionEmbark, &EmbarkOrder{ShipType: string(ships.Fighter), Count: 10}); r.Status != ResponseOK || s.HangarUsed() != 10 || len(s.Ships[ships.Fighter]) != 0 {
+		t.Fatalf("embark: %+v, hangar %v", r, s.Hangar)
+	}
+	r := run(ActionShipAbility, &AbilityCast{ShipType: string(ships.Carrier), Ability: string(ships.AbilityHangarLaunch)})
+	if r.Status != ResponseOK || s.Hangar != nil || s.Ships[ships.Fighter][0].Count != 10 || !r.EndTime.After(now) {
+		t.Fatalf("launch: %+v, ships %v, hangar %v", r, s.Ships, s.Hangar)
+	}
+}
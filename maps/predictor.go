@@ -0,0 +1,375 @@
+package maps
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/ships"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	EventCollision   = "collision"
+	EventAttackRange = "attack_range"
+
+	// StackCollisionRadius is the notional radius of a stack for collision checks.
+	StackCollisionRadius = 50.0
+	// DefaultPredictionHorizon bounds how far ahead the predictor looks.
+	DefaultPredictionHorizon = 6 * time.Hour
+)
+
+// Predictor computes, for every pair of stacks of different players where at
+// least one is moving, the earliest time they collide and the earliest time
+// each comes within the other's attack range. Pairs already in range at the
+// time of prediction produce no event.
+//
+// The predictor keeps the stacks it was given and remembers the course each
+// prediction was built from. Refresh re-predicts every stack whose legs have
+// changed since (new leg, interdiction, retime, arrival), so calling it once
+// per tick after movement keeps predictions current. Invalidate does the same
+// for a single stack and must be called by hand for changes that do not touch
+// the legs, such as a new attack range after losses.
+type Predictor struct {
+	Horizon time.Duration
+
+	stacks  map[bson.ObjectID]*ships.ShipStack
+	ranges  map[bson.ObjectID]float64
+	courses map[bson.ObjectID][]courseLeg
+	events  map[bson.ObjectID][]PredictedEvent
+}
+
+// courseLeg is the part of a travel leg a prediction depends on.
+type courseLeg struct {
+	startX, startY   float64
+	targetX, targetY float64
+	start, end       time.Time
+}
+
+// pathSegment is a linear stretch of a stack's path: position p + v*(t-from).
+type pathSegment struct {
+	from, to time.Time
+	x, y     float64
+	vx, vy   float64 // units per second
+}
+
+// NewPredictor builds predictions for all pairs at now.
+func NewPredictor(stacks []*ships.ShipStack, horizon time.Duration, now time.Time) *Predictor {
+	if horizon <= 0 {
+		horizon = DefaultPredictionHorizon
+	}
+	p := &Predictor{
+		Horizon: horizon,
+		stacks:  make(map[bson.ObjectID]*ships.ShipStack, len(stacks)),
+		ranges:  make(map[bson.ObjectID]float64, len(stacks)),
+		courses: make(map[bson.ObjectID][]courseLeg, len(stacks)),
+		events:  make(map[bson.ObjectID][]PredictedEvent),
+	}
+	for _, s := range stacks {
+		p.stacks[s.ID] = s
+		p.ranges[s.ID] = float64(ships.ComputeStackAttackRange(s, now))
+		p.courses[s.ID] = courseOf(s)
+	}
+	list := p.sortedStacks()
+	for i := 0; i < len(list); i++ {
+		for j := i + 1; j < len(list); j++ {
+			p.predictPair(list[i], list[j], now)
+		}
+	}
+	p.sortEvents()
+	return p
+}
+
+// Invalidate replaces every prediction involving the stack after it changed
+// course. Passing a stack not yet known adds it.
+func (p *Predictor) Invalidate(stack *ships.ShipStack, now time.Time) {
+	p.stacks[stack.ID] = stack
+	p.ranges[stack.ID] = float64(ships.ComputeStackAttackRange(stack, now))
+	p.courses[stack.ID] = courseOf(stack)
+	p.dropInvolving(stack.ID)
+	for _, other := range p.sortedStacks() {
+		if other.ID != stack.ID {
+			p.predictPair(stack, other, now)
+		}
+	}
+	p.sortEvents()
+}
+
+// Refresh invalidates every stack whose legs changed since its predictions
+// were built and returns their IDs in order.
+func (p *Predictor) Refresh(now time.Time) []bson.ObjectID {
+	var changed []bson.ObjectID
+	for _, s := range p.sortedStacks() {
+		if !sameCourse(p.courses[s.ID], courseOf(s)) {
+			changed = append(changed, s.ID)
+		}
+	}
+	for _, id := range changed {
+		p.Invalidate(p.stacks[id], now)
+	}
+	return changed
+}
+
+// Remove forgets a stack and all predictions involving it.
+func (p *Predictor) Remove(id bson.ObjectID) {
+	delete(p.stacks, id)
+	delete(p.ranges, id)
+	delete(p.courses, id)
+	p.dropInvolving(id)
+}
+
+// EventsFor returns the stack's pending predictions, earliest first.
+func (p *Predictor) EventsFor(id bson.ObjectID) []PredictedEvent {
+	return p.events[id]
+}
+
+// Prune drops predictions whose time has passed.
+func (p *Predictor) Prune(now time.Time) {
+	for id, evs := range p.events {
+		kept := evs[:0]
+		for _, ev := range evs {
+			if !ev.Timestamp.Before(now) {
+				kept = append(kept, ev)
+			}
+		}
+		if len(kept) == 0 {
+			delete(p.events, id)
+		} else {
+			p.events[id] = kept
+		}
+	}
+}
+
+// ApplyToQueue copies the source stack's predictions onto a queue item.
+func (p *Predictor) ApplyToQueue(q *Queue) {
+	q.PredictedEvents = append([]PredictedEvent(nil), p.events[q.SourceID]...)
+}
+
+// ApplyToResponse copies predictions onto a response and lists every stack
+// taking part, source first.
+func (p *Predictor) ApplyToResponse(r *ResponseQueue) {
+	evs := p.events[r.SourceID]
+	r.PredictedEvents = append([]PredictedEvent(nil), evs...)
+	r.EventParticipants = nil
+	if len(evs) == 0 {
+		return
+	}
+	seen := map[bson.ObjectID]bool{r.SourceID: true}
+	r.EventParticipants = append(r.EventParticipants, r.SourceID)
+	for _, ev := range evs {
+		if !seen[ev.WithID] {
+			seen[ev.WithID] = true
+			r.EventParticipants = append(r.EventParticipants, ev.WithID)
+		}
+	}
+}
+
+func (p *Predictor) predictPair(a, b *ships.ShipStack, now time.Time) {
+	if a.PlayerID == b.PlayerID {
+		return
+	}
+	pa, pb := p.path(a, now), p.path(b, now)
+	if len(pa) == 1 && len(pb) == 1 && pa[0].vx == 0 && pa[0].vy == 0 && pb[0].vx == 0 && pb[0].vy == 0 {
+		return
+	}
+
+	if t, ok := earliestWithin(pa, pb, 2*StackCollisionRadius, now); ok {
+		ax, ay := positionOn(pa, t)
+		bx, by := positionOn(pb, t)
+		mx, my := (ax+bx)/2, (ay+by)/2
+		p.events[a.ID] = append(p.events[a.ID], PredictedEvent{Type: EventCollision, Timestamp: t, WithID: b.ID, WithType: "stack", AtX: mx, AtY: my})
+		p.events[b.ID] = append(p.events[b.ID], PredictedEvent{Type: EventCollision, Timestamp: t, WithID: a.ID, WithType: "stack", AtX: mx, AtY: my})
+	}
+	if r := p.ranges[a.ID]; r > 0 {
+		if t, ok := earliestWithin(pa, pb, r, now); ok {
+			x, y := positionOn(pb, t)
+			p.events[a.ID] = append(p.events[a.ID], PredictedEvent{Type: EventAttackRange, Timestamp: t, WithID: b.ID, WithType: "stack", AtX: x, AtY: y})
+		}
+	}
+	if r := p.ranges[b.ID]; r > 0 {
+		if t, ok := earliestWithin(pb, pa, r, now); ok {
+			x, y := positionOn(pa, t)
+			p.events[b.ID] = append(p.events[b.ID], PredictedEvent{Type: EventAttackRange, Timestamp: t, WithID: a.ID, WithType: "stack", AtX: x, AtY: y})
+		}
+	}
+}
+
+// path flattens the stack's Movement into segments covering [now, now+Horizon].
+func (p *Predictor) path(s *ships.ShipStack, now time.Time) []pathSegment {
+	end := now.Add(p.Horizon)
+	x, y := s.PositionAt(now)
+	cursor := now
+	var segs []pathSegment
+	for _, leg := range s.Movement {
+		if leg == nil || !leg.IsTravelLeg() || !cursor.Before(end) {
+			break
+		}
+		if !leg.EndTime.After(cursor) {
+			continue
+		}
+		if leg.StartTime.After(cursor) {
+			stop := minT(leg.StartTime, end)
+			segs = append(segs, pathSegment{from: cursor, to: stop, x: x, y: y})
+			cursor = stop
+			x, y = leg.StartX, leg.StartY
+		}
+		if !cursor.Before(end) {
+			break
+		}
+		span := leg.EndTime.Sub(leg.StartTime).Seconds()
+		var vx, vy float64
+		if span > 0 {
+			vx, vy = (leg.TargetX-leg.StartX)/span, (leg.TargetY-leg.StartY)/span
+		}
+		x, y = leg.PositionAt(cursor)
+		stop := minT(leg.EndTime, end)
+		segs = append(segs, pathSegment{from: cursor, to: stop, x: x, y: y, vx: vx, vy: vy})
+		cursor = stop
+		x, y = leg.PositionAt(stop)
+	}
+	if cursor.Before(end) || len(segs) == 0 {
+		segs = append(segs, pathSegment{from: cursor, to: end, x: x, y: y})
+	}
+	return segs
+}
+
+// earliestWithin finds the first t where |b(t)-a(t)| <= r. It reports false
+// if the pair never gets that close or is already that close at now.
+func earliestWithin(pa, pb []pathSegment, r float64, now time.Time) (time.Time, bool) {
+	ax, ay := positionOn(pa, now)
+	bx, by := positionOn(pb, now)
+	if math.Hypot(bx-ax, by-ay) <= r {
+		return time.Time{}, false
+	}
+	i, j := 0, 0
+	for i < len(pa) && j < len(pb) {
+		sa, sb := pa[i], pb[j]
+		from := maxT(sa.from, sb.from)
+		to := minT(sa.to, sb.to)
+		if to.After(from) {
+			ax, ay := segPos(sa, from)
+			bx, by := segPos(sb, from)
+			dx, dy := bx-ax, by-ay
+			dvx, dvy := sb.vx-sa.vx, sb.vy-sa.vy
+			if u, ok := firstRoot(dx, dy, dvx, dvy, r, to.Sub(from).Seconds()); ok {
+				return from.Add(time.Duration(u * float64(time.Second))), true
+			}
+		}
+		if sa.to.Before(sb.to) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return time.Time{}, false
+}
+
+// firstRoot solves |d + dv*u| = r for the smallest u in [0, maxU].
+func firstRoot(dx, dy, dvx, dvy, r, maxU float64) (float64, bool) {
+	c := dx*dx + dy*dy - r*r
+	if c <= 0 {
+		return 0, true
+	}
+	a := dvx*dvx + dvy*dvy
+	if a == 0 {
+		return 0, false
+	}
+	b := 2 * (dx*dvx + dy*dvy)
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return 0, false
+	}
+	u := (-b - math.Sqrt(disc)) / (2 * a)
+	if u < 0 || u > maxU {
+		return 0, false
+	}
+	return u, true
+}
+
+func segPos(s pathSegment, t time.Time) (float64, float64) {
+	dt := t.Sub(s.from).Seconds()
+	return s.x + s.vx*dt, s.y + s.vy*dt
+}
+
+func positionOn(segs []pathSegment, t time.Time) (float64, float64) {
+	for _, s := range segs {
+		if !t.After(s.to) {
+			return segPos(s, maxT(t, s.from))
+		}
+	}
+	last := segs[len(segs)-1]
+	return segPos(last, last.to)
+}
+
+func courseOf(s *ships.ShipStack) []courseLeg {
+	var out []courseLeg
+	for _, leg := range s.Movement {
+		if leg == nil || !leg.IsTravelLeg() {
+			break
+		}
+		out = append(out, courseLeg{leg.StartX, leg.StartY, leg.TargetX, leg.TargetY, leg.StartTime, leg.EndTime})
+	}
+	return out
+}
+
+func sameCourse(a, b []courseLeg) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].startX != b[i].startX || a[i].startY != b[i].startY || a[i].targetX != b[i].targetX || a[i].targetY != b[i].targetY ||
+			!a[i].start.Equal(b[i].start) || !a[i].end.Equal(b[i].end) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Predictor) dropInvolving(id bson.ObjectID) {
+	delete(p.events, id)
+	for sid, evs := range p.events {
+		kept := evs[:0]
+		for _, ev := range evs {
+			if ev.WithID != id {
+				kept = append(kept, ev)
+			}
+		}
+		if len(kept) == 0 {
+			delete(p.events, sid)
+		} else {
+			p.events[sid] = kept
+		}
+	}
+}
+
+func (p *Predictor) sortEvents() {
+	for _, evs := range p.events {
+		sort.SliceStable(evs, func(i, j int) bool { return evs[i].Timestamp.Before(evs[j].Timestamp) })
+	}
+}
+
+func (p *Predictor) sortedStacks() []*ships.ShipStack {
+	ids := make(map[bson.ObjectID]bool, len(p.stacks))
+	for id := range p.stacks {
+		ids[id] = true
+	}
+	out := make([]*ships.ShipStack, 0, len(ids))
+	for _, id := range sortedIDs(ids) {
+		out = append(out, p.stacks[id])
+	}
+	return out
+}
+
+func maxT(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minT(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+package maps
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func predictorTestStack(player bson.ObjectID, x, y float64) *ships.ShipStack {
+	return &ships.ShipStack{
+		ID:        bson.NewObjectID(),
+		PlayerID:  player,
+		PositionX: x,
+		PositionY: y,
+		Ships:     map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 2}}},
+	}
+}
+
+func TestPredictorCollisionAndRange(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mover := predictorTestStack(bson.NewObjectID(), 0, 0)
+	target := predictorTestStack(bson.NewObjectID(), 5000, 0)
+	friend := predictorTestStack(mover.PlayerID, 5000, 0)
+	leg, err := mover.QueueMove(5000, 0, bson.NilObjectID, "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPredictor([]*ships.ShipStack{mover, target, friend}, 0, now)
+	if evs := p.EventsFor(friend.ID); len(evs) != 0 {
+		t.Fatalf("allied stacks got predictions: %+v", evs)
+	}
+	evs := p.EventsFor(mover.ID)
+	var collision *PredictedEvent
+	for i := range evs {
+		if evs[i].WithID != target.ID {
+			t.Fatalf("prediction against %s", evs[i].WithID.Hex())
+		}
+		if i > 0 && evs[i].Timestamp.Before(evs[i-1].Timestamp) {
+			t.Fatal("predictions not sorted")
+		}
+		if evs[i].Type == EventCollision {
+			collision = &evs[i]
+		}
+	}
+	frac := (5000 - 2*StackCollisionRadius) / 5000
+	want := leg.StartTime.Add(time.Duration(frac * float64(leg.EndTime.Sub(leg.StartTime))))
+	if collision == nil || collision.Timestamp.Sub(want).Abs() > time.Millisecond {
+		t.Fatalf("collision = %+v, want at %v", collision, want)
+	}
+	if r := ships.ComputeStackAttackRange(mover, now); r > 0 && len(evs) != 2 {
+		t.Fatalf("range %d but no attack_range prediction: %+v", r, evs)
+	}
+	if len(p.EventsFor(target.ID)) == 0 {
+		t.Fatal("target has no predictions")
+	}
+
+	p.Prune(want.Add(time.Second))
+	if evs := p.EventsFor(mover.ID); len(evs) != 0 {
+		t.Fatalf("past predictions kept: %+v", evs)
+	}
+}
+
+func TestPredictorRefreshFollowsCourseChanges(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mover := predictorTestStack(bson.NewObjectID(), 0, 0)
+	target := predictorTestStack(bson.NewObjectID(), 5000, 0)
+	mover.QueueMove(5000, 0, bson.NilObjectID, "", now)
+
+	p := NewPredictor([]*ships.ShipStack{mover, target}, 0, now)
+	if changed := p.Refresh(now); len(changed) != 0 {
+		t.Fatalf("unchanged course refreshed: %v", changed)
+	}
+	if len(p.EventsFor(target.ID)) == 0 {
+		t.Fatal("no predictions before the course change")
+	}
+
+	later := now.Add(time.Minute)
+	mover.ClearMovement(later)
+	mover.QueueMove(mover.PositionX, -20000, bson.NilObjectID, "", later)
+	changed := p.Refresh(later)
+	if len(changed) != 1 || changed[0] != mover.ID {
+		t.Fatalf("Refresh = %v, want the mover", changed)
+	}
+	if evs := p.EventsFor(target.ID); len(evs) != 0 {
+		t.Fatalf("stale predictions survived the course change: %+v", evs)
+	}
+	if changed := p.Refresh(later); len(changed) != 0 {
+		t.Fatalf("second Refresh = %v", changed)
+	}
+}
@@ -24,7 +24,7 @@ type Queue struct {
 	ProcessedAt     time.Time        `bson:"processedAt,omitempty" json:"processedAt"` // When the action was processed
 	PredictedEvents []PredictedEvent `bson:"predictedEvents,omitempty" json:"predictedEvents,omitempty"`
 	Version         int64            `bson:"version" json:"version"`                     // For optimistic locking
-	Payload         bson.D           `bson:"payload,omitempty" json:"payload,omitempty"` // Additional action-specific data
+	Payload         Payload          `bson:"payload,omitempty" json:"payload,omitempty"` // Action-specific data; the struct registered for Type
 }
 
 type ResponseQueue struct {
@@ -44,9 +44,11 @@ type ResponseQueue struct {
 	CreatedAt         time.Time        `bson:"createdAt" json:"createdAt"`                 // When the action was created
 	ProcessedAt       time.Time        `bson:"processedAt,omitempty" json:"processedAt"`   // When the action was processed
 	Version           int64            `bson:"version" json:"version"`                     // For optimistic locking
-	Payload           bson.D           `bson:"payload,omitempty" json:"payload,omitempty"` // Additional action-specific data
+	Payload           ResultPayload    `bson:"payload,omitempty" json:"payload,omitempty"` // Result data; the struct registered for Type
 	PredictedEvents   []PredictedEvent `bson:"predictedEvents,omitempty" json:"predictedEvents,omitempty"`
 	EventParticipants []bson.ObjectID  `bson:"eventParticipants,omitempty" json:"eventParticipants,omitempty"` // Participants in the event
+	Status            string           `bson:"status,omitempty" json:"status,omitempty"`                       // "ok", "rejected", "failed", "conflict"
+	Error             string           `bson:"error,omitempty" json:"error,omitempty"`                         // Reason when Status is not "ok"
 }
 
 type PredictedEvent struct {
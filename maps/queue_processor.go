@@ -0,0 +1,388 @@
+package maps
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Response statuses.
+const (
+	ResponseOK       = "ok"
+	ResponseRejected = "rejected" // validation failed; the action had no effect
+	ResponseFailed   = "failed"   // execution error
+	ResponseConflict = "conflict" // still conflicting after MaxRetries
+)
+
+const DefaultMaxRetries = 3
+
+var (
+	ErrVersionConflict = errors.New("version conflict")
+	ErrNotOwner        = errors.New("source does not belong to the acting player")
+	ErrOutOfRange      = errors.New("target is out of range")
+	ErrInvalidTarget   = errors.New("invalid target")
+)
+
+// GameState is the persistence the processor and built-in handlers need.
+// Save methods must perform an optimistic-locking write on Version and return
+// ErrVersionConflict when the stored document moved on.
+type GameState interface {
+	Stack(ctx context.Context, id bson.ObjectID) (*ships.ShipStack, error)
+	SaveStack(ctx context.Context, s *ships.ShipStack) error
+	System(ctx context.Context, id bson.ObjectID) (*orbitables.System, error)
+	SaveSystem(ctx context.Context, s *orbitables.System) error
+	Phantoms(ctx context.Context, mapID bson.ObjectID) (ships.PhantomSet, error)
+	SavePhantom(ctx context.Context, p *ships.PhantomContact) error
+	SaveBattleReport(ctx context.Context, r *ships.BattleReport) error
+}
+
+// QueueStore persists queue items and responses.
+type QueueStore interface {
+	// Reload fetches the latest version of a queue item.
+	Reload(ctx context.Context, id bson.ObjectID) (*Queue, error)
+	// MarkProcessed sets ProcessedAt conditioned on q.Version, returning
+	// ErrVersionConflict if the item changed.
+	MarkProcessed(ctx context.Context, q *Queue, now time.Time) error
+	WriteResponse(ctx context.Context, r *ResponseQueue) error
+}
+
+// ActionResult is what a handler reports back for the ResponseQueue entry.
+type ActionResult struct {
+	Payload      ResultPayload
+	TargetX      float64
+	TargetY      float64
+	EndTime      time.Time
+	Participants []bson.ObjectID
+}
+
+// ActionHandler validates and executes one action type. Validate must not
+// mutate state. Execute may return ErrVersionConflict to request a retry with
+// freshly loaded state.
+type ActionHandler interface {
+	Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error
+	Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error)
+}
+
+// QueueProcessor drains queue items through typed handlers.
+type QueueProcessor struct {
+	State      GameState
+	Store      QueueStore
+	MaxRetries int
+
+	handlers map[string]ActionHandler
+}
+
+// NewQueueProcessor creates a processor with a built-in handler registered for
+// every action type in the payload registry.
+func NewQueueProcessor(state GameState, store QueueStore) *QueueProcessor {
+	p := &QueueProcessor{
+		State:      state,
+		Store:      store,
+		MaxRetries: DefaultMaxRetries,
+		handlers:   make(map[string]ActionHandler),
+	}
+	p.Register(ActionShipAttack, AttackHandler{})
+	p.Register(ActionShipAbility, AbilityHandler{})
+	p.Register(ActionShipConstruction, ShipConstructionHandler{})
+	p.Register(ActionBuildingConstruction, BuildingConstructionHandler{})
+	p.Register(ActionResearch, ResearchHandler{})
+	p.Register(ActionAnchor, AnchorHandler{})
+	p.Register(ActionFormationChange, FormationChangeHandler{})
+	p.Register(ActionGemSocketChange, GemSocketHandler{})
+	p.Register(ActionRoleSwitch, RoleSwitchHandler{})
+	p.Register(ActionEmbark, EmbarkHandler{})
+	return p
+}
+
+// Register installs or replaces the handler for an action type.
+func (p *QueueProcessor) Register(actionType string, h ActionHandler) {
+	p.handlers[actionType] = h
+}
+
+// ProcessBatch handles items in a deterministic order (CreatedAt, then ID)
+// and returns one response per item.
+func (p *QueueProcessor) ProcessBatch(ctx context.Context, items []*Queue, now time.Time) []*ResponseQueue {
+	sorted := append([]*Queue(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+		return bytes.Compare(sorted[i].ID[:], sorted[j].ID[:]) < 0
+	})
+	out := make([]*ResponseQueue, 0, len(sorted))
+	for _, q := range sorted {
+		r, err := p.Process(ctx, q, now)
+		if err != nil && r == nil {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Process validates and executes one queue item, retrying on version
+// conflicts, then writes the response. The returned error is only non-nil
+// when the response itself could not be stored.
+func (p *QueueProcessor) Process(ctx context.Context, q *Queue, now time.Time) (*ResponseQueue, error) {
+	resp := newResponse(q, now)
+
+	if _, err := q.TypedPayload(); err != nil {
+		resp.Status, resp.Error = ResponseRejected, err.Error()
+		return resp, p.finish(ctx, q, resp, now)
+	}
+	h, ok := p.handlers[q.Type]
+	if !ok {
+		resp.Status, resp.Error = ResponseRejected, fmt.Errorf("%w: no handler for %q", ErrUnknownActionType, q.Type).Error()
+		return resp, p.finish(ctx, q, resp, now)
+	}
+
+	retries := p.MaxRetries
+	if retries <= 0 {
+		retries = DefaultMaxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		err := h.Validate(ctx, p.State, q, now)
+		if err != nil {
+			resp.Status, resp.Error = ResponseRejected, err.Error()
+			break
+		}
+		res, err := h.Execute(ctx, p.State, q, now)
+		if errors.Is(err, ErrVersionConflict) {
+			if attempt >= retries {
+				resp.Status, resp.Error = ResponseConflict, err.Error()
+				break
+			}
+			if fresh, rerr := p.Store.Reload(ctx, q.ID); rerr == nil && fresh != nil {
+				q = fresh
+			}
+			continue
+		}
+		if err != nil {
+			resp.Status, resp.Error = ResponseFailed, err.Error()
+			break
+		}
+		resp.Status = ResponseOK
+		if res.TargetX != 0 || res.TargetY != 0 {
+			resp.TargetX, resp.TargetY = res.TargetX, res.TargetY
+		}
+		if !res.EndTime.IsZero() {
+			resp.EndTime = res.EndTime
+		}
+		resp.EventParticipants = res.Participants
+		resp.Payload = res.Payload
+		break
+	}
+	return resp, p.finish(ctx, q, resp, now)
+}
+
+func (p *QueueProcessor) finish(ctx context.Context, q *Queue, resp *ResponseQueue, now time.Time) error {
+	for attempt := 0; ; attempt++ {
+		err := p.Store.MarkProcessed(ctx, q, now)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrVersionConflict) || attempt >= p.MaxRetries {
+			return err
+		}
+		fresh, rerr := p.Store.Reload(ctx, q.ID)
+		if rerr != nil {
+			return rerr
+		}
+		if !fresh.ProcessedAt.IsZero() {
+			// Another worker already handled it; do not write a second response.
+			return nil
+		}
+		q = fresh
+	}
+	q.ProcessedAt = now
+	return p.Store.WriteResponse(ctx, resp)
+}
+
+func newResponse(q *Queue, now time.Time) *ResponseQueue {
+	return &ResponseQueue{
+		MapID:       q.MapID,
+		PlayerID:    q.PlayerID,
+		QueueItemID: q.ID,
+		Type:        q.Type,
+		TargetID:    q.TargetID,
+		SourceID:    q.SourceID,
+		StartX:      q.StartX,
+		StartY:      q.StartY,
+		TargetX:     q.TargetX,
+		TargetY:     q.TargetY,
+		StartTime:   q.StartTime,
+		EndTime:     q.EndTime,
+		CreatedAt:   now,
+		ProcessedAt: now,
+	}
+}
+
+// loadOwnedStack loads the source stack and checks ownership.
+func loadOwnedStack(ctx context.Context, gs GameState, q *Queue) (*ships.ShipStack, error) {
+	s, err := gs.Stack(ctx, q.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	if s.PlayerID != q.PlayerID {
+		return nil, ErrNotOwner
+	}
+	return s, nil
+}
+
+// AttackHandler starts a battle between the source stack and a target stack
+// within the attacker's range.
+type AttackHandler struct{}
+
+func (AttackHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	if _, ok := q.Payload.(*AttackOrder); !ok {
+		return unexpectedPayload(q)
+	}
+	phantoms, err := gs.Phantoms(ctx, q.MapID)
+	if err != nil {
+		return err
+	}
+	if err := phantoms.CheckAttackTarget(q.TargetID); err != nil {
+		return err
+	}
+	attacker, err := loadOwnedStack(ctx, gs, q)
+	if err != nil {
+		return err
+	}
+	defender, err := gs.Stack(ctx, q.TargetID)
+	if err != nil {
+		return err
+	}
+	if defender.PlayerID == attacker.PlayerID || defender.MapID != attacker.MapID {
+		return ErrInvalidTarget
+	}
+	ax, ay := attacker.PositionAt(now)
+	dx, dy := defender.PositionAt(now)
+	if math.Hypot(ax-dx, ay-dy) > float64(ships.ComputeStackAttackRange(attacker, now)) {
+		return ErrOutOfRange
+	}
+	return nil
+}
+
+func (AttackHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	attacker, err := gs.Stack(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	defender, err := gs.Stack(ctx, q.TargetID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	x, y := defender.PositionAt(now)
+	location := ships.BattleLocation{Type: "empty_space", X: x, Y: y}
+	report := ships.InitiateBattle(attacker, defender, location, now)
+	attacker.RecordAttack(now)
+
+	if err := gs.SaveStack(ctx, attacker); err != nil {
+		return ActionResult{}, err
+	}
+	if err := gs.SaveStack(ctx, defender); err != nil {
+		return ActionResult{}, err
+	}
+	if err := gs.SaveBattleReport(ctx, report); err != nil {
+		return ActionResult{}, err
+	}
+	return ActionResult{
+		Payload:      &AttackResult{BattleID: report.BattleID},
+		TargetX:      location.X,
+		TargetY:      location.Y,
+		Participants: []bson.ObjectID{attacker.ID, defender.ID},
+	}, nil
+}
+
+// AbilityHandler activates an ability on the source stack. Ping marks its
+// target (or exposes a phantom); DecoyBeacon projects a phantom at TargetX/Y;
+// HangarLaunch deploys everything embarked.
+type AbilityHandler struct{}
+
+func (AbilityHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	cast, ok := q.Payload.(*AbilityCast)
+	if !ok {
+		return unexpectedPayload(q)
+	}
+	s, err := loadOwnedStack(ctx, gs, q)
+	if err != nil {
+		return err
+	}
+	if !s.HasAbility(ships.ShipType(cast.ShipType), ships.AbilityID(cast.Ability)) {
+		return ships.ErrAbilityUnavailable
+	}
+	return nil
+}
+
+func (AbilityHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	cast, ok := q.Payload.(*AbilityCast)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	s, err := gs.Stack(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	id := ships.AbilityID(cast.Ability)
+	res := ActionResult{Participants: []bson.ObjectID{s.ID}}
+
+	switch id {
+	case ships.AbilityDecoyBeacon:
+		p, err := s.DeployDecoyBeacon(q.TargetX, q.TargetY, nil, now)
+		if err != nil {
+			return ActionResult{}, err
+		}
+		if err := gs.SavePhantom(ctx, p); err != nil {
+			return ActionResult{}, err
+		}
+		res.EndTime = p.ExpiresAt
+	case ships.AbilityHangarLaunch:
+		if _, err := s.HangarLaunch(now); err != nil {
+			return ActionResult{}, err
+		}
+		if st := s.ActiveAbility(id, now); st != nil {
+			res.EndTime = st.EndTime
+		}
+	default:
+		st, err := s.ActivateAbility(ships.ShipType(cast.ShipType), id, now)
+		if err != nil {
+			return ActionResult{}, err
+		}
+		res.EndTime = st.EndTime
+		if id == ships.AbilityPing && !q.TargetID.IsZero() {
+			if err := applyPing(ctx, gs, s, q.TargetID, now); err != nil {
+				return ActionResult{}, err
+			}
+			res.Participants = append(res.Participants, q.TargetID)
+		}
+	}
+
+	if err := gs.SaveStack(ctx, s); err != nil {
+		return ActionResult{}, err
+	}
+	return res, nil
+}
+
+func applyPing(ctx context.Context, gs GameState, caster *ships.ShipStack, targetID bson.ObjectID, now time.Time) error {
+	phantoms, err := gs.Phantoms(ctx, caster.MapID)
+	if err != nil {
+		return err
+	}
+	if phantoms.ApplyPing(targetID, caster.PlayerID) {
+		return gs.SavePhantom(ctx, phantoms[targetID])
+	}
+	target, err := gs.Stack(ctx, targetID)
+	if err != nil {
+		return err
+	}
+	target.ApplyPingReveal(caster.PlayerID, caster.ID, now)
+	return gs.SaveStack(ctx, target)
+}
@@ -0,0 +1,291 @@
+package maps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// memoryState is an in-memory GameState.
+type memoryState struct {
+	stacks   map[bson.ObjectID]*ships.ShipStack
+	systems  map[bson.ObjectID]*orbitables.System
+	phantoms ships.PhantomSet
+	reports  []*ships.BattleReport
+}
+
+func newMemoryState(stacks ...*ships.ShipStack) *memoryState {
+	gs := &memoryState{stacks: map[bson.ObjectID]*ships.ShipStack{}, systems: map[bson.ObjectID]*orbitables.System{}, phantoms: ships.PhantomSet{}}
+	for _, s := range stacks {
+		gs.stacks[s.ID] = s
+	}
+	return gs
+}
+
+func (m *memoryState) Stack(_ context.Context, id bson.ObjectID) (*ships.ShipStack, error) {
+	s, ok := m.stacks[id]
+	if !ok {
+		return nil, ErrInvalidTarget
+	}
+	return s, nil
+}
+
+func (m *memoryState) SaveStack(_ context.Context, s *ships.ShipStack) error {
+	m.stacks[s.ID] = s
+	return nil
+}
+
+func (m *memoryState) System(_ context.Context, id bson.ObjectID) (*orbitables.System, error) {
+	s, ok := m.systems[id]
+	if !ok {
+		return nil, ErrInvalidTarget
+	}
+	return s, nil
+}
+
+func (m *memoryState) SaveSystem(_ context.Context, s *orbitables.System) error {
+	m.systems[s.ID] = s
+	return nil
+}
+
+func (m *memoryState) Phantoms(context.Context, bson.ObjectID) (ships.PhantomSet, error) {
+	return m.phantoms, nil
+}
+
+func (m *memoryState) SavePhantom(_ context.Context, p *ships.PhantomContact) error {
+	m.phantoms[p.ID] = p
+	return nil
+}
+
+func (m *memoryState) SaveBattleReport(_ context.Context, r *ships.BattleReport) error {
+	m.reports = append(m.reports, r)
+	return nil
+}
+
+// memoryStore is an in-memory QueueStore. The first markConflicts calls to
+// MarkProcessed fail with ErrVersionConflict.
+type memoryStore struct {
+	items         map[bson.ObjectID]*Queue
+	responses     []*ResponseQueue
+	reloads       int
+	markConflicts int
+}
+
+func newMemoryStore(items ...*Queue) *memoryStore {
+	st := &memoryStore{items: map[bson.ObjectID]*Queue{}}
+	for _, q := range items {
+		st.items[q.ID] = q
+	}
+	return st
+}
+
+func (m *memoryStore) Reload(_ context.Context, id bson.ObjectID) (*Queue, error) {
+	m.reloads++
+	q, ok := m.items[id]
+	if !ok {
+		return nil, ErrInvalidTarget
+	}
+	fresh := *q
+	return &fresh, nil
+}
+
+func (m *memoryStore) MarkProcessed(_ context.Context, q *Queue, now time.Time) error {
+	if m.markConflicts > 0 {
+		m.markConflicts--
+		return ErrVersionConflict
+	}
+	if stored, ok := m.items[q.ID]; ok {
+		stored.ProcessedAt = now
+		stored.Version++
+	}
+	return nil
+}
+
+func (m *memoryStore) WriteResponse(_ context.Context, r *ResponseQueue) error {
+	m.responses = append(m.responses, r)
+	return nil
+}
+
+// conflictHandler fails Execute with ErrVersionConflict a set number of times.
+type conflictHandler struct {
+	conflicts *int
+	executed  *int
+}
+
+func (conflictHandler) Validate(context.Context, GameState, *Queue, time.Time) error { return nil }
+
+func (h conflictHandler) Execute(context.Context, GameState, *Queue, time.Time) (ActionResult, error) {
+	*h.executed++
+	if *h.conflicts > 0 {
+		*h.conflicts--
+		return ActionResult{}, ErrVersionConflict
+	}
+	return ActionResult{Payload: &AnchorResult{Release: true}}, nil
+}
+
+func TestProcessAttackUsesInterpolatedPositions(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	attacker := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(),
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 3}}}}
+	defender := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(), PositionX: 1000,
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 3}}}}
+	// Stored at x=1000 but one second from reaching the attacker.
+	defender.Movement = []*ships.MovementState{{
+		Type: ships.MovementTypeTravel, StartX: 1000, StartTime: now.Add(-1000 * time.Second), EndTime: now.Add(time.Second),
+	}}
+	q, err := NewQueueItem(bson.NilObjectID, attacker.PlayerID, ActionShipAttack, attacker.ID, defender.ID, &AttackOrder{TargetType: "stack"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs, store := newMemoryState(attacker, defender), newMemoryStore(q)
+	resp, err := NewQueueProcessor(gs, store).Process(context.Background(), q, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != ResponseOK {
+		t.Fatalf("status %s: %s", resp.Status, resp.Error)
+	}
+	result, ok := resp.Payload.(*AttackResult)
+	if !ok || result.BattleID == "" || len(gs.reports) != 1 {
+		t.Fatalf("payload %#v, %d reports", resp.Payload, len(gs.reports))
+	}
+	if wantX, _ := defender.PositionAt(now); math.Abs(resp.TargetX-wantX) > 1e-9 {
+		t.Fatalf("battle at x=%.2f, want the defender's interpolated %.2f", resp.TargetX, wantX)
+	}
+	if len(store.responses) != 1 || store.responses[0] != resp || store.items[q.ID].ProcessedAt.IsZero() {
+		t.Fatalf("responses = %d, processedAt = %v", len(store.responses), store.items[q.ID].ProcessedAt)
+	}
+}
+
+func TestProcessRetriesVersionConflicts(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		conflicts int
+		status    string
+		executed  int
+	}{
+		{"no conflict", 0, ResponseOK, 1},
+		{"recovers within retries", DefaultMaxRetries, ResponseOK, DefaultMaxRetries + 1},
+		{"gives up after retries", DefaultMaxRetries + 1, ResponseConflict, DefaultMaxRetries + 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q, _ := NewQueueItem(bson.NilObjectID, bson.NewObjectID(), ActionAnchor, bson.NewObjectID(), bson.NilObjectID, &AnchorOrder{Release: true}, now)
+			store := newMemoryStore(q)
+			p := NewQueueProcessor(newMemoryState(), store)
+			conflicts, executed := tc.conflicts, 0
+			p.Register(ActionAnchor, conflictHandler{&conflicts, &executed})
+
+			resp, err := p.Process(context.Background(), q, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tc.status || executed != tc.executed || store.reloads != tc.executed-1 {
+				t.Fatalf("status %s, executed %d, reloads %d", resp.Status, executed, store.reloads)
+			}
+			if tc.status == ResponseOK {
+				if r, ok := resp.Payload.(*AnchorResult); !ok || !r.Release {
+					t.Fatalf("payload %#v", resp.Payload)
+				}
+			}
+			if len(store.responses) != 1 {
+				t.Fatalf("%d responses written", len(store.responses))
+			}
+		})
+	}
+}
+
+func TestProcessRejectsAndFinishes(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	bad := &Queue{ID: bson.NewObjectID(), Type: ActionShipAttack, Payload: &AnchorOrder{Release: true}}
+	store := newMemoryStore(bad)
+	resp, err := NewQueueProcessor(newMemoryState(), store).Process(ctx, bad, now)
+	if err != nil || resp.Status != ResponseRejected || len(store.responses) != 1 {
+		t.Fatalf("wrong payload struct: %+v, %v", resp, err)
+	}
+
+	missing := &Queue{ID: bson.NewObjectID(), Type: ActionShipAttack, SourceID: bson.NewObjectID(), Payload: &AttackOrder{}}
+	store = newMemoryStore(missing)
+	if resp, _ := NewQueueProcessor(newMemoryState(), store).Process(ctx, missing, now); resp.Status != ResponseRejected || resp.Error == "" {
+		t.Fatalf("missing source stack: %+v", resp)
+	}
+
+	// Another worker marks the item processed first: no second response.
+	raced := &Queue{ID: bson.NewObjectID(), Type: ActionShipAttack, Payload: &AnchorOrder{}}
+	store = newMemoryStore(raced)
+	store.items[raced.ID] = &Queue{ID: raced.ID, Type: raced.Type, ProcessedAt: now}
+	store.markConflicts = 1
+	if _, err := NewQueueProcessor(newMemoryState(), store).Process(ctx, raced, now); err != nil || len(store.responses) != 0 {
+		t.Fatalf("raced item: err %v, %d responses", err, len(store.responses))
+	}
+
+	// Persistent MarkProcessed conflicts surface as an error.
+	stuck := &Queue{ID: bson.NewObjectID(), Type: ActionShipAttack, Payload: &AnchorOrder{}}
+	store = newMemoryStore(stuck)
+	store.markConflicts = DefaultMaxRetries + 1
+	if _, err := NewQueueProcessor(newMemoryState(), store).Process(ctx, stuck, now); !errors.Is(err, ErrVersionConflict) || len(store.responses) != 0 {
+		t.Fatalf("stuck item: err %v, %d responses", err, len(store.responses))
+	}
+}
+
+func TestQueuePayloadRoundTrip(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	q, err := NewQueueItem(bson.NewObjectID(), bson.NewObjectID(), ActionShipConstruction, bson.NewObjectID(), bson.NilObjectID,
+		&ShipBuildOrder{ShipType: string(ships.Fighter), Count: 4}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := bson.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromBSON Queue
+	if err := bson.Unmarshal(raw, &fromBSON); err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := fromBSON.Payload.(*ShipBuildOrder); !ok || o.Count != 4 || fromBSON.ID != q.ID || fromBSON.Type != q.Type {
+		t.Fatalf("bson round trip: %+v / %#v", fromBSON, fromBSON.Payload)
+	}
+
+	js, err := json.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromJSON Queue
+	if err := json.Unmarshal(js, &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := fromJSON.Payload.(*ShipBuildOrder); !ok || o.ShipType != string(ships.Fighter) || fromJSON.SourceID != q.SourceID {
+		t.Fatalf("json round trip: %+v / %#v", fromJSON, fromJSON.Payload)
+	}
+
+	resp := newResponse(q, now)
+	resp.Payload = &ShipBuildResult{OrderID: bson.NewObjectID(), Start: now}
+	raw, err = bson.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back ResponseQueue
+	if err := bson.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := back.Payload.(*ShipBuildResult); !ok || r.OrderID != resp.Payload.(*ShipBuildResult).OrderID {
+		t.Fatalf("response round trip: %#v", back.Payload)
+	}
+
+	unknown, _ := bson.Marshal(bson.D{{Key: "type", Value: "warp_gate"}, {Key: "payload", Value: bson.D{{Key: "x", Value: 1}}}})
+	if err := bson.Unmarshal(unknown, &fromBSON); !errors.Is(err, ErrUnknownActionType) {
+		t.Fatalf("unknown action type: err = %v", err)
+	}
+}
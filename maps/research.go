@@ -0,0 +1,149 @@
+package maps
+
+import (
+	"context"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// TickEventResearchCompleted is emitted when a research project finishes.
+// SubjectID is the system, RelatedID the owning player and Detail the project.
+const TickEventResearchCompleted = "research_completed"
+
+// ResearchSource is an optional GameState extension supplying a player's
+// completed research. Without it prerequisites count as unmet.
+type ResearchSource interface {
+	CompletedResearch(ctx context.Context, playerID bson.ObjectID) ([]string, error)
+}
+
+// ResearchHandler starts or cancels a research project on the source
+// system's particle accelerator.
+type ResearchHandler struct{}
+
+func (ResearchHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	order, ok := q.Payload.(*ResearchOrder)
+	if !ok {
+		return unexpectedPayload(q)
+	}
+	sys, err := gs.System(ctx, q.SourceID)
+	if err != nil {
+		return err
+	}
+	_, err = applyResearchOrder(ctx, gs, sys, q.PlayerID, *order, now)
+	return err
+}
+
+func (ResearchHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	order, ok := q.Payload.(*ResearchOrder)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	sys, err := gs.System(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	planet, err := applyResearchOrder(ctx, gs, sys, q.PlayerID, *order, now)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	sys.Planet = planet
+	if err := gs.SaveSystem(ctx, sys); err != nil {
+		return ActionResult{}, err
+	}
+	res := ActionResult{
+		Payload:      &ResearchResult{Project: order.Project, Cancelled: order.Cancel},
+		TargetX:      sys.X,
+		TargetY:      sys.Y,
+		Participants: []bson.ObjectID{sys.ID},
+	}
+	if !order.Cancel {
+		if building, _ := planet.Building(orbitables.SlotParticleAccelerator); building != nil {
+			if job, ok := b.ActiveResearch(building); ok {
+				res.EndTime = job.End()
+			}
+		}
+	}
+	return res, nil
+}
+
+// applyResearchOrder runs the order against a copy of the system's planet and
+// returns the updated copy.
+func applyResearchOrder(ctx context.Context, gs GameState, sys *orbitables.System, playerID bson.ObjectID, order ResearchOrder, now time.Time) (*orbitables.Planet, error) {
+	if !sys.ControlledBy(playerID) {
+		return nil, orbitables.ErrNotSystemOwner
+	}
+	if sys.Planet == nil {
+		return nil, orbitables.ErrNoPlanet
+	}
+	planet := *sys.Planet
+	if order.Cancel {
+		return &planet, planet.CancelResearch(now)
+	}
+	completed, err := completedResearch(ctx, gs, playerID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = planet.StartResearch(order.Project, completed, now)
+	return &planet, err
+}
+
+// completedResearch asks the state for the player's finished projects; nil
+// when it is not a ResearchSource.
+func completedResearch(ctx context.Context, gs GameState, playerID bson.ObjectID) ([]string, error) {
+	src, ok := gs.(ResearchSource)
+	if !ok {
+		return nil, nil
+	}
+	return src.CompletedResearch(ctx, playerID)
+}
+
+// researchPhase advances every accelerator's project, records finished ones
+// on the owner's PlayerGameState and stamps them on the owner's stacks.
+func (e *TickEngine) researchPhase(w *WorldState, from, to time.Time) []TickEvent {
+	var events []TickEvent
+	byPlayer := make(map[bson.ObjectID]*players.PlayerGameState, len(w.Players))
+	for _, ps := range w.Players {
+		byPlayer[ps.PlayerID] = ps
+	}
+	for _, sys := range w.Systems {
+		if sys.Planet == nil || sys.Planet.ParticleAccelerator == nil {
+			continue
+		}
+		project, err := sys.Planet.AdvanceResearch(from, to)
+		if err != nil {
+			events = append(events, constructionFailed(sys, err, to))
+			continue
+		}
+		if project == "" {
+			continue
+		}
+		var owner bson.ObjectID
+		if sys.DefendingFleet != nil {
+			owner = sys.DefendingFleet.PlayerID
+		}
+		if ps := byPlayer[owner]; ps != nil && !containsString(ps.Research, project) {
+			ps.Research = append(ps.Research, project)
+			for _, s := range w.Stacks {
+				if s.PlayerID == owner {
+					s.Research = append([]string(nil), ps.Research...)
+					s.UpdateStackAttackRange(to)
+				}
+			}
+		}
+		events = append(events, TickEvent{Phase: PhaseConstruction, Kind: TickEventResearchCompleted, SubjectID: sys.ID, RelatedID: owner, Detail: project, At: to})
+	}
+	return events
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
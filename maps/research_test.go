@@ -0,0 +1,85 @@
+package maps
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// researchState adds a ResearchSource to memoryState.
+type researchState struct {
+	*memoryState
+	research map[bson.ObjectID][]string
+}
+
+func (r researchState) CompletedResearch(_ context.Context, playerID bson.ObjectID) ([]string, error) {
+	return r.research[playerID], nil
+}
+
+func TestResearchPhaseUpgradesStacks(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := bson.NewObjectID()
+	planet := &orbitables.Planet{ID: bson.NewObjectID(), Metals: 10000, Crystals: 10000, Plasma: 1000}
+	planet.SetBuilding(orbitables.SlotParticleAccelerator, b.ParticleAccelerator{BaseBuilding: b.BaseBuilding{Level: 1}})
+	job, err := planet.StartResearch("fighter_afterburners", nil, now)
+	if err != nil {
+		t.Fatalf("start research: %v", err)
+	}
+	sys := &orbitables.System{
+		ID:             bson.NewObjectID(),
+		Colonization:   &orbitables.Colonization{IsColonized: true, ColonizedBy: owner},
+		DefendingFleet: &orbitables.DefendingFleet{PlayerID: owner},
+		Planet:         planet,
+	}
+	mine := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: owner, Ships: map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 3}}}}
+	theirs := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(), Ships: map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 3}}}}
+	ps := &players.PlayerGameState{PlayerID: owner}
+	w := &WorldState{Systems: []*orbitables.System{sys}, Stacks: []*ships.ShipStack{mine, theirs}, Players: []*players.PlayerGameState{ps}}
+
+	_, before := ships.ComputeStackModifiers(mine, ships.Fighter, 0, job.End(), false, "")
+	events := NewTickEngine(time.Minute).researchPhase(w, now, job.End())
+	if len(events) != 1 || events[0].Kind != TickEventResearchCompleted {
+		t.Fatalf("events = %+v", events)
+	}
+	if len(mine.Research) != 1 || len(theirs.Research) != 0 {
+		t.Fatalf("research stamped on stacks: mine %v, theirs %v", mine.Research, theirs.Research)
+	}
+	if _, after := ships.ComputeStackModifiers(mine, ships.Fighter, 0, job.End(), false, ""); after.SpeedDelta != before.SpeedDelta+1 {
+		t.Fatalf("fighter speed delta %d after research, want %d", after.SpeedDelta, before.SpeedDelta+1)
+	}
+}
+
+func TestGemSocketChecksResearchedTier(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	s := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(),
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: 100, Count: 4}}}}
+	s.EnsureFormationInitialized(now)
+	gs := researchState{memoryState: newMemoryState(s), research: map[bson.ObjectID][]string{}}
+	q, err := NewQueueItem(bson.NilObjectID, s.PlayerID, ActionGemSocketChange, s.ID, bson.NilObjectID,
+		&GemSocketChange{ShipType: string(ships.Fighter), Sockets: []string{"laser-4"}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (GemSocketHandler{}).Validate(ctx, gs, q, now); !errors.Is(err, ships.ErrGemTierLocked) {
+		t.Fatalf("tier 4 without research: got %v, want ErrGemTierLocked", err)
+	}
+	gs.research[s.PlayerID] = []string{"gem_lattice_4"}
+	if err := (GemSocketHandler{}).Validate(ctx, gs, q, now); err != nil {
+		t.Fatalf("tier 4 with research: %v", err)
+	}
+	if _, err := (GemSocketHandler{}).Execute(ctx, gs, q, now); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if load := s.Loadouts[ships.Fighter]; len(load.Sockets) != 1 || load.Sockets[0].Tier != 4 {
+		t.Fatalf("loadout = %+v", load)
+	}
+}
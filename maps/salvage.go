@@ -0,0 +1,81 @@
+package maps
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/essences"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Salvage tick events. For TickEventSalvageDropped SubjectID is the field,
+// RelatedID the defending stack and Detail the amounts added; for
+// TickEventSalvageHarvested SubjectID is the stack, RelatedID the field and
+// Detail the amounts taken; for TickEventSalvageDecayed SubjectID is the
+// field that vanished.
+const (
+	TickEventSalvageDropped   = "salvage_dropped"
+	TickEventSalvageHarvested = "salvage_harvested"
+	TickEventSalvageDecayed   = "salvage_decayed"
+)
+
+// SalvageSource is an optional GameState extension for loading salvage
+// fields. Without it stacks cannot anchor onto salvage.
+type SalvageSource interface {
+	SalvageField(ctx context.Context, id bson.ObjectID) (*orbitables.SalvageField, error)
+}
+
+// dropSalvage leaves a battle round's wreckage at the defender's position,
+// topping up a field already there or opening a new one. New fields take
+// their ID from the round time and the defender's ID so replays agree.
+func dropSalvage(w *WorldState, defender *ships.ShipStack, c ships.Cargo, at time.Time) (TickEvent, bool) {
+	if c.Total() == 0 {
+		return TickEvent{}, false
+	}
+	x, y := defender.PositionX, defender.PositionY
+	for _, f := range w.Salvage {
+		if f.Contains(x, y) {
+			f.Add(c, at)
+			return TickEvent{Phase: PhaseCombat, Kind: TickEventSalvageDropped, SubjectID: f.ID, RelatedID: defender.ID, Detail: cargoDetail(c), At: at}, true
+		}
+	}
+	var id bson.ObjectID
+	binary.BigEndian.PutUint32(id[:4], uint32(at.Unix()))
+	copy(id[4:], defender.ID[4:])
+	f := orbitables.NewSalvageField(id, w.MapID, x, y, c, at)
+	w.Salvage = append(w.Salvage, f)
+	sort.Slice(w.Salvage, func(i, j int) bool { return bytes.Compare(w.Salvage[i].ID[:], w.Salvage[j].ID[:]) < 0 })
+	return TickEvent{Phase: PhaseCombat, Kind: TickEventSalvageDropped, SubjectID: f.ID, RelatedID: defender.ID, Detail: cargoDetail(c), At: at}, true
+}
+
+// harvestSalvage runs every field's harvest, loads the wreckage into the
+// harvesting stacks and removes fields that are spent or have decayed away.
+func harvestSalvage(w *WorldState, byID map[bson.ObjectID]*ships.ShipStack, to time.Time) []TickEvent {
+	var events []TickEvent
+	kept := w.Salvage[:0]
+	for _, f := range w.Salvage {
+		for _, y := range f.Harvest(w.Stacks, to) {
+			s := byID[y.StackID]
+			loadGathered(s, "metal", y.Cargo.Metals, to)
+			loadGathered(s, "crystal", y.Cargo.Crystals, to)
+			loadGathered(s, "hydrogen", y.Cargo.Hydrogen, to)
+			if s.Bio != nil {
+				s.Bio.OnTrigger(string(essences.TriggerOnResourceExtract), to)
+			}
+			events = append(events, TickEvent{Phase: PhaseMining, Kind: TickEventSalvageHarvested, SubjectID: s.ID, RelatedID: f.ID, Detail: cargoDetail(y.Cargo), At: to})
+		}
+		f.Decay(to)
+		if f.Gone(to) {
+			events = append(events, TickEvent{Phase: PhaseMining, Kind: TickEventSalvageDecayed, SubjectID: f.ID, At: to})
+			continue
+		}
+		kept = append(kept, f)
+	}
+	w.Salvage = kept
+	return events
+}
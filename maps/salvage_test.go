@@ -0,0 +1,59 @@
+package maps
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// salvageState adds a SalvageSource to memoryState.
+type salvageState struct {
+	*memoryState
+	fields map[bson.ObjectID]*orbitables.SalvageField
+}
+
+func (s salvageState) SalvageField(_ context.Context, id bson.ObjectID) (*orbitables.SalvageField, error) {
+	if f, ok := s.fields[id]; ok {
+		return f, nil
+	}
+	return nil, ErrInvalidTarget
+}
+
+func TestAnchorOnSalvageStartsHarvest(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	f := orbitables.NewSalvageField(bson.NewObjectID(), bson.NilObjectID, 0, 0, ships.Cargo{Metals: 1000}, now)
+	s := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(), PositionX: 10,
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Drone: {{HP: 100, Count: 2}}}}
+	gs := salvageState{memoryState: newMemoryState(s), fields: map[bson.ObjectID]*orbitables.SalvageField{f.ID: f}}
+	order := AnchorOrder{TargetType: orbitables.SalvageTargetType}
+
+	if _, err := applyAnchorOrder(ctx, gs, s, f.ID, order, now); !errors.Is(err, orbitables.ErrNotEconomic) {
+		t.Fatalf("tactical stack: got %v, want ErrNotEconomic", err)
+	}
+	s.Role = ships.RoleEconomic
+	if _, err := applyAnchorOrder(ctx, newMemoryState(s), s, f.ID, order, now); !errors.Is(err, ErrInvalidTarget) {
+		t.Fatalf("without a SalvageSource: got %v, want ErrInvalidTarget", err)
+	}
+	held, err := applyAnchorOrder(ctx, gs, s, f.ID, order, now)
+	if err != nil {
+		t.Fatalf("anchor: %v", err)
+	}
+
+	w := &WorldState{Stacks: []*ships.ShipStack{s}, Salvage: []*orbitables.SalvageField{f}}
+	ev, ok := advanceAnchor(w, s, held)
+	if !ok || ev.Kind != TickEventAnchorHeld || ev.Detail != "" {
+		t.Fatalf("event = %+v", ev)
+	}
+	if g := s.Gathering; g == nil || !g.IsGathering || g.TargetID != f.ID || g.TargetType != orbitables.SalvageTargetType {
+		t.Fatalf("gathering = %+v", s.Gathering)
+	}
+	if yields := f.Harvest(w.Stacks, held.Add(orbitables.MiningTick)); len(yields) != 1 || yields[0].StackID != s.ID {
+		t.Fatalf("yields = %+v", yields)
+	}
+}
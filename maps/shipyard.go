@@ -0,0 +1,151 @@
+package maps
+
+import (
+	"context"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// TickEventShipsDelivered is emitted when a shipyard order completes.
+// SubjectID is the system, RelatedID the order.
+const TickEventShipsDelivered = "ships_delivered"
+
+// FormationTreeSource is an optional GameState extension. When the state
+// implements it, the player's Fleet Command Mastery bonuses apply to
+// shipyard prices.
+type FormationTreeSource interface {
+	FormationTree(ctx context.Context, playerID bson.ObjectID) (*ships.FormationTreeState, error)
+}
+
+// ShipConstructionHandler queues a ship order at the source system's
+// shipyard, or cancels one.
+type ShipConstructionHandler struct{}
+
+func (ShipConstructionHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	order, ok := q.Payload.(*ShipBuildOrder)
+	if !ok {
+		return unexpectedPayload(q)
+	}
+	sys, err := gs.System(ctx, q.SourceID)
+	if err != nil {
+		return err
+	}
+	if order.Cancel {
+		// Run the cancel against a copy of the planet.
+		probe := *sys
+		if sys.Planet != nil {
+			planet := *sys.Planet
+			probe.Planet = &planet
+		}
+		return probe.CancelShipOrder(q.PlayerID, order.OrderID, now)
+	}
+	if !sys.ControlledBy(q.PlayerID) {
+		return orbitables.ErrNotSystemOwner
+	}
+	if sys.Planet == nil {
+		return orbitables.ErrNoPlanet
+	}
+	mods, err := constructionMods(ctx, gs, q.PlayerID)
+	if err != nil {
+		return err
+	}
+	cost, ok := ships.ShipBuildCost(ships.ShipType(order.ShipType), order.Count, mods)
+	if !ok {
+		return orbitables.ErrUnknownShipType
+	}
+	if !sys.Planet.CanAfford(cost) {
+		return orbitables.ErrInsufficientResources
+	}
+	return nil
+}
+
+func (ShipConstructionHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	order, ok := q.Payload.(*ShipBuildOrder)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	sys, err := gs.System(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	if order.Cancel {
+		if err := sys.CancelShipOrder(q.PlayerID, order.OrderID, now); err != nil {
+			return ActionResult{}, err
+		}
+		if err := gs.SaveSystem(ctx, sys); err != nil {
+			return ActionResult{}, err
+		}
+		return ActionResult{
+			Payload:      &ShipBuildResult{OrderID: order.OrderID, Cancelled: true},
+			TargetX:      sys.X,
+			TargetY:      sys.Y,
+			Participants: []bson.ObjectID{sys.ID},
+		}, nil
+	}
+	mods, err := constructionMods(ctx, gs, q.PlayerID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	queued, err := sys.QueueShipOrder(q.PlayerID, ships.ShipType(order.ShipType), order.Count, order.DeliverTo, mods, now)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	if err := gs.SaveSystem(ctx, sys); err != nil {
+		return ActionResult{}, err
+	}
+	return ActionResult{
+		Payload:      &ShipBuildResult{OrderID: queued.ID, Start: queued.Start},
+		TargetX:      sys.X,
+		TargetY:      sys.Y,
+		EndTime:      queued.End,
+		Participants: []bson.ObjectID{sys.ID},
+	}, nil
+}
+
+func constructionMods(ctx context.Context, gs GameState, playerID bson.ObjectID) (ships.StatMods, error) {
+	src, ok := gs.(FormationTreeSource)
+	if !ok {
+		return ships.StatMods{}, nil
+	}
+	tree, err := src.FormationTree(ctx, playerID)
+	if err != nil {
+		return ships.StatMods{}, err
+	}
+	return tree.GlobalMods(), nil
+}
+
+// shipyardPhase delivers finished shipyard orders. New stacks are appended
+// to w.Stacks for the caller to persist and recorded on their owner's
+// PlayerGameState.
+func (e *TickEngine) shipyardPhase(w *WorldState, from, to time.Time) []TickEvent {
+	var events []TickEvent
+	byPlayer := make(map[bson.ObjectID]*players.PlayerGameState, len(w.Players))
+	for _, ps := range w.Players {
+		byPlayer[ps.PlayerID] = ps
+	}
+	for _, sys := range w.Systems {
+		if sys.Planet == nil || sys.Planet.ShipYard == nil {
+			continue
+		}
+		delivered, stacks, err := sys.CompleteShipOrders(to)
+		if err != nil {
+			events = append(events, constructionFailed(sys, err, to))
+			continue
+		}
+		w.Stacks = append(w.Stacks, stacks...)
+		for _, s := range stacks {
+			if ps := byPlayer[s.PlayerID]; ps != nil {
+				ps.AddActiveStack(s.ID)
+				s.Research = append([]string(nil), ps.Research...)
+			}
+		}
+		for _, o := range delivered {
+			events = append(events, TickEvent{Phase: PhaseConstruction, Kind: TickEventShipsDelivered, SubjectID: sys.ID, RelatedID: o.ID, At: o.End})
+		}
+	}
+	return events
+}
@@ -0,0 +1,94 @@
+package maps
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestShipyardPhaseRecordsNewStacks(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := bson.NewObjectID()
+	planet := &orbitables.Planet{Type: b.PlanetEarth, LastProduced: now, Metals: 100000, Crystals: 100000, Plasma: 100000}
+	planet.SetBuilding(orbitables.SlotShipYard, b.ShipYard{BaseBuilding: b.BaseBuilding{Level: 1}})
+	sys := &orbitables.System{
+		ID:             bson.NewObjectID(),
+		Colonization:   &orbitables.Colonization{IsColonized: true, ColonizedBy: owner},
+		DefendingFleet: &orbitables.DefendingFleet{PlayerID: owner},
+		Planet:         planet,
+	}
+	order, err := sys.QueueShipOrder(owner, ships.Scout, 2, orbitables.DeliverToStack, ships.StatMods{}, now)
+	if err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	ps := &players.PlayerGameState{PlayerID: owner}
+	w := &WorldState{Systems: []*orbitables.System{sys}, Players: []*players.PlayerGameState{ps}}
+
+	e := NewTickEngine(time.Minute)
+	events := e.shipyardPhase(w, now, order.End)
+	if len(events) != 1 || events[0].RelatedID != order.ID {
+		t.Fatalf("events = %+v", events)
+	}
+	if len(w.Stacks) != 1 {
+		t.Fatalf("%d stacks delivered, want 1", len(w.Stacks))
+	}
+	s := w.Stacks[0]
+	if s.ID == order.ID || s.PlayerID != owner {
+		t.Fatalf("delivered stack %v (order %v) owned by %v", s.ID, order.ID, s.PlayerID)
+	}
+	if len(ps.ActiveStacks) != 1 || ps.ActiveStacks[0] != s.ID {
+		t.Fatalf("active stacks = %v, want [%v]", ps.ActiveStacks, s.ID)
+	}
+}
+
+func TestShipConstructionCancel(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := bson.NewObjectID()
+	planet := &orbitables.Planet{Type: b.PlanetEarth, LastProduced: now, Metals: 100000, Crystals: 100000, Plasma: 100000}
+	planet.SetBuilding(orbitables.SlotShipYard, b.ShipYard{BaseBuilding: b.BaseBuilding{Level: 1}})
+	sys := &orbitables.System{
+		ID:             bson.NewObjectID(),
+		Colonization:   &orbitables.Colonization{IsColonized: true, ColonizedBy: owner},
+		DefendingFleet: &orbitables.DefendingFleet{PlayerID: owner},
+		Planet:         planet,
+	}
+	order, err := sys.QueueShipOrder(owner, ships.Fighter, 4, "", ships.StatMods{}, now)
+	if err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	metals := planet.Metals
+	gs := newMemoryState()
+	gs.systems[sys.ID] = sys
+	p := NewQueueProcessor(gs, newMemoryStore())
+	cancel := func(at time.Time) *ResponseQueue {
+		t.Helper()
+		q, err := NewQueueItem(bson.NilObjectID, owner, ActionShipConstruction, sys.ID, bson.NilObjectID,
+			&ShipBuildOrder{Cancel: true, OrderID: order.ID}, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := p.Process(ctx, q, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if r := cancel(order.End); r.Status != ResponseRejected || planet.Metals != metals {
+		t.Fatalf("cancel at completion: %+v, metals %d", r, planet.Metals)
+	}
+	r := cancel(now)
+	if res, ok := r.Payload.(*ShipBuildResult); r.Status != ResponseOK || !ok || !res.Cancelled || res.OrderID != order.ID {
+		t.Fatalf("cancel: %+v", r)
+	}
+	if planet.Metals != metals+order.Metal {
+		t.Fatalf("metals after cancel = %d, want %d", planet.Metals, metals+order.Metal)
+	}
+}
@@ -0,0 +1,184 @@
+package maps
+
+import (
+	"context"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Stack configuration
+//
+// formation_change, gem_socket_change, role_switch and embark reconfigure the
+// source stack in place. None of them is allowed mid-battle; the formation and
+// role timers they start are reported as the response EndTime. Embarked ships
+// come back out with the carrier's HangarLaunch ability.
+
+// FormationChangeHandler switches the source stack's formation.
+type FormationChangeHandler struct{}
+
+func (FormationChangeHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	if _, ok := q.Payload.(*FormationChange); !ok {
+		return unexpectedPayload(q)
+	}
+	s, err := loadOwnedStack(ctx, gs, q)
+	if err != nil {
+		return err
+	}
+	return checkReconfigurable(s)
+}
+
+func (FormationChangeHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	order, ok := q.Payload.(*FormationChange)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	s, err := gs.Stack(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	end := s.SetFormation(ships.FormationType(order.Formation), now)
+	if err := gs.SaveStack(ctx, s); err != nil {
+		return ActionResult{}, err
+	}
+	return ActionResult{EndTime: end, Participants: []bson.ObjectID{s.ID}}, nil
+}
+
+// GemSocketHandler replaces the sockets of one ship type on the source stack.
+type GemSocketHandler struct{}
+
+func (GemSocketHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	order, ok := q.Payload.(*GemSocketChange)
+	if !ok {
+		return unexpectedPayload(q)
+	}
+	s, err := loadOwnedStack(ctx, gs, q)
+	if err != nil {
+		return err
+	}
+	_, err = gemLoadout(ctx, gs, s, order)
+	return err
+}
+
+func (GemSocketHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	order, ok := q.Payload.(*GemSocketChange)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	s, err := gs.Stack(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	load, err := gemLoadout(ctx, gs, s, order)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	if s.Loadouts == nil {
+		s.Loadouts = make(map[ships.ShipType]ships.ShipLoadout)
+	}
+	s.Loadouts[ships.ShipType(order.ShipType)] = load
+	s.UpdateStackAttackRange(now)
+	if err := gs.SaveStack(ctx, s); err != nil {
+		return ActionResult{}, err
+	}
+	return ActionResult{Participants: []bson.ObjectID{s.ID}}, nil
+}
+
+// RoleSwitchHandler starts switching the source stack's role.
+type RoleSwitchHandler struct{}
+
+func (RoleSwitchHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	if _, ok := q.Payload.(*RoleSwitch); !ok {
+		return unexpectedPayload(q)
+	}
+	s, err := loadOwnedStack(ctx, gs, q)
+	if err != nil {
+		return err
+	}
+	return checkReconfigurable(s)
+}
+
+func (RoleSwitchHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	order, ok := q.Payload.(*RoleSwitch)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	s, err := gs.Stack(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	end := s.StartModeSwitch(ships.RoleMode(order.Role), now)
+	if err := gs.SaveStack(ctx, s); err != nil {
+		return ActionResult{}, err
+	}
+	return ActionResult{EndTime: end, Participants: []bson.ObjectID{s.ID}}, nil
+}
+
+// EmbarkHandler loads ships of the source stack into its carriers' hangar.
+type EmbarkHandler struct{}
+
+func (EmbarkHandler) Validate(ctx context.Context, gs GameState, q *Queue, now time.Time) error {
+	order, ok := q.Payload.(*EmbarkOrder)
+	if !ok {
+		return unexpectedPayload(q)
+	}
+	s, err := loadOwnedStack(ctx, gs, q)
+	if err != nil {
+		return err
+	}
+	if err := checkReconfigurable(s); err != nil {
+		return err
+	}
+	return s.CheckEmbark(ships.ShipType(order.ShipType), order.Count, now)
+}
+
+func (EmbarkHandler) Execute(ctx context.Context, gs GameState, q *Queue, now time.Time) (ActionResult, error) {
+	order, ok := q.Payload.(*EmbarkOrder)
+	if !ok {
+		return ActionResult{}, unexpectedPayload(q)
+	}
+	s, err := gs.Stack(ctx, q.SourceID)
+	if err != nil {
+		return ActionResult{}, err
+	}
+	if err := s.Embark(ships.ShipType(order.ShipType), order.Count, now); err != nil {
+		return ActionResult{}, err
+	}
+	if err := gs.SaveStack(ctx, s); err != nil {
+		return ActionResult{}, err
+	}
+	return ActionResult{Participants: []bson.ObjectID{s.ID}}, nil
+}
+
+func checkReconfigurable(s *ships.ShipStack) error {
+	if s.Battle != nil && s.Battle.IsInCombat {
+		return ships.ErrStackBusy
+	}
+	return nil
+}
+
+// gemLoadout builds the new loadout for the order's ship type, keeping its
+// anchored flag. Gems above the owner's researched tier are rejected.
+func gemLoadout(ctx context.Context, gs GameState, s *ships.ShipStack, order *GemSocketChange) (ships.ShipLoadout, error) {
+	if err := checkReconfigurable(s); err != nil {
+		return ships.ShipLoadout{}, err
+	}
+	t := ships.ShipType(order.ShipType)
+	if len(s.Ships[t]) == 0 {
+		return ships.ShipLoadout{}, ships.ErrShipTypeNotInStack
+	}
+	load, err := order.loadout()
+	if err != nil {
+		return ships.ShipLoadout{}, err
+	}
+	completed, err := completedResearch(ctx, gs, s.PlayerID)
+	if err != nil {
+		return ships.ShipLoadout{}, err
+	}
+	if err := ships.ValidateGemTiers(load, completed); err != nil {
+		return ships.ShipLoadout{}, err
+	}
+	load.Anchored = s.Loadouts[t].Anchored
+	return load, nil
+}
@@ -0,0 +1,303 @@
+package maps
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/players"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Tick scheduler
+//
+// TickEngine advances a map's state to a target time in fixed steps. Step
+// boundaries sit on a grid of absolute multiples of Step (time.Truncate), so
+// advancing to t1 and later to t2 performs exactly the same steps as a single
+// advance to t2. Time past the last boundary is left for the next call; this
+// is what makes the engine resumable from any point with identical results.
+//
+// Within a step, phases run in PhaseOrder and every phase visits entities in
+// ID order. Nothing here reads the wall clock or uses randomness.
+
+// TickPhase names one subsystem advanced by the scheduler.
+type TickPhase string
+
+const (
+	PhaseMovement     TickPhase = "movement"
+	PhaseBio          TickPhase = "bio"
+	PhaseCombat       TickPhase = "combat"
+	PhaseMining       TickPhase = "mining"
+	PhaseConstruction TickPhase = "construction"
+	PhaseEnergy       TickPhase = "energy"
+)
+
+// PhaseOrder is the order phases run in within each step.
+var PhaseOrder = []TickPhase{PhaseMovement, PhaseBio, PhaseCombat, PhaseMining, PhaseConstruction, PhaseEnergy}
+
+const (
+	DefaultTickStep            = 10 * time.Second
+	DefaultCombatRoundInterval = 30 * time.Second
+)
+
+// Tick event kinds.
+const (
+	TickEventArrival     = "arrival"
+	TickEventInterdicted = "interdicted" // RelatedID is the interdicting stack
+	TickEventCombatRound = "combat_round"
+	TickEventBattleEnded = "battle_ended"
+)
+
+// WorldState is the slice of a map the tick engine works on. Callers load it,
+// advance it and persist whatever changed; LastTick must be stored with it.
+type WorldState struct {
+	MapID     bson.ObjectID
+	LastTick  time.Time
+	Stacks    []*ships.ShipStack
+	Systems   []*orbitables.System
+	Asteroids []*orbitables.Asteroid
+	Nebulas   []*orbitables.Nebula
+	Salvage   []*orbitables.SalvageField
+	Players   []*players.PlayerGameState
+	// Reports holds ongoing battle reports keyed by the attacking stack's ID.
+	Reports map[bson.ObjectID]*ships.BattleReport
+}
+
+// TickEvent is something that happened during a step.
+type TickEvent struct {
+	Phase     TickPhase
+	Kind      string
+	SubjectID bson.ObjectID
+	RelatedID bson.ObjectID
+	Detail    string // Extra context, e.g. the planet slot
+	At        time.Time
+}
+
+// PhaseFunc advances one subsystem over the step (from, to].
+type PhaseFunc func(w *WorldState, from, to time.Time) []TickEvent
+
+// TickEngine runs the phases. Phases without a function are skipped.
+type TickEngine struct {
+	Step                time.Duration
+	CombatRoundInterval time.Duration
+
+	phases map[TickPhase]PhaseFunc
+}
+
+// NewTickEngine creates an engine with the movement, bio, combat, mining,
+// construction and energy phases installed. A non-positive step uses
+// DefaultTickStep.
+func NewTickEngine(step time.Duration) *TickEngine {
+	if step <= 0 {
+		step = DefaultTickStep
+	}
+	e := &TickEngine{
+		Step:                step,
+		CombatRoundInterval: DefaultCombatRoundInterval,
+		phases:              make(map[TickPhase]PhaseFunc),
+	}
+	e.SetPhase(PhaseMovement, e.movementPhase)
+	e.SetPhase(PhaseBio, e.bioPhase)
+	e.SetPhase(PhaseCombat, e.combatPhase)
+	e.SetPhase(PhaseMining, e.miningPhase)
+	e.SetPhase(PhaseConstruction, e.constructionPhase)
+	e.SetPhase(PhaseEnergy, e.energyPhase)
+	return e
+}
+
+// SetPhase installs or replaces (nil removes) the function for a phase.
+func (e *TickEngine) SetPhase(p TickPhase, fn PhaseFunc) {
+	if fn == nil {
+		delete(e.phases, p)
+		return
+	}
+	e.phases[p] = fn
+}
+
+// Advance runs every whole step between w.LastTick and target. A zero
+// LastTick starts at the grid boundary at or before target, i.e. nothing runs.
+func (e *TickEngine) Advance(w *WorldState, target time.Time) []TickEvent {
+	if w.LastTick.IsZero() {
+		w.LastTick = target.Truncate(e.Step)
+		return nil
+	}
+	var events []TickEvent
+	for {
+		next := w.LastTick.Truncate(e.Step).Add(e.Step)
+		if next.After(target) {
+			break
+		}
+		events = append(events, e.step(w, w.LastTick, next)...)
+		w.LastTick = next
+	}
+	return events
+}
+
+func (e *TickEngine) step(w *WorldState, from, to time.Time) []TickEvent {
+	sortWorld(w)
+	var events []TickEvent
+	for _, p := range PhaseOrder {
+		if fn := e.phases[p]; fn != nil {
+			events = append(events, fn(w, from, to)...)
+		}
+	}
+	return events
+}
+
+func (e *TickEngine) movementPhase(w *WorldState, from, to time.Time) []TickEvent {
+	me := MovementEngine{Systems: w.Systems, Asteroids: w.Asteroids, Nebulas: w.Nebulas}
+	// Fields live at from so pulses expiring mid-step still catch warps.
+	wc := ships.WarpContext{Stabilizers: w.Stacks, Interdictions: ships.InterdictionFieldsFrom(w.Stacks, from)}
+	var events []TickEvent
+	for _, s := range w.Stacks {
+		if ev, ok := advanceAnchor(w, s, to); ok {
+			events = append(events, ev)
+		}
+		if s.Battle != nil && s.Battle.IsInCombat {
+			continue
+		}
+		if ev, ok := interdictWarp(s, wc, to); ok {
+			events = append(events, ev)
+		}
+		for _, a := range me.Advance(s, to) {
+			events = append(events, TickEvent{Phase: PhaseMovement, Kind: TickEventArrival, SubjectID: a.StackID, RelatedID: a.TargetID, At: a.At})
+			if a.TargetType == "system" {
+				if ev, ok := unloadAtSystem(w.Systems, s, a.TargetID, a.At); ok {
+					events = append(events, ev)
+				}
+				if ev, ok := startCaptureOnArrival(w.Systems, s, a.TargetID, a.At); ok {
+					events = append(events, ev)
+				}
+			}
+		}
+	}
+	return events
+}
+
+// interdictWarp checks the stack's warp legs against the step's interdiction
+// fields before it moves. It stops at the first caught leg: the legs behind it
+// are rebased when the stack reaches the stop point.
+func interdictWarp(s *ships.ShipStack, wc ships.WarpContext, to time.Time) (TickEvent, bool) {
+	if len(wc.Interdictions) == 0 {
+		return TickEvent{}, false
+	}
+	for _, leg := range s.Movement {
+		if leg == nil || leg.Warp == nil || !ships.ResolveInterdiction(s, leg, wc, to) {
+			continue
+		}
+		return TickEvent{Phase: PhaseMovement, Kind: TickEventInterdicted, SubjectID: s.ID, RelatedID: leg.Warp.InterdictedBy, At: leg.Warp.InterdictedAt}, true
+	}
+	return TickEvent{}, false
+}
+
+func (e *TickEngine) bioPhase(w *WorldState, from, to time.Time) []TickEvent {
+	for _, s := range w.Stacks {
+		s.TickBio(to)
+		s.ExpireAbilities(to)
+		s.PruneReveals(to)
+	}
+	return nil
+}
+
+// combatPhase runs one formation round per CombatRoundInterval elapsed since
+// each battle started. A pair is resolved once per step, from the side that
+// owns the report (or the lower stack ID when there is none). Stacks idle in
+// enemy systems then fight the garrisons, and captures whose hold timer ran
+// out are resolved last.
+func (e *TickEngine) combatPhase(w *WorldState, from, to time.Time) []TickEvent {
+	interval := e.CombatRoundInterval
+	if interval <= 0 {
+		interval = DefaultCombatRoundInterval
+	}
+	byID := make(map[bson.ObjectID]*ships.ShipStack, len(w.Stacks))
+	for _, s := range w.Stacks {
+		byID[s.ID] = s
+	}
+
+	var events []TickEvent
+	done := make(map[bson.ObjectID]bool)
+	for _, s := range w.Stacks {
+		if done[s.ID] || s.Battle == nil || !s.Battle.IsInCombat || len(s.Battle.EnemyStackID) == 0 {
+			continue
+		}
+		enemy := byID[s.Battle.EnemyStackID[0]]
+		if enemy == nil || done[enemy.ID] {
+			continue
+		}
+		attacker, defender := s, enemy
+		if _, ok := w.Reports[enemy.ID]; ok {
+			attacker, defender = enemy, s
+		} else if _, ok := w.Reports[s.ID]; !ok && bytes.Compare(enemy.ID[:], s.ID[:]) < 0 {
+			attacker, defender = enemy, s
+		}
+		done[attacker.ID], done[defender.ID] = true, true
+
+		// Rounds fall on BattleStartedAt + k*interval; those inside (from, to]
+		// resolve at the step boundary so they see this step's bio state.
+		start := attacker.Battle.BattleStartedAt
+		kTo := int(to.Sub(start) / interval)
+		kFrom := 0
+		if from.After(start) {
+			kFrom = int(from.Sub(start) / interval)
+		}
+		for k := kFrom + 1; k <= kTo; k++ {
+			var result ships.FormationBattleResult
+			if report := w.Reports[attacker.ID]; report != nil {
+				_, result = ships.ProcessCombatWithReporting(attacker, defender, report, to)
+			} else {
+				result = ships.ExecuteFormationBattleRound(attacker, defender, to)
+			}
+			events = append(events, TickEvent{Phase: PhaseCombat, Kind: TickEventCombatRound, SubjectID: attacker.ID, RelatedID: defender.ID, At: to})
+			wreckage := result.Salvage
+			for _, side := range []*ships.ShipStack{attacker, defender} {
+				if c, ev, ok := spillCargo(side, to); ok {
+					wreckage = wreckage.Plus(c)
+					events = append(events, ev)
+				}
+			}
+			if ev, ok := dropSalvage(w, defender, wreckage, to); ok {
+				events = append(events, ev)
+			}
+			if stackEmpty(attacker) || stackEmpty(defender) {
+				endBattle(attacker, to)
+				endBattle(defender, to)
+				events = append(events, TickEvent{Phase: PhaseCombat, Kind: TickEventBattleEnded, SubjectID: attacker.ID, RelatedID: defender.ID, At: to})
+				break
+			}
+		}
+	}
+	events = append(events, garrisonRounds(w, from, to, interval)...)
+	return append(events, resolveCaptures(w, to)...)
+}
+
+func endBattle(s *ships.ShipStack, now time.Time) {
+	s.Battle.IsInCombat = false
+	s.Battle.EnemyStackID = nil
+	s.Battle.EnemyPlayerID = nil
+	s.Battle.ProcessedAt = now
+}
+
+func stackEmpty(s *ships.ShipStack) bool {
+	for _, buckets := range s.Ships {
+		for _, b := range buckets {
+			if b.Count > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// sortWorld puts every entity list in ID order so phases iterate deterministically.
+func sortWorld(w *WorldState) {
+	sort.Slice(w.Stacks, func(i, j int) bool { return bytes.Compare(w.Stacks[i].ID[:], w.Stacks[j].ID[:]) < 0 })
+	sort.Slice(w.Systems, func(i, j int) bool { return bytes.Compare(w.Systems[i].ID[:], w.Systems[j].ID[:]) < 0 })
+	sort.Slice(w.Asteroids, func(i, j int) bool { return bytes.Compare(w.Asteroids[i].ID[:], w.Asteroids[j].ID[:]) < 0 })
+	sort.Slice(w.Nebulas, func(i, j int) bool { return bytes.Compare(w.Nebulas[i].ID[:], w.Nebulas[j].ID[:]) < 0 })
+	sort.Slice(w.Salvage, func(i, j int) bool { return bytes.Compare(w.Salvage[i].ID[:], w.Salvage[j].ID[:]) < 0 })
+	sort.Slice(w.Players, func(i, j int) bool {
+		return bytes.Compare(w.Players[i].PlayerID[:], w.Players[j].PlayerID[:]) < 0
+	})
+}
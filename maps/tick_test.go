@@ -0,0 +1,135 @@
+package maps
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func tickWorld(t *testing.T, start time.Time) *WorldState {
+	t.Helper()
+	p1, p2 := bson.NewObjectID(), bson.NewObjectID()
+	newStack := func(player bson.ObjectID, x, y float64, ships_ map[ships.ShipType][]ships.HPBucket) *ships.ShipStack {
+		s := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: player, PositionX: x, PositionY: y, Ships: ships_}
+		s.EnsureFormationInitialized(start)
+		return s
+	}
+	mover := newStack(p1, 0, 0, map[ships.ShipType][]ships.HPBucket{ships.Scout: {{HP: 100, Count: 3}}})
+	if _, err := mover.QueueMove(4000, 0, bson.ObjectID{}, "", start); err != nil {
+		t.Fatal(err)
+	}
+	a := newStack(p1, 5000, 5000, map[ships.ShipType][]ships.HPBucket{ships.Fighter: {{HP: ships.ShipBlueprints[ships.Fighter].HP, Count: 20}}})
+	d := newStack(p2, 5100, 5000, map[ships.ShipType][]ships.HPBucket{ships.Destroyer: {{HP: ships.ShipBlueprints[ships.Destroyer].HP, Count: 6}}})
+	ships.InitiateBattle(a, d, ships.BattleLocation{Type: "empty_space", X: 5050, Y: 5000}, start)
+	return &WorldState{LastTick: start, Stacks: []*ships.ShipStack{mover, a, d}}
+}
+
+// snapshot round-trips each stack through BSON so both worlds compare with
+// the same time locations and nil/empty normalisation.
+func snapshot(t *testing.T, w *WorldState) []ships.ShipStack {
+	t.Helper()
+	out := make([]ships.ShipStack, 0, len(w.Stacks))
+	for _, s := range w.Stacks {
+		raw, err := bson.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var c ships.ShipStack
+		if err := bson.Unmarshal(raw, &c); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// Advancing in arbitrary chunks must give the same state as one big advance.
+func TestTickEngineResumable(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 3, 0, time.UTC)
+	end := start.Add(17 * time.Minute)
+
+	whole := tickWorld(t, start)
+	chunked := &WorldState{LastTick: whole.LastTick}
+	for _, c := range snapshot(t, whole) {
+		c := c
+		chunked.Stacks = append(chunked.Stacks, &c)
+	}
+
+	e := NewTickEngine(10 * time.Second)
+	wholeEvents := e.Advance(whole, end)
+
+	var chunkEvents []TickEvent
+	for at := start; at.Before(end); at = at.Add(37 * time.Second) {
+		chunkEvents = append(chunkEvents, e.Advance(chunked, at)...)
+	}
+	chunkEvents = append(chunkEvents, e.Advance(chunked, end)...)
+
+	if !whole.LastTick.Equal(chunked.LastTick) {
+		t.Fatalf("LastTick differs: %v vs %v", whole.LastTick, chunked.LastTick)
+	}
+	if len(wholeEvents) != len(chunkEvents) {
+		t.Fatalf("event count differs: %d vs %d", len(wholeEvents), len(chunkEvents))
+	}
+	for i := range wholeEvents {
+		if wholeEvents[i] != chunkEvents[i] {
+			t.Fatalf("event %d differs: %+v vs %+v", i, wholeEvents[i], chunkEvents[i])
+		}
+	}
+	a, b := snapshot(t, whole), snapshot(t, chunked)
+	for i := range a {
+		if !reflect.DeepEqual(a[i], b[i]) {
+			t.Fatalf("stack %d state differs after chunked advance", i)
+		}
+	}
+
+	var rounds int
+	for _, ev := range wholeEvents {
+		if ev.Kind == TickEventCombatRound {
+			rounds++
+		}
+	}
+	if rounds == 0 {
+		t.Fatal("expected combat rounds")
+	}
+	if mover := whole.Stacks[0]; len(mover.Movement) != 0 || mover.PositionX != 4000 {
+		t.Fatalf("expected the scout to finish its move, at %v with %d legs", mover.PositionX, len(mover.Movement))
+	}
+}
+
+func TestTickInterdictsWarp(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	warper := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(),
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Cruiser: {{HP: ships.ShipBlueprints[ships.Cruiser].HP, Count: 2}}}}
+	warper.EnsureFormationInitialized(start)
+	leg, err := ships.PlanWarp(warper, 10000, 0, ships.WarpContext{}, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warper.Movement = append(warper.Movement, leg)
+
+	jump := leg.StartTime.Truncate(DefaultTickStep)
+	interdictor := &ships.ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(), PositionX: 2000, PositionY: 300,
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Destroyer: {{HP: ships.ShipBlueprints[ships.Destroyer].HP, Count: 2}}}}
+	interdictor.EnsureFormationInitialized(start)
+	if _, err := interdictor.ActivateAbility(ships.Destroyer, ships.AbilityInterdictorPulse, jump); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &WorldState{LastTick: jump, Stacks: []*ships.ShipStack{warper, interdictor}}
+	events := NewTickEngine(DefaultTickStep).Advance(w, leg.EndTime.Add(DefaultTickStep))
+	var caught []TickEvent
+	for _, ev := range events {
+		if ev.Kind == TickEventInterdicted {
+			caught = append(caught, ev)
+		}
+	}
+	if len(caught) != 1 || caught[0].SubjectID != warper.ID || caught[0].RelatedID != interdictor.ID {
+		t.Fatalf("interdiction events = %+v", caught)
+	}
+	if warper.PositionX >= 2000 || warper.PositionX <= 0 || len(warper.Movement) != 0 {
+		t.Fatalf("warper stopped at (%.0f, %.0f) with %d legs left", warper.PositionX, warper.PositionY, len(warper.Movement))
+	}
+}
@@ -0,0 +1,399 @@
+package maps
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// UpdateStackVisibility recomputes which stacks each observer can see, applying
+// cloak and detection rules from ships.DetectStacks, and writes the result into
+// the observer's ShipVisibility document. existing may be nil or partial;
+// missing documents are created. Version and LastUpdated only change when the
+// visible set changes.
+func UpdateStackVisibility(stacks []*ships.ShipStack, existing map[bson.ObjectID]*ShipVisibility, now time.Time) map[bson.ObjectID]*ShipVisibility {
+	if existing == nil {
+		existing = make(map[bson.ObjectID]*ShipVisibility)
+	}
+	profiles := ships.BuildSensorProfiles(stacks, now)
+	for _, obs := range stacks {
+		v := existing[obs.ID]
+		if v == nil {
+			v = &ShipVisibility{StackID: obs.ID}
+			existing[obs.ID] = v
+		}
+		v.SetStacks(ships.DetectStacks(obs, stacks, profiles, now), now)
+	}
+	return existing
+}
+
+// SetStacks replaces the visible stack list, bumping Version when it changed.
+func (v *ShipVisibility) SetStacks(ids []bson.ObjectID, now time.Time) bool {
+	changed := !sameIDSet(v.Stacks, ids)
+	if changed {
+		v.Stacks = ids
+		v.LastUpdated = now
+		v.Version++
+	}
+	v.LastProcessed = now
+	return changed
+}
+
+func sameIDSet(a, b []bson.ObjectID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[bson.ObjectID]int, len(a))
+	for _, id := range a {
+		seen[id]++
+	}
+	for _, id := range b {
+		if seen[id] == 0 {
+			return false
+		}
+		seen[id]--
+	}
+	return true
+}
+
+// PlayerVisibility is the union of everything a player's stacks and garrisons see.
+type PlayerVisibility struct {
+	PlayerID  bson.ObjectID
+	Systems   []bson.ObjectID
+	Stacks    []bson.ObjectID
+	Nebulas   []bson.ObjectID
+	Asteroids []bson.ObjectID
+}
+
+// VisibilityEngine computes fog-of-war for a single map. Observers are free
+// stacks plus the defending fleets of colonized systems; each observer's sensor
+// range is its best effective VisibilityRange (VisibilityDelta included).
+//
+// Recompute rebuilds everything. UpdateStacks only re-evaluates the moved
+// stacks as observers and as targets, which is enough for position changes;
+// changes that affect other stacks' cloak (e.g. a new smoke screen) need a
+// full Recompute.
+type VisibilityEngine struct {
+	MapID bson.ObjectID
+
+	stacks    map[bson.ObjectID]*ships.ShipStack
+	garrisons map[bson.ObjectID]*ships.ShipStack // keyed by system ID
+	systems   []*orbitables.System
+	asteroids []*orbitables.Asteroid
+	nebulas   []*orbitables.Nebula
+
+	phantoms ships.PhantomSet
+
+	profiles map[bson.ObjectID]ships.SensorProfile
+	views    map[bson.ObjectID]*ShipVisibility
+}
+
+// Contact is a client-facing entry for something that looks like a stack.
+// Phantom is only true for players who know the contact is a decoy.
+type Contact struct {
+	ID       bson.ObjectID                       `bson:"id" json:"id"`
+	PlayerID bson.ObjectID                       `bson:"playerId" json:"playerId"`
+	X        float64                             `bson:"x" json:"x"`
+	Y        float64                             `bson:"y" json:"y"`
+	Ships    map[ships.ShipType][]ships.HPBucket `bson:"ships" json:"ships"`
+	Phantom  bool                                `bson:"phantom,omitempty" json:"phantom,omitempty"`
+}
+
+// NewVisibilityEngine builds an engine and runs a full computation.
+func NewVisibilityEngine(mapID bson.ObjectID, stacks []*ships.ShipStack, systems []*orbitables.System, asteroids []*orbitables.Asteroid, nebulas []*orbitables.Nebula, now time.Time) *VisibilityEngine {
+	e := &VisibilityEngine{
+		MapID:     mapID,
+		stacks:    make(map[bson.ObjectID]*ships.ShipStack, len(stacks)),
+		systems:   systems,
+		asteroids: asteroids,
+		nebulas:   nebulas,
+		views:     make(map[bson.ObjectID]*ShipVisibility),
+	}
+	for _, s := range stacks {
+		e.stacks[s.ID] = s
+	}
+	e.Recompute(now)
+	return e
+}
+
+// Recompute rebuilds sensor profiles and every observer's view.
+func (e *VisibilityEngine) Recompute(now time.Time) {
+	e.garrisons = make(map[bson.ObjectID]*ships.ShipStack)
+	for _, sys := range e.systems {
+		if sys.DefendingFleet == nil {
+			continue
+		}
+		e.garrisons[sys.ID] = &ships.ShipStack{
+			ID:        sys.ID,
+			PlayerID:  sys.DefendingFleet.PlayerID,
+			MapID:     sys.MapID,
+			PositionX: sys.X,
+			PositionY: sys.Y,
+			Ships:     sys.DefendingFleet.Ships,
+		}
+	}
+
+	all := e.stackList()
+	e.profiles = ships.BuildSensorProfiles(all, now)
+	for id, g := range e.garrisons {
+		e.profiles[id] = ships.SensorProfile{Range: ships.ComputeStackVisibilityRange(g, now)}
+	}
+
+	for _, obs := range e.observers() {
+		e.recomputeObserver(obs, all, now)
+	}
+}
+
+// UpdateStacks upserts the given stacks (typically ones that moved) and
+// refreshes only the affected visibility entries.
+func (e *VisibilityEngine) UpdateStacks(moved []*ships.ShipStack, now time.Time) {
+	if len(moved) == 0 {
+		return
+	}
+	for _, s := range moved {
+		e.stacks[s.ID] = s
+	}
+	all := e.stackList()
+	movedIDs := make(map[bson.ObjectID]bool, len(moved))
+	for _, s := range moved {
+		movedIDs[s.ID] = true
+		e.profiles[s.ID] = ships.SensorProfile{
+			Range:       ships.ComputeStackVisibilityRange(s, now),
+			CloakDetect: ships.HasCloakDetect(s, now),
+			Cloaked:     s.IsCloaked(all, now),
+		}
+	}
+
+	for _, obs := range e.observers() {
+		if movedIDs[obs.ID] {
+			e.recomputeObserver(obs, all, now)
+			continue
+		}
+		v := e.views[obs.ID]
+		op := e.profiles[obs.ID]
+		next := make([]bson.ObjectID, 0, len(v.Stacks))
+		for _, id := range v.Stacks {
+			if !movedIDs[id] {
+				next = append(next, id)
+			}
+		}
+		for _, s := range moved {
+			if s.ID != obs.ID && ships.CanDetect(obs, op, s, e.profiles[s.ID], now) {
+				next = append(next, s.ID)
+			}
+		}
+		v.SetStacks(next, now)
+	}
+}
+
+// RemoveStack drops a stack (destroyed, merged or colonized) from every view.
+func (e *VisibilityEngine) RemoveStack(id bson.ObjectID, now time.Time) {
+	delete(e.stacks, id)
+	delete(e.profiles, id)
+	delete(e.views, id)
+	for _, v := range e.views {
+		next := make([]bson.ObjectID, 0, len(v.Stacks))
+		for _, sid := range v.Stacks {
+			if sid != id {
+				next = append(next, sid)
+			}
+		}
+		v.SetStacks(next, now)
+	}
+}
+
+// StackView returns the visibility document for a stack or garrisoned system.
+func (e *VisibilityEngine) StackView(id bson.ObjectID) *ShipVisibility {
+	return e.views[id]
+}
+
+// PlayerView merges the views of every observer the player controls. Owned
+// stacks and systems are always included.
+func (e *VisibilityEngine) PlayerView(playerID bson.ObjectID) PlayerVisibility {
+	pv := PlayerVisibility{PlayerID: playerID}
+	systems := map[bson.ObjectID]bool{}
+	stacks := map[bson.ObjectID]bool{}
+	nebulas := map[bson.ObjectID]bool{}
+	asteroids := map[bson.ObjectID]bool{}
+
+	for _, obs := range e.observers() {
+		if obs.PlayerID != playerID {
+			continue
+		}
+		if _, isGarrison := e.garrisons[obs.ID]; isGarrison {
+			systems[obs.ID] = true
+		} else {
+			stacks[obs.ID] = true
+		}
+		v := e.views[obs.ID]
+		for _, id := range v.Systems {
+			systems[id] = true
+		}
+		for _, id := range v.Stacks {
+			stacks[id] = true
+		}
+		for _, id := range v.Nebulas {
+			nebulas[id] = true
+		}
+		for _, id := range v.Asteroids {
+			asteroids[id] = true
+		}
+	}
+	pv.Systems = sortedIDs(systems)
+	pv.Stacks = sortedIDs(stacks)
+	pv.Nebulas = sortedIDs(nebulas)
+	pv.Asteroids = sortedIDs(asteroids)
+	return pv
+}
+
+func (e *VisibilityEngine) recomputeObserver(obs *ships.ShipStack, all []*ships.ShipStack, now time.Time) {
+	v := e.views[obs.ID]
+	if v == nil {
+		v = &ShipVisibility{StackID: obs.ID}
+		e.views[obs.ID] = v
+	}
+	r := e.profiles[obs.ID].Range
+
+	var systems, asteroids, nebulas []bson.ObjectID
+	for _, sys := range e.systems {
+		if sys.ID != obs.ID && inSensorRange(obs, r, sys.X, sys.Y, sys.CollisionRadius) {
+			systems = append(systems, sys.ID)
+		}
+	}
+	for _, a := range e.asteroids {
+		if inSensorRange(obs, r, a.X, a.Y, a.CollisionRadius) {
+			asteroids = append(asteroids, a.ID)
+		}
+	}
+	for _, n := range e.nebulas {
+		if inSensorRange(obs, r, n.X, n.Y, n.CollisionRadius) {
+			nebulas = append(nebulas, n.ID)
+		}
+	}
+
+	changed := !sameIDSet(v.Systems, systems) || !sameIDSet(v.Asteroids, asteroids) || !sameIDSet(v.Nebulas, nebulas)
+	if changed {
+		v.Systems, v.Asteroids, v.Nebulas = systems, asteroids, nebulas
+		v.LastUpdated = now
+		v.Version++
+	}
+	v.SetStacks(ships.DetectStacks(obs, all, e.profiles, now), now)
+	e.recomputeObserverPhantoms(obs, v, now)
+}
+
+// SetPhantoms replaces the map's decoy contacts, drops expired ones and
+// refreshes every observer's phantom list.
+func (e *VisibilityEngine) SetPhantoms(ps ships.PhantomSet, now time.Time) {
+	ps.Prune(now)
+	e.phantoms = ps
+	for _, obs := range e.observers() {
+		e.recomputeObserverPhantoms(obs, e.views[obs.ID], now)
+	}
+}
+
+// Contacts lists everything the player sees as a stack: real stacks and live
+// phantoms. Phantoms carry their fake composition unless revealed.
+func (e *VisibilityEngine) Contacts(playerID bson.ObjectID, now time.Time) []Contact {
+	pv := e.PlayerView(playerID)
+	out := make([]Contact, 0, len(pv.Stacks))
+	for _, id := range pv.Stacks {
+		s := e.stacks[id]
+		if s == nil {
+			continue
+		}
+		out = append(out, Contact{ID: s.ID, PlayerID: s.PlayerID, X: s.PositionX, Y: s.PositionY, Ships: s.Ships})
+	}
+
+	seen := map[bson.ObjectID]bool{}
+	for _, obs := range e.observers() {
+		if obs.PlayerID != playerID {
+			continue
+		}
+		for _, id := range e.views[obs.ID].Phantoms {
+			seen[id] = true
+		}
+	}
+	for id, p := range e.phantoms {
+		if p.PlayerID == playerID {
+			seen[id] = true
+		}
+	}
+	for _, id := range sortedIDs(seen) {
+		p := e.phantoms[id]
+		if p == nil || p.IsExpired(now) {
+			continue
+		}
+		out = append(out, Contact{ID: p.ID, PlayerID: p.PlayerID, X: p.X, Y: p.Y, Ships: p.Ships, Phantom: p.IsRevealedTo(playerID)})
+	}
+	return out
+}
+
+// recomputeObserverPhantoms lists the phantoms in the observer's sensor range
+// and reveals them as fake when the observer has CloakDetect close enough.
+func (e *VisibilityEngine) recomputeObserverPhantoms(obs *ships.ShipStack, v *ShipVisibility, now time.Time) {
+	op := e.profiles[obs.ID]
+	ids := map[bson.ObjectID]bool{}
+	for id, p := range e.phantoms {
+		if p.PlayerID == obs.PlayerID || p.IsExpired(now) {
+			continue
+		}
+		d := math.Hypot(obs.PositionX-p.X, obs.PositionY-p.Y)
+		if d > op.Range {
+			continue
+		}
+		ids[id] = true
+		if op.CloakDetect && d <= op.Range*ships.CloakDetectRangePct {
+			p.RevealTo(obs.PlayerID)
+		}
+	}
+	next := sortedIDs(ids)
+	if !sameIDSet(v.Phantoms, next) {
+		v.Phantoms = next
+		v.LastUpdated = now
+		v.Version++
+	}
+}
+
+// observers returns free stacks followed by garrisons, in a stable order.
+func (e *VisibilityEngine) observers() []*ships.ShipStack {
+	out := e.stackList()
+	ids := make(map[bson.ObjectID]bool, len(e.garrisons))
+	for id := range e.garrisons {
+		ids[id] = true
+	}
+	for _, id := range sortedIDs(ids) {
+		out = append(out, e.garrisons[id])
+	}
+	return out
+}
+
+func (e *VisibilityEngine) stackList() []*ships.ShipStack {
+	ids := make(map[bson.ObjectID]bool, len(e.stacks))
+	for id := range e.stacks {
+		ids[id] = true
+	}
+	out := make([]*ships.ShipStack, 0, len(ids))
+	for _, id := range sortedIDs(ids) {
+		out = append(out, e.stacks[id])
+	}
+	return out
+}
+
+// inSensorRange treats orbitables as discs: any part inside range is visible.
+func inSensorRange(obs *ships.ShipStack, r, x, y, radius float64) bool {
+	return math.Hypot(obs.PositionX-x, obs.PositionY-y) <= r+radius
+}
+
+func sortedIDs(set map[bson.ObjectID]bool) []bson.ObjectID {
+	out := make([]bson.ObjectID, 0, len(set))
+	for id := range set {
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i][:], out[j][:]) < 0 })
+	return out
+}
@@ -0,0 +1,143 @@
+package maps
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const visibilityMapSize = 20000.0
+
+// TestUpdateStacksMatchesRecompute tests that incremental updates after moves
+// and removals leave every view equal to a fresh full computation.
+func TestUpdateStacksMatchesRecompute(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rng := rand.New(rand.NewSource(1))
+	players := []bson.ObjectID{bson.NewObjectID(), bson.NewObjectID(), bson.NewObjectID()}
+	types := []ships.ShipType{ships.Scout, ships.Fighter, ships.Ghost, ships.Cruiser}
+
+	var stacks []*ships.ShipStack
+	for i := 0; i < 120; i++ {
+		st := types[i%len(types)]
+		s := &ships.ShipStack{
+			ID:        bson.NewObjectID(),
+			PlayerID:  players[i%len(players)],
+			PositionX: rng.Float64() * visibilityMapSize,
+			PositionY: rng.Float64() * visibilityMapSize,
+			Ships:     map[ships.ShipType][]ships.HPBucket{st: {{HP: ships.ShipBlueprints[st].HP, Count: 3}}},
+		}
+		if st == ships.Ghost && i%2 == 0 {
+			if _, err := s.ActivateAbility(ships.Ghost, ships.AbilityActiveCamo, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		stacks = append(stacks, s)
+	}
+	var systems []*orbitables.System
+	for i := 0; i < 20; i++ {
+		sys := &orbitables.System{ID: bson.NewObjectID(), X: rng.Float64() * visibilityMapSize, Y: rng.Float64() * visibilityMapSize, CollisionRadius: 200}
+		if i%3 == 0 {
+			sys.DefendingFleet = &orbitables.DefendingFleet{
+				PlayerID: players[i%len(players)],
+				Ships:    map[ships.ShipType][]ships.HPBucket{ships.Scout: {{HP: 50, Count: 2}}},
+			}
+		}
+		systems = append(systems, sys)
+	}
+	var asteroids []*orbitables.Asteroid
+	for i := 0; i < 20; i++ {
+		asteroids = append(asteroids, &orbitables.Asteroid{ID: bson.NewObjectID(), X: rng.Float64() * visibilityMapSize, Y: rng.Float64() * visibilityMapSize, CollisionRadius: 100})
+	}
+
+	e := NewVisibilityEngine(bson.NilObjectID, stacks, systems, asteroids, nil, now)
+	for round := 0; round < 10; round++ {
+		now = now.Add(time.Minute)
+		var moved []*ships.ShipStack
+		for _, s := range stacks {
+			if rng.Intn(4) == 0 {
+				s.PositionX += (rng.Float64() - 0.5) * 4000
+				s.PositionY += (rng.Float64() - 0.5) * 4000
+				moved = append(moved, s)
+			}
+		}
+		e.UpdateStacks(moved, now)
+		if round%3 == 2 {
+			gone := stacks[rng.Intn(len(stacks))]
+			e.RemoveStack(gone.ID, now)
+			kept := stacks[:0]
+			for _, s := range stacks {
+				if s != gone {
+					kept = append(kept, s)
+				}
+			}
+			stacks = kept
+		}
+
+		full := NewVisibilityEngine(bson.NilObjectID, stacks, systems, asteroids, nil, now)
+		contacts := 0
+		for _, obs := range full.observers() {
+			got, want := e.StackView(obs.ID), full.StackView(obs.ID)
+			contacts += len(want.Stacks)
+			if got == nil {
+				t.Fatalf("round %d: no incremental view for %s", round, obs.ID.Hex())
+			}
+			if !sameIDSet(got.Stacks, want.Stacks) || !sameIDSet(got.Systems, want.Systems) || !sameIDSet(got.Asteroids, want.Asteroids) {
+				t.Fatalf("round %d: view of %s = %+v, want %+v", round, obs.ID.Hex(), got, want)
+			}
+		}
+		if contacts == 0 {
+			t.Fatalf("round %d: no observer sees any stack", round)
+		}
+		for _, p := range players {
+			got, want := e.PlayerView(p), full.PlayerView(p)
+			if !sameIDSet(got.Stacks, want.Stacks) || !sameIDSet(got.Systems, want.Systems) {
+				t.Fatalf("round %d: player view = %+v, want %+v", round, got, want)
+			}
+		}
+	}
+}
+
+// TestPhantomContacts tests that enemies see a decoy as a stack until a
+// detector or ping exposes it, and that its owner always knows.
+func TestPhantomContacts(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner, enemy := bson.NewObjectID(), bson.NewObjectID()
+	watcher := &ships.ShipStack{
+		ID:       bson.NewObjectID(),
+		PlayerID: enemy,
+		Ships:    map[ships.ShipType][]ships.HPBucket{ships.Scout: {{HP: 50, Count: 1}}},
+	}
+	e := NewVisibilityEngine(bson.NilObjectID, []*ships.ShipStack{watcher}, nil, nil, nil, now)
+	r := ships.ComputeStackVisibilityRange(watcher, now)
+
+	near := &ships.PhantomContact{ID: bson.NewObjectID(), PlayerID: owner, X: r * 0.8, ExpiresAt: now.Add(time.Minute),
+		Ships: map[ships.ShipType][]ships.HPBucket{ships.Cruiser: {{HP: 1, Count: 30}}}}
+	far := &ships.PhantomContact{ID: bson.NewObjectID(), PlayerID: owner, X: r * 2, ExpiresAt: now.Add(time.Minute)}
+	stale := &ships.PhantomContact{ID: bson.NewObjectID(), PlayerID: owner, X: 10, ExpiresAt: now}
+	ps := ships.PhantomSet{near.ID: near, far.ID: far, stale.ID: stale}
+	e.SetPhantoms(ps, now)
+
+	if _, ok := ps[stale.ID]; ok {
+		t.Fatal("expired phantom kept")
+	}
+	contacts := e.Contacts(enemy, now)
+	if len(contacts) != 2 || contacts[1].ID != near.ID || contacts[1].Phantom || contacts[1].Ships[ships.Cruiser][0].Count != 30 {
+		t.Fatalf("enemy contacts = %+v", contacts)
+	}
+	if got := e.Contacts(owner, now); len(got) != 2 || !got[0].Phantom || !got[1].Phantom {
+		t.Fatalf("owner contacts = %+v", got)
+	}
+
+	// A sensor gem gives CloakDetect, which exposes phantoms within half range.
+	watcher.Loadouts = map[ships.ShipType]ships.ShipLoadout{ships.Scout: {Sockets: []ships.Gem{ships.GemCatalog["sensor-1"]}}}
+	watcher.PositionX = near.X - ships.ComputeStackVisibilityRange(watcher, now)*ships.CloakDetectRangePct/2
+	e.UpdateStacks([]*ships.ShipStack{watcher}, now)
+	contacts = e.Contacts(enemy, now)
+	if len(contacts) != 2 || !contacts[1].Phantom {
+		t.Fatalf("detector did not expose the phantom: %+v", contacts)
+	}
+}
@@ -0,0 +1,178 @@
+package orbitables
+
+import (
+	"errors"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/ships"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Capture
+//
+// Once a colonized system's garrison is destroyed (its DefendingFleet has no
+// ships left) an enemy stack inside the system can start a capture. If it is
+// still there, idle, and the garrison has not been rebuilt when
+// CaptureHoldDuration has passed, the stack becomes the new DefendingFleet.
+// Colonization.ColonizedBy keeps the original colonizer; DefendingFleet.PlayerID
+// is the new controller.
+//
+// The planet changes hands with it: every building loses CaptureDamagePct of
+// its levels (at least one, never below level 1) and abandons its running
+// jobs, and the shipyard drops the previous owner's orders unrefunded.
+//
+// An enemy stack idle inside the system wears the garrison down with
+// GarrisonRound: one formation round against the DefendingFleet, which fights
+// as a transient stack carrying the system's ID and loses ships like any
+// other defender.
+
+const (
+	CaptureHoldDuration = 10 * time.Minute
+	CaptureDamagePct    = 0.25
+)
+
+var (
+	ErrNoGarrison        = errors.New("system has no garrison to fight")
+	ErrGarrisonStanding  = errors.New("system garrison has not been destroyed")
+	ErrOwnSystem         = errors.New("player already controls the system")
+	ErrCaptureInProgress = errors.New("another stack is capturing the system")
+	ErrNoCapture         = errors.New("stack is not capturing the system")
+	ErrCaptureHolding    = errors.New("capture hold timer has not finished")
+)
+
+// CaptureState tracks a stack holding a system with a destroyed garrison.
+type CaptureState struct {
+	StackID   bson.ObjectID `bson:"stackId" json:"stackId"`
+	PlayerID  bson.ObjectID `bson:"playerId" json:"playerId"`
+	StartedAt time.Time     `bson:"startedAt" json:"startedAt"`
+	EndsAt    time.Time     `bson:"endsAt" json:"endsAt"`
+}
+
+// GarrisonDestroyed reports whether the system is colonized but its
+// DefendingFleet has no ships left.
+func (s *System) GarrisonDestroyed() bool {
+	return s.Colonization != nil && s.Colonization.IsColonized &&
+		s.DefendingFleet != nil && fleetTotal(s.DefendingFleet.Ships) == 0
+}
+
+// GarrisonRound fights one round between stack, as the attacker, and the
+// system's garrison. The garrison's losses are written back to DefendingFleet.
+func (s *System) GarrisonRound(stack *ships.ShipStack, now time.Time) (ships.FormationBattleResult, error) {
+	if s.Colonization == nil || !s.Colonization.IsColonized || s.DefendingFleet == nil || fleetTotal(s.DefendingFleet.Ships) == 0 {
+		return ships.FormationBattleResult{}, ErrNoGarrison
+	}
+	if s.DefendingFleet.PlayerID == stack.PlayerID {
+		return ships.FormationBattleResult{}, ErrOwnSystem
+	}
+	if err := s.checkArriving(stack); err != nil {
+		return ships.FormationBattleResult{}, err
+	}
+	garrison := &ships.ShipStack{
+		ID:        s.ID,
+		MapID:     s.MapID,
+		PlayerID:  s.DefendingFleet.PlayerID,
+		PositionX: s.X,
+		PositionY: s.Y,
+		Ships:     s.DefendingFleet.Ships,
+	}
+	garrison.EnsureFormationInitialized(now)
+	result := ships.ExecuteFormationBattleRound(stack, garrison, now)
+	s.DefendingFleet.Ships = garrison.Ships
+	return result, nil
+}
+
+// StartCapture begins the hold timer for stack and returns when it ends.
+func (s *System) StartCapture(stack *ships.ShipStack, now time.Time) (time.Time, error) {
+	if !s.GarrisonDestroyed() {
+		return time.Time{}, ErrGarrisonStanding
+	}
+	if s.DefendingFleet.PlayerID == stack.PlayerID {
+		return time.Time{}, ErrOwnSystem
+	}
+	if s.Capture != nil && s.Capture.StackID != stack.ID {
+		return time.Time{}, ErrCaptureInProgress
+	}
+	if err := s.checkArriving(stack); err != nil {
+		return time.Time{}, err
+	}
+	if s.Capture == nil {
+		s.Capture = &CaptureState{StackID: stack.ID, PlayerID: stack.PlayerID, StartedAt: now, EndsAt: now.Add(CaptureHoldDuration)}
+	}
+	return s.Capture.EndsAt, nil
+}
+
+// CheckCapture reports whether CompleteCapture would succeed without
+// changing anything.
+func (s *System) CheckCapture(stack *ships.ShipStack, now time.Time) error {
+	if s.Capture == nil || s.Capture.StackID != stack.ID {
+		return ErrNoCapture
+	}
+	if now.Before(s.Capture.EndsAt) {
+		return ErrCaptureHolding
+	}
+	if !s.GarrisonDestroyed() {
+		return ErrGarrisonStanding
+	}
+	return s.checkArriving(stack)
+}
+
+// CompleteCapture hands the system to the capturing stack, which is folded
+// into the new DefendingFleet, and returns the previous controller.
+func (s *System) CompleteCapture(stack *ships.ShipStack, now time.Time) (bson.ObjectID, error) {
+	if err := s.CheckCapture(stack, now); err != nil {
+		return bson.ObjectID{}, err
+	}
+	previous := s.DefendingFleet.PlayerID
+	s.DefendingFleet = &DefendingFleet{
+		OriginalStackID: stack.ID,
+		PlayerID:        stack.PlayerID,
+		ArrivedAt:       now,
+		Activity:        FleetActivityDefending,
+	}
+	s.Capture = nil
+	s.absorb(stack)
+	if s.Planet != nil {
+		if err := s.Planet.damageOnCapture(now); err != nil {
+			return previous, err
+		}
+	}
+	return previous, nil
+}
+
+// CancelCapture drops a capture in progress, e.g. when the stack leaves or
+// the garrison is rebuilt.
+func (s *System) CancelCapture() {
+	s.Capture = nil
+}
+
+// damageOnCapture knocks levels off every building and clears its jobs.
+func (p *Planet) damageOnCapture(now time.Time) error {
+	for _, slot := range Slots {
+		building, err := p.Building(slot)
+		if err != nil {
+			return err
+		}
+		if building == nil {
+			continue
+		}
+		base := b.Base(building)
+		if base.Level > 1 {
+			lost := max(1, int(float64(base.Level)*CaptureDamagePct))
+			base.Level = max(1, base.Level-lost)
+			base.Upkeep = b.UpkeepFor(building.GetType(), base.Level)
+		}
+		base.Queue = nil
+		base.LastUpdated = now
+		building = b.SetBase(building, base)
+		if yard, ok := building.(b.ShipYard); ok {
+			yard.Orders = nil
+			building = yard
+		}
+		if err := p.SetBuilding(slot, building); err != nil {
+			return err
+		}
+	}
+	return nil
+}
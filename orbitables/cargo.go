@@ -0,0 +1,21 @@
+package orbitables
+
+import (
+	"github.com/nicoberrocal/galaxyCore/ships"
+)
+
+// UnloadCargo moves the stack's hold into the system's planet. Only the
+// player controlling the system can unload there.
+func (s *System) UnloadCargo(stack *ships.ShipStack) (ships.Cargo, error) {
+	if !s.ControlledBy(stack.PlayerID) {
+		return ships.Cargo{}, ErrNotSystemOwner
+	}
+	if s.Planet == nil {
+		return ships.Cargo{}, ErrNoPlanet
+	}
+	c := stack.UnloadCargo()
+	s.Planet.Metals += c.Metals
+	s.Planet.Crystals += c.Crystals
+	s.Planet.Hydrogen += c.Hydrogen
+	return c, nil
+}
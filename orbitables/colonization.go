@@ -0,0 +1,197 @@
+package orbitables
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/ships"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Colonization
+//
+// Systems use the hybrid approach described on System: a stack that
+// colonizes or reinforces a system is folded into DefendingFleet and its own
+// document is deleted by the caller; Depart carves ships back out into a new
+// ShipStack. Embarked ships join the fleet and the hold is unloaded into the
+// planet (or lost without one); per-stack runtime (loadouts, bio, formation)
+// does not survive.
+//
+// Every operation validates before it mutates, so an error leaves the
+// system and stack untouched, and the consistency rules hold after each
+// call: a colonized system has a DefendingFleet and an abandoned one has
+// neither.
+
+// Defending fleet activities.
+const (
+	FleetActivityColonizing = "colonizing"
+	FleetActivityDefending  = "defending"
+)
+
+var (
+	ErrSystemColonized  = errors.New("system is already colonized")
+	ErrNotAtSystem      = errors.New("stack is not inside the system")
+	ErrEmptyStack       = errors.New("stack has no ships")
+	ErrNotEnoughDefense = errors.New("defending fleet does not have those ships")
+)
+
+// Colonize embeds the stack as the system's DefendingFleet. The stack must be
+// idle inside the system's CollisionRadius and the system unclaimed.
+func (s *System) Colonize(stack *ships.ShipStack, now time.Time) error {
+	if (s.Colonization != nil && s.Colonization.IsColonized) || s.DefendingFleet != nil {
+		return ErrSystemColonized
+	}
+	if err := s.checkArriving(stack); err != nil {
+		return err
+	}
+	s.Colonization = &Colonization{
+		IsColonized:       true,
+		ColonizedBy:       stack.PlayerID,
+		ColonizingFleetID: stack.ID,
+		ColonizedAt:       now,
+	}
+	s.DefendingFleet = &DefendingFleet{
+		OriginalStackID: stack.ID,
+		PlayerID:        stack.PlayerID,
+		ArrivedAt:       now,
+		Activity:        FleetActivityColonizing,
+	}
+	s.absorb(stack)
+	return nil
+}
+
+// Reinforce folds the stack into the DefendingFleet of a system its player
+// controls.
+func (s *System) Reinforce(stack *ships.ShipStack, now time.Time) error {
+	if !s.ControlledBy(stack.PlayerID) {
+		return ErrNotSystemOwner
+	}
+	if err := s.checkArriving(stack); err != nil {
+		return err
+	}
+	s.absorb(stack)
+	return nil
+}
+
+// Depart carves ships out of the DefendingFleet into a new stack with the
+// given ID at the system's position. take lists how many of each type leave,
+// healthiest first; nil takes the whole fleet. A system left without ships
+// is abandoned.
+func (s *System) Depart(playerID bson.ObjectID, take map[ships.ShipType]int, stackID bson.ObjectID, now time.Time) (*ships.ShipStack, error) {
+	if !s.ControlledBy(playerID) {
+		return nil, ErrNotSystemOwner
+	}
+	fleet := s.DefendingFleet.Ships
+	if take == nil {
+		take = make(map[ships.ShipType]int, len(fleet))
+		for t, buckets := range fleet {
+			for _, b := range buckets {
+				take[t] += b.Count
+			}
+		}
+	}
+	moving := 0
+	for t, n := range take {
+		if n < 0 || n > fleetCount(fleet[t]) {
+			return nil, ErrNotEnoughDefense
+		}
+		moving += n
+	}
+	if moving == 0 {
+		return nil, ErrEmptyStack
+	}
+
+	out := make(map[ships.ShipType][]ships.HPBucket, len(take))
+	for t, n := range take {
+		buckets := append([]ships.HPBucket(nil), fleet[t]...)
+		sort.SliceStable(buckets, func(i, j int) bool { return buckets[i].HP > buckets[j].HP })
+		var left []ships.HPBucket
+		for _, b := range buckets {
+			moved := min(n, b.Count)
+			n -= moved
+			if moved > 0 {
+				out[t] = append(out[t], ships.HPBucket{HP: b.HP, Count: moved})
+			}
+			if b.Count > moved {
+				left = append(left, ships.HPBucket{HP: b.HP, Count: b.Count - moved})
+			}
+		}
+		if len(left) == 0 {
+			delete(fleet, t)
+		} else {
+			fleet[t] = left
+		}
+	}
+	if len(fleet) == 0 {
+		s.DefendingFleet = nil
+		s.Colonization.IsColonized = false
+	}
+
+	stack := &ships.ShipStack{
+		ID:        stackID,
+		PlayerID:  playerID,
+		MapID:     s.MapID,
+		PositionX: s.X,
+		PositionY: s.Y,
+		Ships:     out,
+		CreatedAt: now,
+	}
+	stack.EnsureFormationInitialized(now)
+	stack.UpdateStackAttackRange(now)
+	return stack, nil
+}
+
+// checkArriving validates a stack about to join the system.
+func (s *System) checkArriving(stack *ships.ShipStack) error {
+	if len(stack.Movement) > 0 || (stack.Battle != nil && stack.Battle.IsInCombat) ||
+		(stack.Gathering != nil && stack.Gathering.IsGathering) || stack.IsAnchored() {
+		return ships.ErrStackBusy
+	}
+	if math.Hypot(stack.PositionX-s.X, stack.PositionY-s.Y) > s.CollisionRadius {
+		return ErrNotAtSystem
+	}
+	if fleetTotal(stack.Ships)+fleetTotal(stack.Hangar) == 0 {
+		return ErrEmptyStack
+	}
+	return nil
+}
+
+// absorb moves the stack's ships, hangar and hold into the system and
+// empties the stack.
+func (s *System) absorb(stack *ships.ShipStack) {
+	for _, src := range []map[ships.ShipType][]ships.HPBucket{stack.Ships, stack.Hangar} {
+		for t, buckets := range src {
+			for _, b := range buckets {
+				if b.Count > 0 {
+					s.DefendingFleet.Ships = addFleetShips(s.DefendingFleet.Ships, t, b.HP, b.Count)
+				}
+			}
+		}
+	}
+	if s.Planet != nil {
+		c := stack.UnloadCargo()
+		s.Planet.Metals += c.Metals
+		s.Planet.Crystals += c.Crystals
+		s.Planet.Hydrogen += c.Hydrogen
+	}
+	stack.Ships, stack.Hangar, stack.Cargo = nil, nil, nil
+}
+
+func fleetCount(buckets []ships.HPBucket) int {
+	n := 0
+	for _, b := range buckets {
+		n += b.Count
+	}
+	return n
+}
+
+func fleetTotal(fleet map[ships.ShipType][]ships.HPBucket) int {
+	n := 0
+	for _, buckets := range fleet {
+		n += fleetCount(buckets)
+	}
+	return n
+}
@@ -0,0 +1,238 @@
+package orbitables
+
+import (
+	"errors"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Building slots
+//
+// Each planet slot holds at most one building and runs at most one upgrade
+// job at a time. Starting a job charges the planet up front; cancelling it
+// refunds the share of the price matching the time left, and cancelling the
+// construction of a brand new building clears the slot again. Jobs complete
+// when CompleteUpgrades is called at or after their end time.
+
+// Planet slot names, matching the Planet bson field names.
+const (
+	SlotNorthPole           = "northPole"
+	SlotLeft                = "left"
+	SlotRight               = "right"
+	SlotBack                = "back"
+	SlotFront               = "front"
+	SlotShipYard            = "shipyard"
+	SlotParticleAccelerator = "particleAccelerator"
+	SlotFusionReactor       = "fusionReactor"
+)
+
+// Slots lists every planet slot in processing order.
+var Slots = []string{SlotNorthPole, SlotLeft, SlotRight, SlotBack, SlotFront, SlotShipYard, SlotParticleAccelerator, SlotFusionReactor}
+
+// surfaceBuildings may be placed on the pole and side slots.
+var surfaceBuildings = map[string]bool{
+	"solar_farm": true, "wind_farm": true, "hydro_electric_dam": true, "balloon": true,
+	"metal_mine": true, "crystal_mine": true,
+}
+
+var (
+	ErrUnknownSlot      = errors.New("unknown planet slot")
+	ErrSlotBusy         = errors.New("slot already has a job running")
+	ErrSlotEmpty        = errors.New("slot is empty")
+	ErrSlotMismatch     = errors.New("building type does not fit this slot")
+	ErrNoUpgradeRunning = errors.New("no upgrade running in this slot")
+)
+
+// SlotAllows reports whether buildingType can be built in slot.
+func SlotAllows(slot, buildingType string) bool {
+	switch slot {
+	case SlotShipYard:
+		return buildingType == "shipyard"
+	case SlotParticleAccelerator:
+		return buildingType == "particle_accelerator"
+	case SlotFusionReactor:
+		return buildingType == "fusion_reactor"
+	case SlotNorthPole, SlotLeft, SlotRight, SlotBack, SlotFront:
+		return surfaceBuildings[buildingType]
+	}
+	return false
+}
+
+func (p *Planet) slotField(slot string) (**bson.M, error) {
+	switch slot {
+	case SlotNorthPole:
+		return &p.NorthPole, nil
+	case SlotLeft:
+		return &p.Left, nil
+	case SlotRight:
+		return &p.Right, nil
+	case SlotBack:
+		return &p.Back, nil
+	case SlotFront:
+		return &p.Front, nil
+	case SlotShipYard:
+		return &p.ShipYard, nil
+	case SlotParticleAccelerator:
+		return &p.ParticleAccelerator, nil
+	case SlotFusionReactor:
+		return &p.FusionReactor, nil
+	}
+	return nil, ErrUnknownSlot
+}
+
+// Building returns the building in slot, or nil when the slot is empty.
+func (p *Planet) Building(slot string) (b.Building, error) {
+	field, err := p.slotField(slot)
+	if err != nil {
+		return nil, err
+	}
+	if *field == nil {
+		return nil, nil
+	}
+	return b.CreateBuildingFromMongoDB(**field)
+}
+
+// SetBuilding stores building in slot; nil clears the slot.
+func (p *Planet) SetBuilding(slot string, building b.Building) error {
+	field, err := p.slotField(slot)
+	if err != nil {
+		return err
+	}
+	if building == nil {
+		*field = nil
+		return nil
+	}
+	doc, err := b.EncodeBuilding(building)
+	if err != nil {
+		return err
+	}
+	*field = &doc
+	return nil
+}
+
+// StartUpgrade begins raising the building in slot by one level. On an empty
+// slot buildingType names the building to construct; on an occupied one it
+// may be left empty or must match the existing building.
+func (p *Planet) StartUpgrade(slot, buildingType string, now time.Time) (b.Queue, error) {
+	building, err := p.Building(slot)
+	if err != nil {
+		return b.Queue{}, err
+	}
+	if building == nil {
+		if buildingType == "" {
+			return b.Queue{}, ErrSlotEmpty
+		}
+		if !SlotAllows(slot, buildingType) {
+			return b.Queue{}, ErrSlotMismatch
+		}
+		if building, err = b.NewBuilding(buildingType, buildingType); err != nil {
+			return b.Queue{}, err
+		}
+	} else if buildingType != "" && buildingType != building.GetType() {
+		return b.Queue{}, ErrSlotMismatch
+	}
+	if _, busy := b.ActiveUpgrade(building); busy {
+		return b.Queue{}, ErrSlotBusy
+	}
+
+	metal, crystal, d, err := b.UpgradeCost(building.GetType(), building.GetLevel()+1)
+	if err != nil {
+		return b.Queue{}, err
+	}
+	if p.Metals < metal || p.Crystals < crystal {
+		return b.Queue{}, ErrInsufficientResources
+	}
+
+	job := b.NewUpgradeJob(now, d)
+	base := b.Base(building)
+	base.Queue = append(base.Queue, job)
+	base.ConstuctionTime = job.End()
+	base.LastUpdated = now
+	if err := p.SetBuilding(slot, b.SetBase(building, base)); err != nil {
+		return b.Queue{}, err
+	}
+	p.Metals -= metal
+	p.Crystals -= crystal
+	return job, nil
+}
+
+// CancelUpgrade stops the job in slot and refunds the unspent share of its
+// price. Cancelling a new building's construction empties the slot.
+func (p *Planet) CancelUpgrade(slot string, now time.Time) error {
+	building, err := p.Building(slot)
+	if err != nil {
+		return err
+	}
+	if building == nil {
+		return ErrSlotEmpty
+	}
+	job, ok := b.ActiveUpgrade(building)
+	if !ok {
+		return ErrNoUpgradeRunning
+	}
+	metal, crystal, _, err := b.UpgradeCost(building.GetType(), building.GetLevel()+1)
+	if err != nil {
+		return err
+	}
+	frac := 1.0
+	start, end := job.Start.Time(), job.End()
+	if total := end.Sub(start); total > 0 && now.After(start) {
+		frac = float64(end.Sub(now)) / float64(total)
+		if frac < 0 {
+			frac = 0
+		}
+	}
+	p.Metals += int64(float64(metal) * frac)
+	p.Crystals += int64(float64(crystal) * frac)
+
+	if building.GetLevel() == 0 {
+		return p.SetBuilding(slot, nil)
+	}
+	base := b.Base(building)
+	base.Queue = withoutUpgrade(base.Queue)
+	base.ConstuctionTime = time.Time{}
+	base.LastUpdated = now
+	return p.SetBuilding(slot, b.SetBase(building, base))
+}
+
+// CompleteUpgrades finishes every job that ended by now and returns the
+// slots whose building gained a level.
+func (p *Planet) CompleteUpgrades(now time.Time) ([]string, error) {
+	var done []string
+	for _, slot := range Slots {
+		building, err := p.Building(slot)
+		if err != nil {
+			return done, err
+		}
+		if building == nil {
+			continue
+		}
+		job, ok := b.ActiveUpgrade(building)
+		if !ok || job.End().After(now) {
+			continue
+		}
+		base := b.Base(building)
+		base.Level++
+		base.Upkeep = b.UpkeepFor(building.GetType(), base.Level)
+		base.Queue = withoutUpgrade(base.Queue)
+		base.LastUpdated = job.End()
+		if err := p.SetBuilding(slot, b.SetBase(building, base)); err != nil {
+			return done, err
+		}
+		done = append(done, slot)
+	}
+	return done, nil
+}
+
+func withoutUpgrade(queue []b.Queue) []b.Queue {
+	var out []b.Queue
+	for _, q := range queue {
+		if q.Action != b.QueueActionUpgrade {
+			out = append(out, q)
+		}
+	}
+	return out
+}
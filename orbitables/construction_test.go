@@ -0,0 +1,84 @@
+package orbitables
+
+import (
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+)
+
+func TestPlanetUpgradeLifecycle(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := &Planet{Metals: 10000, Crystals: 10000}
+
+	if _, err := p.StartUpgrade(SlotLeft, "shipyard", now); err != ErrSlotMismatch {
+		t.Fatalf("shipyard on a side slot: got %v", err)
+	}
+	job, err := p.StartUpgrade(SlotLeft, "metal_mine", now)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	metal, crystal, d, _ := b.UpgradeCost("metal_mine", 1)
+	if p.Metals != 10000-metal || p.Crystals != 10000-crystal || !job.End().Equal(now.Add(d)) {
+		t.Fatalf("charged to %d/%d, job ends %v", p.Metals, p.Crystals, job.End())
+	}
+	if _, err := p.StartUpgrade(SlotLeft, "", now); err != ErrSlotBusy {
+		t.Fatalf("second job: got %v", err)
+	}
+
+	if done, _ := p.CompleteUpgrades(now.Add(d - time.Second)); len(done) != 0 {
+		t.Fatalf("completed early: %v", done)
+	}
+	if done, _ := p.CompleteUpgrades(now.Add(d)); len(done) != 1 || done[0] != SlotLeft {
+		t.Fatalf("completed %v, want [left]", done)
+	}
+	mine, _ := p.Building(SlotLeft)
+	if mine.GetLevel() != 1 || len(mine.GetQueue()) != 0 {
+		t.Fatalf("after completion: level %d, queue %v", mine.GetLevel(), mine.GetQueue())
+	}
+
+	// Cancelling halfway refunds half of the level 2 price.
+	start := now.Add(d)
+	before := p.Metals
+	if _, err := p.StartUpgrade(SlotLeft, "", start); err != nil {
+		t.Fatalf("level 2: %v", err)
+	}
+	metal2, _, d2, _ := b.UpgradeCost("metal_mine", 2)
+	if err := p.CancelUpgrade(SlotLeft, start.Add(d2/2)); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if got := before - p.Metals; got != metal2-metal2/2 {
+		t.Fatalf("net metal cost %d, want %d", got, metal2-metal2/2)
+	}
+	mine, _ = p.Building(SlotLeft)
+	if mine.GetLevel() != 1 || len(mine.GetQueue()) != 0 {
+		t.Fatalf("after cancel: level %d, queue %v", mine.GetLevel(), mine.GetQueue())
+	}
+}
+
+// unencodable cannot be marshalled to BSON.
+type unencodable struct {
+	b.BaseBuilding
+	Ch chan int `bson:"ch"`
+}
+
+func TestSetBuildingReportsEncodeErrors(t *testing.T) {
+	p := &Planet{}
+	yard := b.ShipYard{BaseBuilding: b.BaseBuilding{Level: 1}}
+	if err := p.SetShipYard(yard); err != nil {
+		t.Fatalf("set shipyard: %v", err)
+	}
+	before := p.ShipYard
+	if err := p.SetBuilding(SlotShipYard, unencodable{Ch: make(chan int)}); err == nil {
+		t.Fatal("SetBuilding accepted a building that cannot be encoded")
+	}
+	if err := p.SetShipYard(unencodable{Ch: make(chan int)}); err == nil {
+		t.Fatal("SetShipYard accepted a building that cannot be encoded")
+	}
+	if p.ShipYard != before {
+		t.Fatal("failed set replaced the stored shipyard")
+	}
+	if _, err := b.BuildingToBSON(unencodable{Ch: make(chan int)}); err == nil {
+		t.Fatal("BuildingToBSON hid the encode error")
+	}
+}
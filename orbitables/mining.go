@@ -0,0 +1,216 @@
+package orbitables
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/ships"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Mining
+//
+// Asteroids and nebulas are mined in whole MiningTicks. Each tick a stack
+// listed in MiningFleets and gathering from the deposit asks for
+//
+//	ExtractionRate * MiningPower
+//
+// where MiningPower counts anchored ships weighted by their hull's
+// EconomicCap (a Drone is 1), capped by the room left in its cargo hold.
+// When the deposit holds less than the fleets ask for, what is left is
+// shared in proportion to their demand; units lost to rounding go one each to
+// the largest demands, ties broken by stack ID. A deposit that reaches zero
+// is marked Exhausted and stops being mined.
+//
+// StartMining lists a stack in MiningFleets and sets it gathering from the
+// deposit; the game layer calls it once an anchor on the deposit holds, and
+// StopMining when the anchor is released.
+
+// MiningTick is the period ExtractionRate is expressed in.
+const MiningTick = time.Minute
+
+// ErrDepositExhausted rejects mining a deposit with nothing left.
+var ErrDepositExhausted = errors.New("deposit is exhausted")
+
+// MiningYield is what one stack extracted from a deposit.
+type MiningYield struct {
+	StackID  bson.ObjectID
+	Resource string
+	Amount   int64
+}
+
+// StartMining sets the stack mining the asteroid.
+func (a *Asteroid) StartMining(s *ships.ShipStack, now time.Time) error {
+	return a.ResourceExtraction.join(a.ID, "asteroid", a.X, a.Y, a.CollisionRadius, s, now)
+}
+
+// StopMining takes the stack off the asteroid's MiningFleets.
+func (a *Asteroid) StopMining(stackID bson.ObjectID) {
+	a.ResourceExtraction.leave(stackID)
+}
+
+// StartMining sets the stack mining the nebula.
+func (n *Nebula) StartMining(s *ships.ShipStack, now time.Time) error {
+	return n.ResourceExtraction.join(n.ID, "nebula", n.X, n.Y, n.CollisionRadius, s, now)
+}
+
+// StopMining takes the stack off the nebula's MiningFleets.
+func (n *Nebula) StopMining(stackID bson.ObjectID) {
+	n.ResourceExtraction.leave(stackID)
+}
+
+// Mine extracts from the asteroid for the stacks gathering from it.
+func (a *Asteroid) Mine(stacks []*ships.ShipStack, now time.Time) []MiningYield {
+	return a.ResourceExtraction.extract(a.ID, stacks, now)
+}
+
+// Mine extracts from the nebula for the stacks gathering from it.
+func (n *Nebula) Mine(stacks []*ships.ShipStack, now time.Time) []MiningYield {
+	return n.ResourceExtraction.extract(n.ID, stacks, now)
+}
+
+// extract runs every whole MiningTick since LastExtracted. The first call
+// only starts the clock. Stacks not listed in MiningFleets or not gathering
+// from depositID are ignored.
+func (r *ResourceExtraction) extract(depositID bson.ObjectID, stacks []*ships.ShipStack, now time.Time) []MiningYield {
+	if r.LastExtracted.IsZero() {
+		r.LastExtracted = now
+		return nil
+	}
+	ticks := int64(now.Sub(r.LastExtracted) / MiningTick)
+	if ticks <= 0 {
+		return nil
+	}
+	r.LastExtracted = r.LastExtracted.Add(time.Duration(ticks) * MiningTick)
+	if r.Exhausted || r.RemainingRes <= 0 {
+		r.markExhausted()
+		return nil
+	}
+
+	miners := r.miners(depositID, stacks)
+	demand := make([]int64, len(miners))
+	var total int64
+	for i, s := range miners {
+		demand[i] = int64(math.Floor(float64(r.ExtractionRate) * s.MiningPower() * float64(ticks)))
+		if free := s.CargoFree(now); demand[i] > free {
+			demand[i] = free
+		}
+		total += demand[i]
+	}
+	r.IsBeingMined = total > 0
+	if total == 0 {
+		return nil
+	}
+	if total > r.RemainingRes {
+		demand = shareDeposit(demand, total, r.RemainingRes)
+		total = r.RemainingRes
+	}
+
+	var yields []MiningYield
+	for i, s := range miners {
+		if demand[i] > 0 {
+			yields = append(yields, MiningYield{StackID: s.ID, Resource: r.ResourceType, Amount: demand[i]})
+		}
+	}
+	r.RemainingRes -= total
+	if r.RemainingRes <= 0 {
+		r.markExhausted()
+	}
+	return yields
+}
+
+// join lists the stack in MiningFleets and sets it gathering from the
+// deposit centred at (x, y).
+func (r *ResourceExtraction) join(depositID bson.ObjectID, kind string, x, y, radius float64, s *ships.ShipStack, now time.Time) error {
+	if len(s.Movement) > 0 || (s.Battle != nil && s.Battle.IsInCombat) {
+		return ships.ErrStackBusy
+	}
+	if r.Exhausted {
+		return ErrDepositExhausted
+	}
+	if math.Hypot(s.PositionX-x, s.PositionY-y) > radius {
+		return ships.ErrAnchorOutOfRange
+	}
+	if !containsID(r.MiningFleets, s.ID) {
+		r.MiningFleets = append(r.MiningFleets, s.ID)
+	}
+	s.Gathering = &ships.GatheringState{
+		IsGathering: true,
+		TargetID:    depositID,
+		TargetType:  kind,
+		StartTime:   now,
+		ProcessedAt: now,
+	}
+	return nil
+}
+
+// leave drops the stack from MiningFleets.
+func (r *ResourceExtraction) leave(stackID bson.ObjectID) {
+	kept := r.MiningFleets[:0]
+	for _, id := range r.MiningFleets {
+		if id != stackID {
+			kept = append(kept, id)
+		}
+	}
+	r.MiningFleets = kept
+	if len(kept) == 0 {
+		r.IsBeingMined = false
+	}
+}
+
+func containsID(ids []bson.ObjectID, id bson.ObjectID) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+// miners returns the stacks mining the deposit, in ID order.
+func (r *ResourceExtraction) miners(depositID bson.ObjectID, stacks []*ships.ShipStack) []*ships.ShipStack {
+	listed := make(map[bson.ObjectID]bool, len(r.MiningFleets))
+	for _, id := range r.MiningFleets {
+		listed[id] = true
+	}
+	var out []*ships.ShipStack
+	for _, s := range stacks {
+		if s == nil || !listed[s.ID] || s.Gathering == nil || !s.Gathering.IsGathering || s.Gathering.TargetID != depositID {
+			continue
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].ID[:], out[j].ID[:]) < 0 })
+	return out
+}
+
+func (r *ResourceExtraction) markExhausted() {
+	r.RemainingRes = 0
+	r.Exhausted = true
+	r.IsBeingMined = false
+}
+
+// shareDeposit splits available among demands (summing to total) in
+// proportion, handing rounding leftovers to the largest demands first.
+func shareDeposit(demand []int64, total, available int64) []int64 {
+	out := make([]int64, len(demand))
+	var given int64
+	for i, d := range demand {
+		out[i] = d * available / total
+		given += out[i]
+	}
+	order := make([]int, len(demand))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return demand[order[a]] > demand[order[b]] })
+	for k := 0; given < available; k = (k + 1) % len(order) {
+		out[order[k]]++
+		given++
+	}
+	return out
+}
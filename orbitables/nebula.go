@@ -13,6 +13,9 @@ type ResourceExtraction struct {
 	ExtractionRate int64           `bson:"extractionRate"` // Resources per tick per fleet
 	RemainingRes   int64           `bson:"remainingRes"`   // Remaining resources
 	LastExtracted  time.Time       `bson:"lastExtracted"`  // Last time resources were extracted
+
+	// Set once RemainingRes reaches zero; the deposit yields nothing more
+	Exhausted bool `bson:"exhausted,omitempty"`
 }
 
 type Nebula struct {
@@ -0,0 +1,86 @@
+package orbitables
+
+import (
+	"math"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+)
+
+// ProductionRate sums the hourly output of every building on the planet,
+// scaled by the planet's brownout level.
+func (p *Planet) ProductionRate() (b.Output, error) {
+	var total b.Output
+	for _, slot := range Slots {
+		building, err := p.Building(slot)
+		if err != nil {
+			return b.Output{}, err
+		}
+		if building == nil {
+			continue
+		}
+		factor := b.PowerFactor(building.GetType(), p.Brownout)
+		total = total.Add(b.ProductionRate(building, p.Type).Scale(factor))
+	}
+	return total, nil
+}
+
+// UpkeepRate sums the hourly energy upkeep of every powered building on the
+// planet. Buildings still under construction cost nothing.
+func (p *Planet) UpkeepRate() (float64, error) {
+	total := 0.0
+	for _, slot := range Slots {
+		building, err := p.Building(slot)
+		if err != nil {
+			return 0, err
+		}
+		if building == nil || building.GetLevel() < 1 {
+			continue
+		}
+		total += float64(b.UpkeepRate(building)) * b.PowerFactor(building.GetType(), p.Brownout)
+	}
+	return total, nil
+}
+
+// AccrueProduction credits the output produced since LastProduced. Metals,
+// crystals and plasma go into the planet's stocks, plasma burning hydrogen as
+// it is made; the whole units produced are returned, with Energy net of
+// upkeep (it belongs to the owner's PlayerGameState and may be negative).
+// Fractions carry over so frequent accrual in small steps loses nothing. The
+// first call only starts the clock.
+func (p *Planet) AccrueProduction(now time.Time) (b.Output, error) {
+	if p.LastProduced.IsZero() || !now.After(p.LastProduced) {
+		if p.LastProduced.IsZero() {
+			p.LastProduced = now
+		}
+		return b.Output{}, nil
+	}
+	rate, err := p.ProductionRate()
+	if err != nil {
+		return b.Output{}, err
+	}
+	upkeep, err := p.UpkeepRate()
+	if err != nil {
+		return b.Output{}, err
+	}
+	rate.Energy -= upkeep
+	total := p.ProductionCarry.Add(b.Produced(rate, now.Sub(p.LastProduced)))
+	whole := b.Output{
+		Energy:   math.Floor(total.Energy),
+		Metals:   math.Floor(total.Metals),
+		Crystals: math.Floor(total.Crystals),
+		Plasma:   math.Floor(total.Plasma),
+	}
+	p.ProductionCarry = total.Add(whole.Scale(-1))
+	if fuel := float64(p.Hydrogen / b.HydrogenPerPlasma); whole.Plasma > fuel {
+		// Out of hydrogen: nothing left to carry over either.
+		whole.Plasma = fuel
+		p.ProductionCarry.Plasma = 0
+	}
+	p.Metals += int64(whole.Metals)
+	p.Crystals += int64(whole.Crystals)
+	p.Plasma += int64(whole.Plasma)
+	p.Hydrogen -= int64(whole.Plasma) * b.HydrogenPerPlasma
+	p.LastProduced = now
+	return whole, nil
+}
@@ -0,0 +1,45 @@
+package orbitables
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+)
+
+func TestPlanetAccrueProduction(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := &Planet{Type: b.PlanetMars}
+	p.SetBuilding(SlotLeft, b.MetalMine{MineBuilding: b.MineBuilding{BaseBuilding: b.BaseBuilding{Level: 3}}})
+	p.SetBuilding(SlotRight, b.SolarFarm{EnergyBuilding: b.EnergyBuilding{BaseBuilding: b.BaseBuilding{Level: 1}}})
+	p.SetBuilding(SlotFront, b.WindFarm{}) // level 0: still being built
+
+	rate, err := p.ProductionRate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMetal := 100 * 1.2 * math.Pow(1.15, 2)
+	if math.Abs(rate.Metals-wantMetal) > 1e-9 || rate.Energy != 80*0.6 || rate.Crystals != 0 {
+		t.Fatalf("rate = %+v, want metals %.3f, energy 48", rate, wantMetal)
+	}
+
+	if out, _ := p.AccrueProduction(now); out != (b.Output{}) {
+		t.Fatalf("first accrual produced %+v", out)
+	}
+	// Two hours in one-minute steps: nothing is lost to rounding. Energy is
+	// net of upkeep.
+	var energy float64
+	for i := 1; i <= 120; i++ {
+		out, err := p.AccrueProduction(now.Add(time.Duration(i) * time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		energy += out.Energy
+	}
+	// 48/h from the farm minus the level 3 mine's upkeep.
+	wantEnergy := 2 * (48 - float64(b.UpkeepFor("metal_mine", 3)))
+	if energy != wantEnergy || p.Metals != int64(math.Floor(2*wantMetal+1e-9)) {
+		t.Fatalf("after 2h: energy %v, metals %d", energy, p.Metals)
+	}
+}
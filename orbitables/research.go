@@ -0,0 +1,167 @@
+package orbitables
+
+import (
+	"errors"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/ships"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Research
+//
+// A planet's particle accelerator runs one research project at a time as a
+// QueueActionResearch job on its queue. The project's price is charged up
+// front and refunded pro rata on cancel. While a brownout keeps the
+// accelerator offline the job is paused: AdvanceResearch pushes its start
+// back by the offline time, so progress resumes where it stopped.
+
+var (
+	ErrNoAccelerator      = errors.New("planet has no particle accelerator")
+	ErrAcceleratorOffline = errors.New("particle accelerator is offline")
+	ErrResearchBusy       = errors.New("particle accelerator is already researching")
+	ErrNoResearchRunning  = errors.New("no research running")
+)
+
+func (p *Planet) accelerator() (b.Building, error) {
+	building, err := p.Building(SlotParticleAccelerator)
+	if err != nil {
+		return nil, err
+	}
+	if building == nil || building.GetLevel() < 1 {
+		return nil, ErrNoAccelerator
+	}
+	return building, nil
+}
+
+func (p *Planet) acceleratorPowered() bool {
+	return b.PowerFactor("particle_accelerator", p.Brownout) > 0
+}
+
+// StartResearch charges the planet and starts projectID on its particle
+// accelerator. completed lists the owner's finished projects.
+func (p *Planet) StartResearch(projectID string, completed []string, now time.Time) (b.Queue, error) {
+	building, err := p.accelerator()
+	if err != nil {
+		return b.Queue{}, err
+	}
+	if !p.acceleratorPowered() {
+		return b.Queue{}, ErrAcceleratorOffline
+	}
+	if _, busy := b.ActiveResearch(building); busy {
+		return b.Queue{}, ErrResearchBusy
+	}
+	proj, err := ships.CanResearch(projectID, completed, building.GetLevel())
+	if err != nil {
+		return b.Queue{}, err
+	}
+	cost := ships.BuildCost{Metal: proj.Metal, Crystal: proj.Crystal, Plasma: proj.Plasma}
+	if !p.CanAfford(cost) {
+		return b.Queue{}, ErrInsufficientResources
+	}
+	p.Metals -= cost.Metal
+	p.Crystals -= cost.Crystal
+	p.Plasma -= cost.Plasma
+
+	job := b.NewResearchJob(now, proj.ID, time.Duration(proj.DurationSeconds)*time.Second)
+	base := b.Base(building)
+	base.Queue = append(base.Queue, job)
+	base.LastUpdated = now
+	return job, p.SetBuilding(SlotParticleAccelerator, b.SetBase(building, base))
+}
+
+// CancelResearch stops the running project and refunds the share of its
+// price matching the time left.
+func (p *Planet) CancelResearch(now time.Time) error {
+	building, err := p.accelerator()
+	if err != nil {
+		return err
+	}
+	job, ok := b.ActiveResearch(building)
+	if !ok {
+		return ErrNoResearchRunning
+	}
+	if proj, ok := ships.ResearchCatalog[job.Project]; ok {
+		frac := 1 - researchProgress(job, now)
+		p.Metals += int64(float64(proj.Metal) * frac)
+		p.Crystals += int64(float64(proj.Crystal) * frac)
+		p.Plasma += int64(float64(proj.Plasma) * frac)
+	}
+	base := b.Base(building)
+	base.Queue = withoutResearch(base.Queue)
+	base.LastUpdated = now
+	return p.SetBuilding(SlotParticleAccelerator, b.SetBase(building, base))
+}
+
+// ResearchProgress returns the running project and how far along it is,
+// from 0 to 1.
+func (p *Planet) ResearchProgress(now time.Time) (string, float64, bool) {
+	building, err := p.accelerator()
+	if err != nil {
+		return "", 0, false
+	}
+	job, ok := b.ActiveResearch(building)
+	if !ok {
+		return "", 0, false
+	}
+	return job.Project, researchProgress(job, now), true
+}
+
+// AdvanceResearch moves the running project over [from, to]. An offline
+// accelerator pauses it; otherwise a project ending by to completes and its
+// ID is returned.
+func (p *Planet) AdvanceResearch(from, to time.Time) (string, error) {
+	building, err := p.Building(SlotParticleAccelerator)
+	if err != nil || building == nil {
+		return "", err
+	}
+	job, ok := b.ActiveResearch(building)
+	if !ok {
+		return "", nil
+	}
+	base := b.Base(building)
+	if !p.acceleratorPowered() {
+		if start := job.Start.Time(); start.After(from) {
+			from = start
+		}
+		if paused := to.Sub(from); paused > 0 {
+			for i := range base.Queue {
+				if base.Queue[i].Action == b.QueueActionResearch {
+					base.Queue[i].Start = bson.NewDateTimeFromTime(job.Start.Time().Add(paused))
+				}
+			}
+			return "", p.SetBuilding(SlotParticleAccelerator, b.SetBase(building, base))
+		}
+		return "", nil
+	}
+	if job.End().After(to) {
+		return "", nil
+	}
+	base.Queue = withoutResearch(base.Queue)
+	base.LastUpdated = job.End()
+	return job.Project, p.SetBuilding(SlotParticleAccelerator, b.SetBase(building, base))
+}
+
+func researchProgress(job b.Queue, now time.Time) float64 {
+	start, end := job.Start.Time(), job.End()
+	total := end.Sub(start)
+	switch {
+	case !now.After(start):
+		return 0
+	case total <= 0 || !now.Before(end):
+		return 1
+	}
+	return float64(now.Sub(start)) / float64(total)
+}
+
+func withoutResearch(queue []b.Queue) []b.Queue {
+	var out []b.Queue
+	for _, q := range queue {
+		if q.Action != b.QueueActionResearch {
+			out = append(out, q)
+		}
+	}
+	return out
+}
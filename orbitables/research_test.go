@@ -0,0 +1,79 @@
+package orbitables
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/ships"
+)
+
+func TestFusionReactorBurnsHydrogen(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := &Planet{Hydrogen: 100}
+	p.SetBuilding(SlotFusionReactor, b.FusionReactor{BaseBuilding: b.BaseBuilding{Level: 2}})
+	p.AccrueProduction(now)
+
+	// 10 plasma/h for 2h, burning 4 hydrogen each.
+	if out, _ := p.AccrueProduction(now.Add(2 * time.Hour)); out.Plasma != 20 || p.Plasma != 20 || p.Hydrogen != 20 {
+		t.Fatalf("after 2h: out %+v, plasma %d, hydrogen %d", out, p.Plasma, p.Hydrogen)
+	}
+	// Only 20 hydrogen left: 5 plasma, then the reactor runs dry.
+	if out, _ := p.AccrueProduction(now.Add(4 * time.Hour)); out.Plasma != 5 || p.Hydrogen != 0 {
+		t.Fatalf("when capped: out %+v, hydrogen %d", out, p.Hydrogen)
+	}
+}
+
+func TestResearchLifecycle(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := &Planet{Metals: 10000, Crystals: 10000, Plasma: 1000}
+	if _, err := p.StartResearch("fighter_afterburners", nil, now); err != ErrNoAccelerator {
+		t.Fatalf("without accelerator: got %v", err)
+	}
+	p.SetBuilding(SlotParticleAccelerator, b.ParticleAccelerator{BaseBuilding: b.BaseBuilding{Level: 1}})
+
+	if _, err := p.StartResearch("carrier_bays", nil, now); !errors.Is(err, ships.ErrResearchLocked) {
+		t.Fatalf("locked project: got %v", err)
+	}
+	job, err := p.StartResearch("fighter_afterburners", nil, now)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	proj := ships.ResearchCatalog["fighter_afterburners"]
+	d := time.Duration(proj.DurationSeconds) * time.Second
+	if p.Plasma != 1000-proj.Plasma || !job.End().Equal(now.Add(d)) {
+		t.Fatalf("plasma %d, job ends %v", p.Plasma, job.End())
+	}
+	if _, err := p.StartResearch("bomber_payload", nil, now); err != ErrResearchBusy {
+		t.Fatalf("second project: got %v", err)
+	}
+
+	// Half done, then a 10 minute brownout pauses it.
+	half := now.Add(d / 2)
+	if _, progress, _ := p.ResearchProgress(half); progress != 0.5 {
+		t.Fatalf("progress %v, want 0.5", progress)
+	}
+	p.Brownout = b.BrownoutResearch
+	if done, _ := p.AdvanceResearch(half, half.Add(10*time.Minute)); done != "" {
+		t.Fatalf("completed while offline: %q", done)
+	}
+	p.Brownout = b.BrownoutNone
+	if done, _ := p.AdvanceResearch(now, now.Add(d)); done != "" {
+		t.Fatalf("completed despite pause: %q", done)
+	}
+	if done, _ := p.AdvanceResearch(now, now.Add(d+10*time.Minute)); done != proj.ID {
+		t.Fatalf("completed %q, want %s", done, proj.ID)
+	}
+	if _, _, running := p.ResearchProgress(now.Add(d + 10*time.Minute)); running {
+		t.Fatal("job still queued after completion")
+	}
+
+	completed := []string{proj.ID}
+	if got := ships.ResearchMods(completed, ships.Fighter); got.SpeedDelta != 1 {
+		t.Fatalf("fighter research mods %+v", got)
+	}
+	if ships.GemTierCap(completed) != ships.BaseGemTierCap {
+		t.Fatalf("gem tier cap %d", ships.GemTierCap(completed))
+	}
+}
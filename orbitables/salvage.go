@@ -0,0 +1,211 @@
+package orbitables
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/ships"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Salvage fields
+//
+// Battles leave wreckage where ships die. A field holds Cargo-like amounts of
+// metal and crystal and decays linearly: by t it can hold at most
+//
+//	Initial * (ExpiresAt - t) / SalvageLifetime
+//
+// so it is gone at ExpiresAt. New wreckage dropped on an existing field
+// tops it up and restarts the clock.
+//
+// A stack in Economic role inside the field starts gathering from it
+// (SalvageTargetType) with StartHarvest; only its anchored ships count, so
+// the game layer calls it once an anchor on the field holds. Harvesters work
+// in whole MiningTicks, each asking SalvageRate * SalvagePower capped by
+// their free cargo; a field that runs short is shared like a mining deposit.
+// A stack that leaves the field or the Economic role stops harvesting.
+
+const (
+	SalvageLifetime = 6 * time.Hour
+	SalvageRadius   = 50.0
+	SalvageRate     = 15 // Units per MiningTick per Drone equivalent
+)
+
+// SalvageTargetType is the GatheringState.TargetType of stacks harvesting a
+// salvage field.
+const SalvageTargetType = "salvage"
+
+var (
+	ErrNotInSalvage = errors.New("stack is not inside the salvage field")
+	ErrNotEconomic  = errors.New("stack is not in economic role")
+)
+
+// SalvageField is wreckage floating in open space.
+type SalvageField struct {
+	ID              bson.ObjectID `bson:"_id,omitempty"`
+	MapID           bson.ObjectID `bson:"mapId,omitempty"`
+	X               float64       `bson:"x"`
+	Y               float64       `bson:"y"`
+	Resources       ships.Cargo   `bson:"resources"`
+	Initial         ships.Cargo   `bson:"initial"`
+	CreatedAt       time.Time     `bson:"createdAt"`
+	ExpiresAt       time.Time     `bson:"expiresAt"`
+	LastHarvested   time.Time     `bson:"lastHarvested"`
+	CollisionRadius float64       `bson:"collisionRadius"`
+}
+
+// NewSalvageField drops wreckage at (x, y).
+func NewSalvageField(id, mapID bson.ObjectID, x, y float64, c ships.Cargo, now time.Time) *SalvageField {
+	return &SalvageField{
+		ID:              id,
+		MapID:           mapID,
+		X:               x,
+		Y:               y,
+		Resources:       c,
+		Initial:         c,
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(SalvageLifetime),
+		LastHarvested:   now,
+		CollisionRadius: SalvageRadius,
+	}
+}
+
+// Contains reports whether (x, y) lies within the field.
+func (f *SalvageField) Contains(x, y float64) bool {
+	return math.Hypot(x-f.X, y-f.Y) <= f.CollisionRadius
+}
+
+// Add tops the field up with fresh wreckage.
+func (f *SalvageField) Add(c ships.Cargo, now time.Time) {
+	f.Decay(now)
+	f.Resources = f.Resources.Plus(c)
+	f.Initial = f.Resources
+	f.CreatedAt = now
+	f.ExpiresAt = now.Add(SalvageLifetime)
+}
+
+// Decay trims the field to what it may still hold at now.
+func (f *SalvageField) Decay(now time.Time) {
+	left := f.ExpiresAt.Sub(now)
+	if left <= 0 {
+		f.Resources = ships.Cargo{}
+		return
+	}
+	frac := float64(left) / float64(SalvageLifetime)
+	capAt := func(held, initial int64) int64 {
+		return min(held, int64(float64(initial)*frac))
+	}
+	f.Resources = ships.Cargo{
+		Metals:   capAt(f.Resources.Metals, f.Initial.Metals),
+		Crystals: capAt(f.Resources.Crystals, f.Initial.Crystals),
+		Hydrogen: capAt(f.Resources.Hydrogen, f.Initial.Hydrogen),
+	}
+}
+
+// Gone reports whether nothing is left to harvest.
+func (f *SalvageField) Gone(now time.Time) bool {
+	return f.Resources.Total() == 0 || !now.Before(f.ExpiresAt)
+}
+
+// StartHarvest sets the stack gathering from the field.
+func (f *SalvageField) StartHarvest(s *ships.ShipStack, now time.Time) error {
+	if len(s.Movement) > 0 || (s.Battle != nil && s.Battle.IsInCombat) {
+		return ships.ErrStackBusy
+	}
+	if !s.InRole(ships.RoleEconomic, now) {
+		return ErrNotEconomic
+	}
+	if !f.Contains(s.PositionAt(now)) {
+		return ErrNotInSalvage
+	}
+	s.Gathering = &ships.GatheringState{
+		IsGathering: true,
+		TargetID:    f.ID,
+		TargetType:  SalvageTargetType,
+		StartTime:   now,
+		ProcessedAt: now,
+	}
+	return nil
+}
+
+// harvesting reports whether s may harvest the field at now.
+func (f *SalvageField) harvesting(s *ships.ShipStack, now time.Time) bool {
+	return s != nil && s.Gathering != nil && s.Gathering.IsGathering &&
+		s.Gathering.TargetType == SalvageTargetType && s.Gathering.TargetID == f.ID &&
+		s.InRole(ships.RoleEconomic, now) && f.Contains(s.PositionAt(now))
+}
+
+// SalvageYield is what one stack recovered from a field.
+type SalvageYield struct {
+	StackID bson.ObjectID
+	Cargo   ships.Cargo
+}
+
+// Harvest decays the field and runs every whole MiningTick since
+// LastHarvested for the stacks gathering from it that are still inside it
+// and in Economic role.
+func (f *SalvageField) Harvest(stacks []*ships.ShipStack, now time.Time) []SalvageYield {
+	ticks := int64(now.Sub(f.LastHarvested) / MiningTick)
+	if ticks <= 0 {
+		return nil
+	}
+	f.LastHarvested = f.LastHarvested.Add(time.Duration(ticks) * MiningTick)
+	f.Decay(now)
+	available := f.Resources.Total()
+	if available == 0 {
+		return nil
+	}
+
+	var harvesters []*ships.ShipStack
+	for _, s := range stacks {
+		if f.harvesting(s, now) {
+			harvesters = append(harvesters, s)
+		}
+	}
+	sort.Slice(harvesters, func(i, j int) bool { return bytes.Compare(harvesters[i].ID[:], harvesters[j].ID[:]) < 0 })
+	demand := make([]int64, len(harvesters))
+	var total int64
+	for i, s := range harvesters {
+		demand[i] = int64(math.Floor(SalvageRate * s.SalvagePower() * float64(ticks)))
+		if free := s.CargoFree(now); demand[i] > free {
+			demand[i] = free
+		}
+		total += demand[i]
+	}
+	if total == 0 {
+		return nil
+	}
+	if total > available {
+		demand = shareDeposit(demand, total, available)
+	}
+
+	var yields []SalvageYield
+	for i, s := range harvesters {
+		if demand[i] == 0 {
+			continue
+		}
+		c := f.take(demand[i])
+		yields = append(yields, SalvageYield{StackID: s.ID, Cargo: c})
+	}
+	return yields
+}
+
+// take removes n units from the field, metal first.
+func (f *SalvageField) take(n int64) ships.Cargo {
+	var c ships.Cargo
+	c.Metals = min(n, f.Resources.Metals)
+	n -= c.Metals
+	c.Crystals = min(n, f.Resources.Crystals)
+	n -= c.Crystals
+	c.Hydrogen = min(n, f.Resources.Hydrogen)
+	f.Resources = ships.Cargo{
+		Metals:   f.Resources.Metals - c.Metals,
+		Crystals: f.Resources.Crystals - c.Crystals,
+		Hydrogen: f.Resources.Hydrogen - c.Hydrogen,
+	}
+	return c
+}
@@ -0,0 +1,93 @@
+package orbitables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nicoberrocal/galaxyCore/ships"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSalvageFieldHarvestAndDecay(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewSalvageField(bson.NewObjectID(), bson.NilObjectID, 0, 0, ships.Cargo{Metals: 1000}, now)
+	harvester := func(t ships.ShipType, count int) *ships.ShipStack {
+		s := &ships.ShipStack{
+			ID:        bson.NewObjectID(),
+			Role:      ships.RoleEconomic,
+			Ships:     map[ships.ShipType][]ships.HPBucket{t: {{HP: 100, Count: count}}},
+			Gathering: &ships.GatheringState{IsGathering: true, TargetID: f.ID, TargetType: SalvageTargetType},
+		}
+		s.SetAnchored(t, true)
+		return s
+	}
+	drones, destroyers := harvester(ships.Drone, 4), harvester(ships.Destroyer, 5) // 4 and 3 Drone equivalents
+
+	yields := f.Harvest([]*ships.ShipStack{drones, destroyers}, now.Add(time.Minute))
+	got := map[bson.ObjectID]int64{}
+	for _, y := range yields {
+		got[y.StackID] = y.Cargo.Metals
+	}
+	if got[drones.ID] != 4*SalvageRate || got[destroyers.ID] != 3*SalvageRate {
+		t.Fatalf("harvested %v, want %d / %d", got, 4*SalvageRate, 3*SalvageRate)
+	}
+	// One minute of decay trims 1000 to 997 before the 105 units are taken.
+	if f.Resources.Metals != 997-7*SalvageRate {
+		t.Fatalf("field holds %d, want %d", f.Resources.Metals, 997-7*SalvageRate)
+	}
+
+	f.Decay(now.Add(SalvageLifetime / 2))
+	if f.Resources.Metals != 500 {
+		t.Fatalf("half-life field holds %d, want 500", f.Resources.Metals)
+	}
+	if f.Gone(now.Add(SalvageLifetime/2)) || !f.Gone(now.Add(SalvageLifetime)) {
+		t.Fatal("field should last exactly SalvageLifetime")
+	}
+}
+
+func TestSalvageStartHarvest(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewSalvageField(bson.NewObjectID(), bson.NilObjectID, 0, 0, ships.Cargo{Metals: 1000}, now)
+	s := &ships.ShipStack{
+		ID:        bson.NewObjectID(),
+		PositionX: 10,
+		Ships:     map[ships.ShipType][]ships.HPBucket{ships.Drone: {{HP: 100, Count: 2}}},
+	}
+	s.SetAnchored(ships.Drone, true)
+
+	if err := f.StartHarvest(s, now); err != ErrNotEconomic {
+		t.Fatalf("tactical stack: got %v, want ErrNotEconomic", err)
+	}
+	s.StartModeSwitch(ships.RoleEconomic, now)
+	if err := f.StartHarvest(s, now); err != ErrNotEconomic {
+		t.Fatalf("mid role switch: got %v, want ErrNotEconomic", err)
+	}
+	ready := s.ReconfigureUntil
+	s.PositionX = f.CollisionRadius + 1
+	if err := f.StartHarvest(s, ready); err != ErrNotInSalvage {
+		t.Fatalf("outside the field: got %v, want ErrNotInSalvage", err)
+	}
+	s.PositionX = 10
+	if err := f.StartHarvest(s, ready); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if g := s.Gathering; g == nil || !g.IsGathering || g.TargetID != f.ID || g.TargetType != SalvageTargetType {
+		t.Fatalf("gathering = %+v", s.Gathering)
+	}
+
+	f.LastHarvested = ready
+	if yields := f.Harvest([]*ships.ShipStack{s}, ready.Add(MiningTick)); len(yields) != 1 {
+		t.Fatalf("yields = %+v, want one", yields)
+	}
+	// Leaving the field or the Economic role stops the harvest.
+	s.PositionX = f.CollisionRadius + 1
+	if yields := f.Harvest([]*ships.ShipStack{s}, ready.Add(2*MiningTick)); len(yields) != 0 {
+		t.Fatalf("harvested from outside the field: %+v", yields)
+	}
+	s.PositionX = 10
+	s.StartModeSwitch(ships.RoleTactical, ready.Add(2*MiningTick))
+	if yields := f.Harvest([]*ships.ShipStack{s}, ready.Add(3*MiningTick)); len(yields) != 0 {
+		t.Fatalf("harvested outside Economic role: %+v", yields)
+	}
+}
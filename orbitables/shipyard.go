@@ -0,0 +1,230 @@
+package orbitables
+
+import (
+	"errors"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/ships"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Shipyard production
+//
+// A colonized system's planet builds ships through its ShipYard. Queueing an
+// order charges the planet's stocks up front (with ConstructionCostPct
+// applied) and schedules it after the last order in the queue. Completed
+// orders are delivered into the DefendingFleet, or as a new ShipStack at the
+// system when requested or when the orderer no longer holds the fleet.
+
+const (
+	DeliverToFleet = "fleet"
+	DeliverToStack = "stack"
+)
+
+var (
+	ErrNoPlanet              = errors.New("system has no planet")
+	ErrNoShipYard            = errors.New("planet has no shipyard")
+	ErrNotSystemOwner        = errors.New("system is not controlled by this player")
+	ErrUnknownShipType       = errors.New("unknown ship type or invalid count")
+	ErrInsufficientResources = errors.New("not enough resources on the planet")
+	ErrOrderNotFound         = errors.New("shipyard order not found")
+	ErrOrderFinished         = errors.New("shipyard order is finished and awaits delivery")
+	ErrShipYardOffline       = errors.New("shipyard is offline due to an energy brownout")
+)
+
+// ControlledBy reports whether playerID currently controls the system.
+func (s *System) ControlledBy(playerID bson.ObjectID) bool {
+	return s.Colonization != nil && s.Colonization.IsColonized &&
+		s.DefendingFleet != nil && s.DefendingFleet.PlayerID == playerID
+}
+
+// shipYard decodes the planet's shipyard; one still under construction
+// (level 0) does not count.
+func (s *System) shipYard() (b.ShipYard, error) {
+	if s.Planet == nil {
+		return b.ShipYard{}, ErrNoPlanet
+	}
+	building, err := s.Planet.GetShipYard()
+	if err != nil {
+		return b.ShipYard{}, err
+	}
+	yard, ok := building.(b.ShipYard)
+	if !ok || yard.Level < 1 {
+		return b.ShipYard{}, ErrNoShipYard
+	}
+	return yard, nil
+}
+
+// CanAfford reports whether the planet's stocks cover cost.
+func (p *Planet) CanAfford(cost ships.BuildCost) bool {
+	return p.Metals >= cost.Metal && p.Crystals >= cost.Crystal && p.Plasma >= cost.Plasma
+}
+
+// QueueShipOrder charges the planet and appends an order to its shipyard.
+// mods carries the player's construction modifiers.
+func (s *System) QueueShipOrder(playerID bson.ObjectID, t ships.ShipType, count int, deliverTo string, mods ships.StatMods, now time.Time) (b.ShipOrder, error) {
+	if !s.ControlledBy(playerID) {
+		return b.ShipOrder{}, ErrNotSystemOwner
+	}
+	yard, err := s.shipYard()
+	if err != nil {
+		return b.ShipOrder{}, err
+	}
+	if b.PowerFactor(yard.GetType(), s.Planet.Brownout) == 0 {
+		return b.ShipOrder{}, ErrShipYardOffline
+	}
+	cost, ok := ships.ShipBuildCost(t, count, mods)
+	if !ok {
+		return b.ShipOrder{}, ErrUnknownShipType
+	}
+	if !s.Planet.CanAfford(cost) {
+		return b.ShipOrder{}, ErrInsufficientResources
+	}
+	if deliverTo == "" {
+		deliverTo = DeliverToFleet
+	}
+
+	start := now
+	if n := len(yard.Orders); n > 0 && yard.Orders[n-1].End.After(start) {
+		start = yard.Orders[n-1].End
+	}
+	order := b.ShipOrder{
+		ID:        bson.NewObjectID(),
+		PlayerID:  playerID,
+		ShipType:  string(t),
+		Count:     count,
+		DeliverTo: deliverTo,
+		Metal:     cost.Metal,
+		Crystal:   cost.Crystal,
+		Plasma:    cost.Plasma,
+		Start:     start,
+		End:       start.Add(cost.Duration),
+	}
+	yard.Orders = append(yard.Orders, order)
+	if err := s.Planet.SetShipYard(yard); err != nil {
+		return b.ShipOrder{}, err
+	}
+	s.Planet.Metals -= cost.Metal
+	s.Planet.Crystals -= cost.Crystal
+	s.Planet.Plasma -= cost.Plasma
+	return order, nil
+}
+
+// CompleteShipOrders delivers every order finished by now, in queue order,
+// and returns them. Ships joining the DefendingFleet are merged in place; any
+// new stacks, each with a fresh ID, are returned for the caller to persist.
+func (s *System) CompleteShipOrders(now time.Time) ([]b.ShipOrder, []*ships.ShipStack, error) {
+	if s.Planet == nil || s.Planet.ShipYard == nil {
+		return nil, nil, nil
+	}
+	yard, err := s.shipYard()
+	if err != nil {
+		return nil, nil, err
+	}
+	if b.PowerFactor(yard.GetType(), s.Planet.Brownout) == 0 {
+		// Finished ships wait in the yard until power returns.
+		return nil, nil, nil
+	}
+	done := 0
+	for done < len(yard.Orders) && !yard.Orders[done].End.After(now) {
+		done++
+	}
+	if done == 0 {
+		return nil, nil, nil
+	}
+
+	var stacks []*ships.ShipStack
+	for _, o := range yard.Orders[:done] {
+		t := ships.ShipType(o.ShipType)
+		hp := ships.ShipBlueprints[t].HP
+		if o.DeliverTo != DeliverToStack && s.ControlledBy(o.PlayerID) {
+			s.DefendingFleet.Ships = addFleetShips(s.DefendingFleet.Ships, t, hp, o.Count)
+			continue
+		}
+		stack := &ships.ShipStack{
+			ID:        bson.NewObjectID(),
+			PlayerID:  o.PlayerID,
+			MapID:     s.MapID,
+			PositionX: s.X,
+			PositionY: s.Y,
+			Ships:     map[ships.ShipType][]ships.HPBucket{t: {{HP: hp, Count: o.Count}}},
+			CreatedAt: o.End,
+		}
+		stack.EnsureFormationInitialized(o.End)
+		stack.UpdateStackAttackRange(o.End)
+		stacks = append(stacks, stack)
+	}
+	delivered := append([]b.ShipOrder(nil), yard.Orders[:done]...)
+	yard.Orders = append([]b.ShipOrder(nil), yard.Orders[done:]...)
+	if err := s.Planet.SetShipYard(yard); err != nil {
+		return nil, nil, err
+	}
+	return delivered, stacks, nil
+}
+
+// CancelShipOrder removes a queued order, refunds what was paid for the
+// ships not yet built and pulls later orders forward. Orders finished by now
+// cannot be cancelled; the next CompleteShipOrders delivers them.
+func (s *System) CancelShipOrder(playerID, orderID bson.ObjectID, now time.Time) error {
+	yard, err := s.shipYard()
+	if err != nil {
+		return err
+	}
+	idx := -1
+	for i, o := range yard.Orders {
+		if o.ID == orderID && o.PlayerID == playerID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return ErrOrderNotFound
+	}
+	o := yard.Orders[idx]
+	if !o.End.After(now) {
+		return ErrOrderFinished
+	}
+	// Refund in proportion to the time left; a started order forfeits its progress.
+	frac := 1.0
+	if total := o.End.Sub(o.Start); total > 0 && now.After(o.Start) {
+		frac = float64(o.End.Sub(now)) / float64(total)
+	}
+	freed := o.End.Sub(maxTime(o.Start, now))
+	rest := append([]b.ShipOrder(nil), yard.Orders[:idx]...)
+	for _, later := range yard.Orders[idx+1:] {
+		later.Start = later.Start.Add(-freed)
+		later.End = later.End.Add(-freed)
+		rest = append(rest, later)
+	}
+	yard.Orders = rest
+	if err := s.Planet.SetShipYard(yard); err != nil {
+		return err
+	}
+	s.Planet.Metals += int64(float64(o.Metal) * frac)
+	s.Planet.Crystals += int64(float64(o.Crystal) * frac)
+	s.Planet.Plasma += int64(float64(o.Plasma) * frac)
+	return nil
+}
+
+func addFleetShips(fleet map[ships.ShipType][]ships.HPBucket, t ships.ShipType, hp, count int) map[ships.ShipType][]ships.HPBucket {
+	if fleet == nil {
+		fleet = make(map[ships.ShipType][]ships.HPBucket)
+	}
+	for i := range fleet[t] {
+		if fleet[t][i].HP == hp {
+			fleet[t][i].Count += count
+			return fleet
+		}
+	}
+	fleet[t] = append(fleet[t], ships.HPBucket{HP: hp, Count: count})
+	return fleet
+}
+
+func maxTime(a, c time.Time) time.Time {
+	if a.After(c) {
+		return a
+	}
+	return c
+}
@@ -0,0 +1,111 @@
+package orbitables
+
+import (
+	"testing"
+	"time"
+
+	b "github.com/nicoberrocal/galaxyCore/buildings"
+	"github.com/nicoberrocal/galaxyCore/ships"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestShipYardQueueAndDeliver(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := bson.NewObjectID()
+	sys := &System{
+		ID:             bson.NewObjectID(),
+		Colonization:   &Colonization{IsColonized: true, ColonizedBy: owner},
+		DefendingFleet: &DefendingFleet{PlayerID: owner},
+		Planet:         &Planet{Metals: 1000, Crystals: 1000, Plasma: 1000},
+	}
+	sys.Planet.SetShipYard(b.ShipYard{BaseBuilding: b.BaseBuilding{Name: "Yard", Level: 1}})
+
+	mods := ships.StatMods{ConstructionCostPct: -0.10}
+	first, err := sys.QueueShipOrder(owner, ships.Fighter, 10, "", mods, now)
+	if err != nil {
+		t.Fatalf("queue fighters: %v", err)
+	}
+	bp := ships.ShipBlueprints[ships.Fighter]
+	if want := int64(float64(bp.MetalCost*10)*0.9 + 0.5); first.Metal != want || sys.Planet.Metals != 1000-want {
+		t.Fatalf("metal paid %d (planet %d), want %d", first.Metal, sys.Planet.Metals, want)
+	}
+	second, err := sys.QueueShipOrder(owner, ships.Scout, 2, DeliverToStack, ships.StatMods{}, now)
+	if err != nil {
+		t.Fatalf("queue scouts: %v", err)
+	}
+	if !second.Start.Equal(first.End) {
+		t.Fatalf("second order starts %v, want after first at %v", second.Start, first.End)
+	}
+	if _, err := sys.QueueShipOrder(bson.NewObjectID(), ships.Fighter, 1, "", mods, now); err != ErrNotSystemOwner {
+		t.Fatalf("foreign order: got %v", err)
+	}
+
+	delivered, stacks, err := sys.CompleteShipOrders(first.End)
+	if err != nil || len(delivered) != 1 || len(stacks) != 0 {
+		t.Fatalf("first completion: %v delivered, %v stacks, err %v", len(delivered), len(stacks), err)
+	}
+	if got := sys.DefendingFleet.Ships[ships.Fighter]; len(got) != 1 || got[0].Count != 10 || got[0].HP != bp.HP {
+		t.Fatalf("fleet fighters = %v", got)
+	}
+
+	delivered, stacks, err = sys.CompleteShipOrders(second.End)
+	if err != nil || len(delivered) != 1 || len(stacks) != 1 {
+		t.Fatalf("second completion: %v delivered, %v stacks, err %v", len(delivered), len(stacks), err)
+	}
+	if stacks[0].ID.IsZero() || stacks[0].ID == second.ID || stacks[0].PlayerID != owner {
+		t.Fatalf("delivered stack %v owned by %v", stacks[0].ID, stacks[0].PlayerID)
+	}
+	yard, _ := sys.Planet.GetShipYard()
+	if n := len(yard.(b.ShipYard).Orders); n != 0 {
+		t.Fatalf("%d orders left in the queue", n)
+	}
+}
+
+func TestCancelShipOrder(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner := bson.NewObjectID()
+	sys := &System{
+		ID:             bson.NewObjectID(),
+		Colonization:   &Colonization{IsColonized: true, ColonizedBy: owner},
+		DefendingFleet: &DefendingFleet{PlayerID: owner},
+		Planet:         &Planet{Metals: 100000, Crystals: 100000, Plasma: 100000},
+	}
+	sys.Planet.SetShipYard(b.ShipYard{BaseBuilding: b.BaseBuilding{Name: "Yard", Level: 1}})
+
+	first, _ := sys.QueueShipOrder(owner, ships.Fighter, 4, "", ships.StatMods{}, now)
+	second, _ := sys.QueueShipOrder(owner, ships.Scout, 2, "", ships.StatMods{}, now)
+	third, err := sys.QueueShipOrder(owner, ships.Scout, 1, "", ships.StatMods{}, now)
+	if err != nil {
+		t.Fatalf("queue: %v", err)
+	}
+	metals := sys.Planet.Metals
+
+	if err := sys.CancelShipOrder(bson.NewObjectID(), second.ID, now); err != ErrOrderNotFound {
+		t.Fatalf("foreign cancel: got %v", err)
+	}
+	// A queued order is refunded in full and the orders behind it move up.
+	if err := sys.CancelShipOrder(owner, second.ID, now); err != nil {
+		t.Fatalf("cancel queued: %v", err)
+	}
+	if got := sys.Planet.Metals; got != metals+second.Metal {
+		t.Fatalf("metals after cancel = %d, want %d", got, metals+second.Metal)
+	}
+	yard, _ := sys.Planet.GetShipYard()
+	orders := yard.(b.ShipYard).Orders
+	if len(orders) != 2 || orders[1].ID != third.ID || !orders[1].Start.Equal(first.End) {
+		t.Fatalf("orders after cancel = %+v", orders)
+	}
+
+	// A finished order is left for delivery: no refund, no reshuffle.
+	metals = sys.Planet.Metals
+	late := first.End.Add(time.Minute)
+	if err := sys.CancelShipOrder(owner, first.ID, late); err != ErrOrderFinished {
+		t.Fatalf("cancel finished: got %v", err)
+	}
+	yard, _ = sys.Planet.GetShipYard()
+	orders = yard.(b.ShipYard).Orders
+	if sys.Planet.Metals != metals || len(orders) != 2 || !orders[1].Start.Equal(first.End) {
+		t.Fatalf("finished cancel changed state: metals %d, orders %+v", sys.Planet.Metals, orders)
+	}
+}
@@ -56,6 +56,7 @@ type System struct {
 	// System control and defense - uses hybrid approach
 	Colonization   *Colonization   `bson:"colonization,omitempty" json:"colonization,omitempty"`
 	DefendingFleet *DefendingFleet `bson:"defendingFleet,omitempty" json:"defendingFleet,omitempty"` // Embedded fleet when colonized
+	Capture        *CaptureState   `bson:"capture,omitempty" json:"capture,omitempty"`               // Enemy hold after the garrison fell (see capture.go)
 
 	// Collision detection for system entry
 	CollisionRadius float64 `bson:"collisionRadius" json:"collisionRadius"` // Radius for determining system entry
@@ -74,6 +75,7 @@ type Planet struct {
 	SystemID            bson.ObjectID `bson:"systemId,omitempty" json:"systemId,omitempty"`
 	MapID               bson.ObjectID `bson:"mapId,omitempty" json:"mapId,omitempty"`
 	Name                string        `bson:"name" json:"name"`
+	Type                string        `bson:"type,omitempty" json:"type,omitempty"` // Mercury/Venus/Earth/Mars (see buildings/production.go)
 	NorthPole           *bson.M       `bson:"northPole,omitempty" json:"northPole,omitempty"`
 	Left                *bson.M       `bson:"left,omitempty" json:"left,omitempty"`
 	Right               *bson.M       `bson:"right,omitempty" json:"right,omitempty"`
@@ -86,6 +88,9 @@ type Planet struct {
 	Crystals            int64         `bson:"crystals" json:"crystals"`
 	Hydrogen            int64         `bson:"hydrogen" json:"hydrogen"`
 	Plasma              int64         `bson:"plasma" json:"plasma"`
+	LastProduced        time.Time     `bson:"lastProduced,omitempty" json:"lastProduced,omitempty"`
+	ProductionCarry     b.Output      `bson:"productionCarry,omitempty" json:"productionCarry,omitempty"`
+	Brownout            int           `bson:"brownout,omitempty" json:"brownout,omitempty"`
 	Version             int64         `bson:"version" json:"version"` // For optimistic locking
 }
 
@@ -97,9 +102,8 @@ func (p *Planet) GetNorthPole() (b.Building, error) {
 	return b.CreateBuildingFromMongoDB(*p.NorthPole)
 }
 
-func (p *Planet) SetNorthPole(building b.Building) {
-	result := b.BuildingToBSON(building)
-	p.NorthPole = &result
+func (p *Planet) SetNorthPole(building b.Building) error {
+	return p.SetBuilding(SlotNorthPole, building)
 }
 
 func (p *Planet) GetLeft() (b.Building, error) {
@@ -109,9 +113,8 @@ func (p *Planet) GetLeft() (b.Building, error) {
 	return b.CreateBuildingFromMongoDB(*p.Left)
 }
 
-func (p *Planet) SetLeft(building b.Building) {
-	result := b.BuildingToBSON(building)
-	p.Left = &result
+func (p *Planet) SetLeft(building b.Building) error {
+	return p.SetBuilding(SlotLeft, building)
 }
 
 func (p *Planet) GetRight() (b.Building, error) {
@@ -121,9 +124,8 @@ func (p *Planet) GetRight() (b.Building, error) {
 	return b.CreateBuildingFromMongoDB(*p.Right)
 }
 
-func (p *Planet) SetRight(building b.Building) {
-	result := b.BuildingToBSON(building)
-	p.Right = &result
+func (p *Planet) SetRight(building b.Building) error {
+	return p.SetBuilding(SlotRight, building)
 }
 
 func (p *Planet) GetBack() (b.Building, error) {
@@ -133,9 +135,8 @@ func (p *Planet) GetBack() (b.Building, error) {
 	return b.CreateBuildingFromMongoDB(*p.Back)
 }
 
-func (p *Planet) SetBack(building b.Building) {
-	result := b.BuildingToBSON(building)
-	p.Back = &result
+func (p *Planet) SetBack(building b.Building) error {
+	return p.SetBuilding(SlotBack, building)
 }
 
 func (p *Planet) GetFront() (b.Building, error) {
@@ -145,9 +146,8 @@ func (p *Planet) GetFront() (b.Building, error) {
 	return b.CreateBuildingFromMongoDB(*p.Front)
 }
 
-func (p *Planet) SetFront(building b.Building) {
-	result := b.BuildingToBSON(building)
-	p.Front = &result
+func (p *Planet) SetFront(building b.Building) error {
+	return p.SetBuilding(SlotFront, building)
 }
 
 func (p *Planet) GetShipYard() (b.Building, error) {
@@ -157,9 +157,8 @@ func (p *Planet) GetShipYard() (b.Building, error) {
 	return b.CreateBuildingFromMongoDB(*p.ShipYard)
 }
 
-func (p *Planet) SetShipYard(building b.Building) {
-	result := b.BuildingToBSON(building)
-	p.ShipYard = &result
+func (p *Planet) SetShipYard(building b.Building) error {
+	return p.SetBuilding(SlotShipYard, building)
 }
 
 func (p *Planet) GetParticleAccelerator() (b.Building, error) {
@@ -169,9 +168,8 @@ func (p *Planet) GetParticleAccelerator() (b.Building, error) {
 	return b.CreateBuildingFromMongoDB(*p.ParticleAccelerator)
 }
 
-func (p *Planet) SetParticleAccelerator(building b.Building) {
-	result := b.BuildingToBSON(building)
-	p.ParticleAccelerator = &result
+func (p *Planet) SetParticleAccelerator(building b.Building) error {
+	return p.SetBuilding(SlotParticleAccelerator, building)
 }
 
 func (p *Planet) GetFusionReactor() (b.Building, error) {
@@ -181,7 +179,6 @@ func (p *Planet) GetFusionReactor() (b.Building, error) {
 	return b.CreateBuildingFromMongoDB(*p.FusionReactor)
 }
 
-func (p *Planet) SetFusionReactor(building b.Building) {
-	result := b.BuildingToBSON(building)
-	p.FusionReactor = &result
+func (p *Planet) SetFusionReactor(building b.Building) error {
+	return p.SetBuilding(SlotFusionReactor, building)
 }
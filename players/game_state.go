@@ -16,6 +16,11 @@ type PlayerGameState struct {
 	// Resources
 	Energy           int64 `bson:"energy"`
 	EnergyProduction int64 `bson:"energyProduction"`
+	EnergyUpkeep     int64 `bson:"energyUpkeep"`
+	Brownout         int   `bson:"brownout,omitempty"` // Brownout level applied to the player's planets
+
+	// Completed particle accelerator research project IDs
+	Research []string `bson:"research,omitempty"`
 
 	// Territory and assets - denormalized for performance
 	// IMPORTANT: These arrays should be mutually exclusive:
@@ -0,0 +1,56 @@
+package players
+
+import "go.mongodb.org/mongo-driver/v2/bson"
+
+// Territory bookkeeping for the denormalized ID arrays on PlayerGameState.
+// Adds are idempotent and removes tolerate missing IDs, so callers can apply
+// them after every colonize, reinforce or depart without checking first.
+
+// AddColonizedSystem records a system the player now holds.
+func (g *PlayerGameState) AddColonizedSystem(id bson.ObjectID) {
+	g.ColonizedSystems = addID(g.ColonizedSystems, id)
+}
+
+// RemoveColonizedSystem forgets a system the player no longer holds.
+func (g *PlayerGameState) RemoveColonizedSystem(id bson.ObjectID) {
+	g.ColonizedSystems = removeID(g.ColonizedSystems, id)
+}
+
+// AddActiveStack records a stack in free space.
+func (g *PlayerGameState) AddActiveStack(id bson.ObjectID) {
+	g.ActiveStacks = addID(g.ActiveStacks, id)
+}
+
+// RemoveActiveStack forgets a stack that was embedded or destroyed.
+func (g *PlayerGameState) RemoveActiveStack(id bson.ObjectID) {
+	g.ActiveStacks = removeID(g.ActiveStacks, id)
+}
+
+// AddMiningOperation records an asteroid or nebula the player mines.
+func (g *PlayerGameState) AddMiningOperation(id bson.ObjectID) {
+	g.MiningOperations = addID(g.MiningOperations, id)
+}
+
+// RemoveMiningOperation forgets an asteroid or nebula the player stopped mining.
+func (g *PlayerGameState) RemoveMiningOperation(id bson.ObjectID) {
+	g.MiningOperations = removeID(g.MiningOperations, id)
+}
+
+func addID(ids []bson.ObjectID, id bson.ObjectID) []bson.ObjectID {
+	for _, x := range ids {
+		if x == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func removeID(ids []bson.ObjectID, id bson.ObjectID) []bson.ObjectID {
+	out := ids[:0]
+	for _, x := range ids {
+		if x != id {
+			out = append(out, x)
+		}
+	}
+	return out
+}
@@ -16,6 +16,11 @@ var AbilityEffectsCatalog = map[AbilityID]StatMods{
 	AbilityPointDefenseScreen: {
 		LaserShieldDelta: 2,
 	},
+	AbilityHangarLaunch: {
+		AttackIntervalPct: -0.15, // Launch surge, applied to the launched escorts
+		EvasionPct:        0.10,
+		SpeedDelta:        1,
+	},
 	
 	// Siege/Fire Support
 	AbilitySiegePayload: {
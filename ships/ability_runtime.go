@@ -0,0 +1,139 @@
+package ships
+
+import (
+	"errors"
+	"time"
+)
+
+// Runtime helpers for AbilityState. Static definitions live in abilities.go;
+// these functions manage activation windows and cooldowns on a concrete stack.
+
+var (
+	ErrShipTypeNotInStack = errors.New("ship type not present in stack")
+	ErrAbilityUnavailable = errors.New("ability not available for this ship type")
+	ErrAbilityOnCooldown  = errors.New("ability is on cooldown")
+)
+
+// HasAbility reports whether ship type t in this stack can use the ability,
+// either from its blueprint or granted by socketed gems and GemWords.
+func (s *ShipStack) HasAbility(t ShipType, id AbilityID) bool {
+	if bp, ok := ShipBlueprints[t]; ok {
+		for _, a := range bp.Abilities {
+			if a.ID == id {
+				return true
+			}
+		}
+	}
+	_, grants, _ := EvaluateGemSockets(s.GetOrInitLoadout(t).Sockets)
+	for _, g := range grants {
+		if g == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveAbility returns the running state of an ability on any ship type in the
+// stack, or nil. Toggles (zero EndTime) stay active until deactivated.
+func (s *ShipStack) ActiveAbility(id AbilityID, now time.Time) *AbilityState {
+	if s.Ability == nil {
+		return nil
+	}
+	states := *s.Ability
+	for i := range states {
+		st := &states[i]
+		if st.Ability != string(id) || !st.IsActive {
+			continue
+		}
+		if st.EndTime.IsZero() || now.Before(st.EndTime) {
+			return st
+		}
+	}
+	return nil
+}
+
+// ActivateAbility starts an ability for ship type t. Timed abilities get an
+// EndTime from AbilitiesCatalog; toggles stay on until DeactivateAbility.
+// A ship type keeps a single state entry per ability so cooldowns survive
+// deactivation.
+func (s *ShipStack) ActivateAbility(t ShipType, id AbilityID, now time.Time) (*AbilityState, error) {
+	if countShips(s.Ships)[t] == 0 {
+		return nil, ErrShipTypeNotInStack
+	}
+	if !s.HasAbility(t, id) {
+		return nil, ErrAbilityUnavailable
+	}
+	spec := AbilitiesCatalog[id]
+
+	if s.Ability == nil {
+		s.Ability = &[]AbilityState{}
+	}
+	states := *s.Ability
+	idx := -1
+	for i := range states {
+		if states[i].Ability == string(id) && states[i].ShipType == t {
+			idx = i
+			break
+		}
+	}
+	if idx >= 0 && spec.CooldownSeconds > 0 {
+		ready := states[idx].StartTime.Add(time.Duration(spec.CooldownSeconds) * time.Second)
+		if now.Before(ready) {
+			return nil, ErrAbilityOnCooldown
+		}
+	}
+
+	st := AbilityState{
+		IsActive:    true,
+		Description: spec.Description,
+		ShipType:    t,
+		Ability:     string(id),
+		StartTime:   now,
+		Duration:    int64(spec.DurationSeconds),
+		LastUpdated: now,
+	}
+	if spec.DurationSeconds > 0 {
+		st.EndTime = now.Add(time.Duration(spec.DurationSeconds) * time.Second)
+	}
+	if idx >= 0 {
+		states[idx] = st
+	} else {
+		states = append(states, st)
+		idx = len(states) - 1
+	}
+	*s.Ability = states
+
+	s.BioOnAbilityCast(id, t, now)
+	return &states[idx], nil
+}
+
+// DeactivateAbility switches an ability off for ship type t. StartTime is kept
+// so the cooldown still applies.
+func (s *ShipStack) DeactivateAbility(t ShipType, id AbilityID, now time.Time) {
+	if s.Ability == nil {
+		return
+	}
+	states := *s.Ability
+	for i := range states {
+		if states[i].Ability == string(id) && states[i].ShipType == t && states[i].IsActive {
+			states[i].IsActive = false
+			states[i].LastUpdated = now
+		}
+	}
+}
+
+// ExpireAbilities deactivates timed abilities whose window has elapsed.
+func (s *ShipStack) ExpireAbilities(now time.Time) {
+	if s.Ability == nil {
+		return
+	}
+	states := *s.Ability
+	for i := range states {
+		st := &states[i]
+		if st.IsActive && !st.EndTime.IsZero() && !now.Before(st.EndTime) {
+			st.IsActive = false
+			st.LastUpdated = now
+			st.ProcessedAt = now
+		}
+	}
+}
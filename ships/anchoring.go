@@ -0,0 +1,139 @@
+package ships
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Anchoring
+//
+// A stack anchors onto an orbitable (asteroid, nebula or system) to gather
+// from it. It must be inside the orbitable's CollisionRadius and have no
+// travel queued. Setting the anchor takes AnchorSetDuration; only when it
+// holds are the stack's ship types marked Anchored, which is what mining and
+// salvage throughput, the anchored combat penalty (AddAnchoredPenalty, -1 to
+// every shield) and CloakWhileAnchored key off. Releasing takes
+// AnchorReleaseDuration and keeps the ships Anchored until it completes.
+//
+// From the moment anchoring starts until the release completes the stack
+// cannot move, warp, split or merge.
+
+const (
+	AnchorSetDuration     = 90 * time.Second
+	AnchorReleaseDuration = 60 * time.Second
+)
+
+type AnchorStage string
+
+const (
+	AnchorStageSetting   AnchorStage = "setting"
+	AnchorStageHeld      AnchorStage = "held"
+	AnchorStageReleasing AnchorStage = "releasing"
+)
+
+var (
+	ErrStackAnchored     = errors.New("stack is anchored")
+	ErrNotAnchored       = errors.New("stack is not anchored")
+	ErrAnchorOutOfRange  = errors.New("stack is outside the orbitable's collision radius")
+	ErrAnchorWhileMoving = errors.New("cannot anchor while moving or in combat")
+)
+
+// AnchorState is the stack's anchoring runtime. The stack is immobile while
+// it is set.
+type AnchorState struct {
+	Stage      AnchorStage   `bson:"stage" json:"stage"`
+	TargetID   bson.ObjectID `bson:"targetId" json:"targetId"`
+	TargetType string        `bson:"targetType" json:"targetType"` // "asteroid", "nebula", "system"
+	StartedAt  time.Time     `bson:"startedAt" json:"startedAt"`
+	EndsAt     time.Time     `bson:"endsAt" json:"endsAt"` // When the current stage completes (zero while held)
+}
+
+// IsAnchored reports whether the stack is anchoring, anchored or releasing,
+// or has a ship type marked Anchored.
+func (s *ShipStack) IsAnchored() bool {
+	if s.Anchor != nil {
+		return true
+	}
+	for t, n := range countShips(s.Ships) {
+		if n > 0 && s.Loadouts[t].Anchored {
+			return true
+		}
+	}
+	return false
+}
+
+// StartAnchoring begins anchoring onto the orbitable centred at (x, y) with
+// the given collision radius and returns when the anchor will hold.
+func (s *ShipStack) StartAnchoring(targetID bson.ObjectID, targetType string, x, y, radius float64, now time.Time) (time.Time, error) {
+	if s.Anchor != nil {
+		return time.Time{}, ErrStackAnchored
+	}
+	if len(s.Movement) > 0 || (s.Battle != nil && s.Battle.IsInCombat) {
+		return time.Time{}, ErrAnchorWhileMoving
+	}
+	if math.Hypot(s.PositionX-x, s.PositionY-y) > radius {
+		return time.Time{}, ErrAnchorOutOfRange
+	}
+	s.Anchor = &AnchorState{
+		Stage:      AnchorStageSetting,
+		TargetID:   targetID,
+		TargetType: targetType,
+		StartedAt:  now,
+		EndsAt:     now.Add(AnchorSetDuration),
+	}
+	return s.Anchor.EndsAt, nil
+}
+
+// StartRelease begins weighing anchor and returns when the stack is free to
+// move. Releasing an anchor that is still being set cancels it at once.
+func (s *ShipStack) StartRelease(now time.Time) (time.Time, error) {
+	switch {
+	case s.Anchor == nil:
+		return time.Time{}, ErrNotAnchored
+	case s.Anchor.Stage == AnchorStageReleasing:
+		return s.Anchor.EndsAt, nil
+	case s.Anchor.Stage == AnchorStageSetting:
+		s.Anchor = nil
+		return now, nil
+	}
+	s.Anchor.Stage = AnchorStageReleasing
+	s.Anchor.StartedAt = now
+	s.Anchor.EndsAt = now.Add(AnchorReleaseDuration)
+	return s.Anchor.EndsAt, nil
+}
+
+// AdvanceAnchor completes a setting or releasing anchor whose timer has run
+// out by now and returns the stage it reached ("" when nothing changed).
+// Reaching AnchorStageHeld marks every ship type Anchored, after which the
+// caller starts the stack gathering from the target; a completed release
+// clears the marks, the anchor and the gathering flag.
+func (s *ShipStack) AdvanceAnchor(now time.Time) AnchorStage {
+	if s.Anchor == nil || s.Anchor.EndsAt.IsZero() || now.Before(s.Anchor.EndsAt) {
+		return ""
+	}
+	switch s.Anchor.Stage {
+	case AnchorStageSetting:
+		s.setAnchored(true)
+		s.Anchor.Stage = AnchorStageHeld
+		s.Anchor.StartedAt = s.Anchor.EndsAt
+		s.Anchor.EndsAt = time.Time{}
+		return AnchorStageHeld
+	case AnchorStageReleasing:
+		s.setAnchored(false)
+		s.Anchor = nil
+		if s.Gathering != nil {
+			s.Gathering.IsGathering = false
+		}
+		return AnchorStageReleasing
+	}
+	return ""
+}
+
+func (s *ShipStack) setAnchored(anchored bool) {
+	for t := range s.Ships {
+		s.SetAnchored(t, anchored)
+	}
+}
@@ -0,0 +1,58 @@
+package ships
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestAnchoringLifecycle(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rock := bson.NewObjectID()
+	s := &ShipStack{
+		ID:    bson.NewObjectID(),
+		Ships: map[ShipType][]HPBucket{Drone: {{HP: ShipBlueprints[Drone].HP, Count: 3}}},
+	}
+	free, _, _ := s.EffectiveShipV2(Drone, 0, now)
+
+	if _, err := s.StartAnchoring(rock, "asteroid", 500, 0, 100, now); !errors.Is(err, ErrAnchorOutOfRange) {
+		t.Fatalf("anchoring out of range: err = %v", err)
+	}
+	held, err := s.StartAnchoring(rock, "asteroid", 50, 0, 100, now)
+	if err != nil || !held.Equal(now.Add(AnchorSetDuration)) {
+		t.Fatalf("StartAnchoring = %v, %v", held, err)
+	}
+	if _, err := s.QueueMove(1000, 0, bson.NilObjectID, "", now); !errors.Is(err, ErrStackAnchored) {
+		t.Fatalf("moving while anchoring: err = %v", err)
+	}
+	if s.MiningPower() != 0 {
+		t.Fatal("ships count as anchored before the anchor holds")
+	}
+
+	if stage := s.AdvanceAnchor(held); stage != AnchorStageHeld {
+		t.Fatalf("stage = %q, want held", stage)
+	}
+	anchored, _, _ := s.EffectiveShipV2(Drone, 0, held)
+	if anchored.LaserShield != free.LaserShield-1 {
+		t.Fatalf("anchored laser shield %d, want %d", anchored.LaserShield, free.LaserShield-1)
+	}
+	if !s.IsCloaked(nil, held) {
+		t.Fatal("drones should cloak while anchored")
+	}
+	if err := CanWarp(s); !errors.Is(err, ErrWarpAnchored) {
+		t.Fatalf("warping while anchored: err = %v", err)
+	}
+
+	released, _ := s.StartRelease(held)
+	if s.AdvanceAnchor(released.Add(-time.Second)) != "" || !s.IsAnchored() {
+		t.Fatal("released before AnchorReleaseDuration")
+	}
+	if s.AdvanceAnchor(released) != AnchorStageReleasing || s.IsAnchored() {
+		t.Fatal("release did not free the stack")
+	}
+	if _, err := s.QueueMove(1000, 0, bson.NilObjectID, "", released); err != nil {
+		t.Fatalf("moving after release: %v", err)
+	}
+}
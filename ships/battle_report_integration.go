@@ -243,14 +243,14 @@ func CreateBattleReportSummary(report *BattleReport) string {
 	summary += "\n"
 	
 	summary += "Initial Forces:\n"
-	summary += "  Attacker: " + string(report.AttackerInitial.TotalShips) + " ships\n"
-	summary += "  Defender: " + string(report.DefenderInitial.TotalShips) + " ships\n"
+	summary += "  Attacker: " + itoa(report.AttackerInitial.TotalShips) + " ships\n"
+	summary += "  Defender: " + itoa(report.DefenderInitial.TotalShips) + " ships\n"
 	summary += "\n"
 	
-	summary += "Total Rounds: " + string(report.TotalRounds) + "\n"
+	summary += "Total Rounds: " + itoa(report.TotalRounds) + "\n"
 	summary += "Total Damage Dealt:\n"
-	summary += "  Attacker: " + string(report.AttackerTotalDamage) + "\n"
-	summary += "  Defender: " + string(report.DefenderTotalDamage) + "\n"
+	summary += "  Attacker: " + itoa(report.AttackerTotalDamage) + "\n"
+	summary += "  Defender: " + itoa(report.DefenderTotalDamage) + "\n"
 	summary += "\n"
 	
 	if report.Outcome != nil {
@@ -107,12 +107,18 @@ type BioNodeRuntimeState struct {
 	// Trigger provenance (e.g. ability cast that triggered this node)
 	TriggeredBy *AbilityCastRef `bson:"triggeredBy,omitempty" json:"triggeredBy,omitempty"`
 
+	// Game events (essences.Trigger values) that start the triggered stage via OnTrigger
+	Triggers []string `bson:"triggers,omitempty" json:"triggers,omitempty"`
+
 	// Stage-based StatMods (kept small and cache-friendly)
 	ModsPassive     StatMods `bson:"modsPassive,omitempty" json:"modsPassive,omitempty"`
 	ModsTriggered   StatMods `bson:"modsTriggered,omitempty" json:"modsTriggered,omitempty"`
 	ModsTick        StatMods `bson:"modsTick,omitempty" json:"modsTick,omitempty"`
 	ModsAccumulated StatMods `bson:"modsAccumulated,omitempty" json:"modsAccumulated,omitempty"`
 
+	// Invisible cloaks the whole stack while the triggered stage runs (StatusInvisible).
+	Invisible bool `bson:"invisible,omitempty" json:"invisible,omitempty"`
+
 	// Outgoing debuff prototype (applied to enemies when node logic triggers).
 	OutgoingDebuffID        string        `bson:"outgoingDebuffId,omitempty" json:"outgoingDebuffId,omitempty"`
 	OutgoingDebuffMods      StatMods      `bson:"outgoingDebuffMods,omitempty" json:"outgoingDebuffMods,omitempty"`
@@ -148,6 +154,26 @@ func (n *BioNodeRuntimeState) WithTriggered(mods StatMods, dur time.Duration, cd
 	n.Cooldown = cd
 	return n
 }
+func (n *BioNodeRuntimeState) WithTrigger(trigger string) *BioNodeRuntimeState {
+	if !n.listensFor(trigger) {
+		n.Triggers = append(n.Triggers, trigger)
+	}
+	return n
+}
+func (n *BioNodeRuntimeState) listensFor(trigger string) bool {
+	for _, t := range n.Triggers {
+		if t == trigger {
+			return true
+		}
+	}
+	return false
+}
+
+// WithInvisibility makes the triggered stage cloak the whole stack.
+func (n *BioNodeRuntimeState) WithInvisibility() *BioNodeRuntimeState {
+	n.Invisible = true
+	return n
+}
 func (n *BioNodeRuntimeState) WithTick(mods StatMods, period time.Duration) *BioNodeRuntimeState {
 	n.ModsTick = mods
 	n.TickPeriod = period
@@ -176,6 +202,11 @@ func (n *BioNodeRuntimeState) WithOutgoingDebuff(id string, mods StatMods, dur t
 }
 func (n *BioNodeRuntimeState) Done() *BioMachine { return n.parent }
 
+// hasTriggeredStage reports whether the node has anything to do when triggered.
+func (n *BioNodeRuntimeState) hasTriggeredStage() bool {
+	return n.Duration > 0 && (n.Invisible || !isZeroMods(n.ModsTriggered))
+}
+
 // CurrentLayers returns the set of active layers (if any) produced by this node for the given shipType.
 // It infers the correct source and lifetime based on node stage and timers.
 func (n *BioNodeRuntimeState) CurrentLayers(shipType ShipType, now time.Time) []BioActiveLayer {
@@ -244,6 +275,10 @@ func (n *BioNodeRuntimeState) CurrentLayers(shipType ShipType, now time.Time) []
 	return layers
 }
 
+// bioTriggerAbilityCast mirrors essences.TriggerOnAbilityCast, which
+// OnAbilityCast serves.
+const bioTriggerAbilityCast = "after_ability_cast"
+
 type BioMachine struct {
 	Nodes          map[string]*BioNodeRuntimeState              `bson:"nodes,omitempty" json:"nodes,omitempty"`
 	InboundDebuffs map[string]*BioDebuffState                   `bson:"inboundDebuffs,omitempty" json:"inboundDebuffs,omitempty"`
@@ -363,7 +398,11 @@ func (bm *BioMachine) OnAbilityCast(ability AbilityID, shipType ShipType, start
 		if !(n.AllShips || (n.ShipTypes != nil && n.ShipTypes[shipType])) {
 			continue
 		}
-		if isZeroMods(n.ModsTriggered) || n.Duration <= 0 {
+		if !n.hasTriggeredStage() {
+			continue
+		}
+		// nodes bound to other game events wait for OnTrigger
+		if len(n.Triggers) > 0 && !n.listensFor(bioTriggerAbilityCast) {
 			continue
 		}
 		// only if not in cooldown
@@ -381,6 +420,31 @@ func (bm *BioMachine) OnAbilityCast(ability AbilityID, shipType ShipType, start
 	}
 }
 
+// OnTrigger starts the triggered stage of every node listening for the game
+// event trigger (see WithTrigger). Nodes in cooldown are skipped. Returns how
+// many nodes activated.
+func (bm *BioMachine) OnTrigger(trigger string, start time.Time) int {
+	activated := 0
+	for _, n := range bm.Nodes {
+		if !n.hasTriggeredStage() {
+			continue
+		}
+		if n.Stage == BioStageCooldown || n.Stage == BioStageCompositeCooloff {
+			continue
+		}
+		if !n.listensFor(trigger) {
+			continue
+		}
+		n.Stage = BioStageTriggered
+		n.TriggeredBy = nil
+		n.StartTime = start
+		n.EndTime = start.Add(n.Duration)
+		n.ActivationCount++
+		activated++
+	}
+	return activated
+}
+
 // ApplyInboundDebuff upserts a debuff applied by an enemy trait/node to this stack.
 func (bm *BioMachine) ApplyInboundDebuff(id string, mods StatMods, duration time.Duration, stacks int, maxStacks int, sourceStack bson.ObjectID, sourceNodeID string, now time.Time) {
 	d, ok := bm.InboundDebuffs[id]
@@ -31,6 +31,7 @@ var ShipBlueprints = map[ShipType]Ship{
 		MetalCost:         20,
 		CrystalCost:       10,
 		PlasmaCost:        0,
+		BuildTimeSeconds:  30,
 		TransportCapacity: 0,
 		CanTransport:      nil,
 	},
@@ -53,9 +54,10 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilityPing],
 			AbilitiesCatalog[AbilityDecoyBeacon],
 		},
-		MetalCost:   50,
-		CrystalCost: 30,
-		PlasmaCost:  0,
+		MetalCost:        50,
+		CrystalCost:      30,
+		PlasmaCost:       0,
+		BuildTimeSeconds: 45,
 	},
 
 	// Versatile backbone fighter. Adapts to shields and focuses targets.
@@ -76,9 +78,10 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilityFocusFire],
 			AbilitiesCatalog[AbilityEvasiveManeuvers],
 		},
-		MetalCost:   80,
-		CrystalCost: 50,
-		PlasmaCost:  20,
+		MetalCost:        80,
+		CrystalCost:      50,
+		PlasmaCost:       20,
+		BuildTimeSeconds: 60,
 	},
 
 	// Siege platform with very long range and structure damage bonuses.
@@ -99,9 +102,10 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilitySiegePayload],
 			AbilitiesCatalog[AbilityStandoffPattern],
 		},
-		MetalCost:   600,
-		CrystalCost: 500,
-		PlasmaCost:  200,
+		MetalCost:        600,
+		CrystalCost:      500,
+		PlasmaCost:       200,
+		BuildTimeSeconds: 120,
 	},
 
 	// Mobile hub with bays for escorts. Tanky and defensive utility.
@@ -124,6 +128,7 @@ var ShipBlueprints = map[ShipType]Ship{
 		MetalCost:         800,
 		CrystalCost:       600,
 		PlasmaCost:        400,
+		BuildTimeSeconds:  600,
 		TransportCapacity: 40,
 		CanTransport:      []string{"drone", "scout", "fighter"},
 	},
@@ -146,9 +151,10 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilityAlphaStrike],
 			AbilitiesCatalog[AbilityInterdictorPulse],
 		},
-		MetalCost:   500,
-		CrystalCost: 700,
-		PlasmaCost:  400,
+		MetalCost:        500,
+		CrystalCost:      700,
+		PlasmaCost:       400,
+		BuildTimeSeconds: 300,
 	},
 
 	// Medium tank/brawler. Fills frontline gap with sustained presence.
@@ -171,9 +177,10 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilityShieldOvercharge],
 			AbilitiesCatalog[AbilityRammingSpeed],
 		},
-		MetalCost:   300,
-		CrystalCost: 200,
-		PlasmaCost:  100,
+		MetalCost:        300,
+		CrystalCost:      200,
+		PlasmaCost:       100,
+		BuildTimeSeconds: 420,
 	},
 
 	// Fast Antimatter attacker/pursuit specialist. Counters Scout swarms.
@@ -197,9 +204,10 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilityAntimatterBurst],
 			AbilitiesCatalog[AbilityTargetLock],
 		},
-		MetalCost:   100,
-		CrystalCost: 150,
-		PlasmaCost:  80,
+		MetalCost:        100,
+		CrystalCost:      150,
+		PlasmaCost:       80,
+		BuildTimeSeconds: 90,
 	},
 
 	// AoE specialist/swarm breaker. Punishes tight formations.
@@ -223,9 +231,10 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilityClusterMunitions],
 			AbilitiesCatalog[AbilityBarrageMode],
 		},
-		MetalCost:   700,
-		CrystalCost: 400,
-		PlasmaCost:  300,
+		MetalCost:        700,
+		CrystalCost:      400,
+		PlasmaCost:       300,
+		BuildTimeSeconds: 360,
 	},
 
 	// Stealth assassin. Surgical strikes on high-value targets.
@@ -249,9 +258,10 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilityBackstab],
 			AbilitiesCatalog[AbilitySmokeScreen],
 		},
-		MetalCost:   250,
-		CrystalCost: 300,
-		PlasmaCost:  150,
+		MetalCost:        250,
+		CrystalCost:      300,
+		PlasmaCost:       150,
+		BuildTimeSeconds: 240,
 	},
 
 	// Electronic warfare specialist. Force multiplier through debuffs.
@@ -275,8 +285,9 @@ var ShipBlueprints = map[ShipType]Ship{
 			AbilitiesCatalog[AbilityAbilityDisruptor],
 			AbilitiesCatalog[AbilityEnergyDrain],
 		},
-		MetalCost:   200,
-		CrystalCost: 250,
-		PlasmaCost:  100,
+		MetalCost:        200,
+		CrystalCost:      250,
+		PlasmaCost:       100,
+		BuildTimeSeconds: 180,
 	},
 }
@@ -0,0 +1,157 @@
+package ships
+
+// HP bucket compaction
+//
+// FormationAssignment.BucketIndex points into Ships[type], so any operation
+// that rewrites buckets must remap indexes in the active formation and in
+// every saved formation. CompactBuckets is the one place that does this; call
+// it after damage, healing, merges and splits instead of editing buckets and
+// hoping UpdateFormationAssignments rebinds correctly.
+//
+// Rules:
+//   - buckets with Count <= 0 or HP <= 0 are dropped
+//   - buckets with equal HP are merged into the first one, keeping its index
+//   - surviving buckets keep their relative order
+//   - ship types left without buckets are removed from Ships
+//   - an assignment follows its bucket to the new index; when buckets merge,
+//     the first assignment wins and the others are dropped (bucket-wide)
+
+// CompactBuckets normalises every ship type's buckets and remaps formation
+// assignments. It returns, per ship type that changed, old index -> new index
+// (-1 when the bucket was dropped).
+func (s *ShipStack) CompactBuckets() map[ShipType][]int {
+	remap := make(map[ShipType][]int)
+	for t, buckets := range s.Ships {
+		compacted, idx, changed := compactBucketList(buckets)
+		if !changed {
+			continue
+		}
+		remap[t] = idx
+		if len(compacted) == 0 {
+			delete(s.Ships, t)
+		} else {
+			s.Ships[t] = compacted
+		}
+	}
+	if len(remap) > 0 {
+		if s.Formation != nil {
+			remapFormationBuckets(s.Formation, remap)
+		}
+		for ft, f := range s.SavedFormations {
+			remapFormationBuckets(&f, remap)
+			s.SavedFormations[ft] = f
+		}
+	}
+	s.UpdateFormationAssignments()
+	return remap
+}
+
+// compactBucketList merges equal-HP buckets and drops empty ones. idx maps
+// each original position to its new one (-1 if dropped).
+func compactBucketList(buckets []HPBucket) (out []HPBucket, idx []int, changed bool) {
+	idx = make([]int, len(buckets))
+	byHP := make(map[int]int, len(buckets))
+	for i, b := range buckets {
+		if b.Count <= 0 || b.HP <= 0 {
+			idx[i] = -1
+			changed = true
+			continue
+		}
+		if j, ok := byHP[b.HP]; ok {
+			out[j].Count += b.Count
+			idx[i] = j
+			changed = true
+			continue
+		}
+		byHP[b.HP] = len(out)
+		idx[i] = len(out)
+		if idx[i] != i {
+			changed = true
+		}
+		out = append(out, b)
+	}
+	return out, idx, changed
+}
+
+// remapFormationBuckets rewrites BucketIndex through remap, dropping
+// assignments whose bucket disappeared or was merged into one that is
+// already assigned.
+func remapFormationBuckets(fws *FormationWithSlots, remap map[ShipType][]int) {
+	// Saved formations may share a backing array with the active one, so
+	// never filter in place.
+	kept := make([]FormationSlotAssignment, 0, len(fws.SlotAssignments))
+	seen := make(map[ShipType]map[int]bool)
+	for _, a := range fws.SlotAssignments {
+		if idx, ok := remap[a.ShipType]; ok {
+			if a.BucketIndex < 0 || a.BucketIndex >= len(idx) || idx[a.BucketIndex] < 0 {
+				continue
+			}
+			a.BucketIndex = idx[a.BucketIndex]
+		}
+		m := seen[a.ShipType]
+		if m == nil {
+			m = make(map[int]bool)
+			seen[a.ShipType] = m
+		}
+		if m[a.BucketIndex] {
+			continue
+		}
+		m[a.BucketIndex] = true
+		kept = append(kept, a)
+	}
+	fws.SlotAssignments = kept
+}
+
+// HealStack restores up to hp hit points to ships of type t, topping up the
+// most damaged bucket first without exceeding the blueprint's HP. Healing a
+// whole bucket moves it up; healing part of one splits it. Returns the HP
+// actually applied.
+func HealStack(s *ShipStack, t ShipType, hp int) int {
+	bp, ok := ShipBlueprints[t]
+	if !ok || hp <= 0 {
+		return 0
+	}
+	applied := 0
+	for hp > 0 {
+		buckets := s.Ships[t]
+		worst := -1
+		for i, b := range buckets {
+			if b.Count > 0 && b.HP > 0 && b.HP < bp.HP && (worst < 0 || b.HP < buckets[worst].HP) {
+				worst = i
+			}
+		}
+		if worst < 0 {
+			break
+		}
+		b := buckets[worst]
+		// Raise the whole bucket to the next HP level (the next-worse bucket or full).
+		target := bp.HP
+		for _, o := range buckets {
+			if o.Count > 0 && o.HP > b.HP && o.HP < target {
+				target = o.HP
+			}
+		}
+		need := (target - b.HP) * b.Count
+		if need <= hp {
+			buckets[worst].HP = target
+			hp -= need
+			applied += need
+		} else {
+			per := hp / b.Count
+			whole := hp % b.Count
+			if per == 0 {
+				// Not enough to lift every ship by 1; lift some of them.
+				buckets[worst].Count -= whole
+				buckets = append(buckets, HPBucket{HP: b.HP + 1, Count: whole})
+				applied += whole
+			} else {
+				buckets[worst].HP += per
+				applied += per * b.Count
+			}
+			s.Ships[t] = buckets
+			hp = 0
+		}
+		s.CompactBuckets()
+	}
+	return applied
+}
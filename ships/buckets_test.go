@@ -0,0 +1,241 @@
+package ships
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// randomStack is a stack with a formation built from its buckets, followed by
+// arbitrary bucket edits (empties, duplicates, dead buckets) that leave the
+// formation indexes stale, as damage or merges would.
+type randomStack struct {
+	Stack *ShipStack
+}
+
+var bucketTestTypes = []ShipType{Fighter, Bomber, Destroyer, Scout}
+
+func (randomStack) Generate(r *rand.Rand, size int) reflect.Value {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &ShipStack{Ships: make(map[ShipType][]HPBucket)}
+	for _, t := range bucketTestTypes {
+		n := r.Intn(6)
+		for i := 0; i < n; i++ {
+			// Few distinct HP values so duplicates are common.
+			s.Ships[t] = append(s.Ships[t], HPBucket{HP: 10 * (1 + r.Intn(4)), Count: 1 + r.Intn(5)})
+		}
+	}
+	s.EnsureFormationInitialized(now)
+	s.BuildAndSaveFormationLayout(FormationBox, now)
+
+	for t, buckets := range s.Ships {
+		for i := range buckets {
+			switch r.Intn(5) {
+			case 0:
+				buckets[i].Count = 0
+			case 1:
+				buckets[i].HP = 0
+			case 2:
+				buckets[i].HP = 10 * (1 + r.Intn(4))
+			}
+		}
+		s.Ships[t] = buckets
+	}
+	return reflect.ValueOf(randomStack{Stack: s})
+}
+
+func liveTotals(ships map[ShipType][]HPBucket) (map[ShipType]int, map[ShipType]int) {
+	count := make(map[ShipType]int)
+	hp := make(map[ShipType]int)
+	for t, buckets := range ships {
+		for _, b := range buckets {
+			if b.Count > 0 && b.HP > 0 {
+				count[t] += b.Count
+				hp[t] += b.Count * b.HP
+			}
+		}
+	}
+	return count, hp
+}
+
+func TestCompactBucketsPreservesShipsAndHP(t *testing.T) {
+	prop := func(rs randomStack) bool {
+		beforeCount, beforeHP := liveTotals(rs.Stack.Ships)
+		rs.Stack.CompactBuckets()
+		afterCount, afterHP := liveTotals(rs.Stack.Ships)
+		return reflect.DeepEqual(beforeCount, afterCount) && reflect.DeepEqual(beforeHP, afterHP)
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompactBucketsNormalForm(t *testing.T) {
+	prop := func(rs randomStack) bool {
+		rs.Stack.CompactBuckets()
+		for _, buckets := range rs.Stack.Ships {
+			if len(buckets) == 0 {
+				return false
+			}
+			seen := make(map[int]bool)
+			for _, b := range buckets {
+				if b.Count <= 0 || b.HP <= 0 || seen[b.HP] {
+					return false
+				}
+				seen[b.HP] = true
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCompactBucketsIdempotent(t *testing.T) {
+	prop := func(rs randomStack) bool {
+		rs.Stack.CompactBuckets()
+		ships := cloneBuckets(rs.Stack.Ships)
+		active := append([]FormationSlotAssignment{}, rs.Stack.Formation.SlotAssignments...)
+		remap := rs.Stack.CompactBuckets()
+		return len(remap) == 0 &&
+			reflect.DeepEqual(ships, rs.Stack.Ships) &&
+			reflect.DeepEqual(active, append([]FormationSlotAssignment{}, rs.Stack.Formation.SlotAssignments...))
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Every assignment in the active and saved formations must point at a live
+// bucket, at most once, with bucket-wide values.
+func TestCompactBucketsAssignmentsValid(t *testing.T) {
+	valid := func(s *ShipStack, fws *FormationWithSlots) bool {
+		seen := make(map[ShipType]map[int]bool)
+		for _, a := range fws.SlotAssignments {
+			buckets := s.Ships[a.ShipType]
+			if a.BucketIndex < 0 || a.BucketIndex >= len(buckets) {
+				return false
+			}
+			b := buckets[a.BucketIndex]
+			if a.Count != b.Count || a.AssignedHP != b.Count*b.HP {
+				return false
+			}
+			if seen[a.ShipType] == nil {
+				seen[a.ShipType] = make(map[int]bool)
+			}
+			if seen[a.ShipType][a.BucketIndex] {
+				return false
+			}
+			seen[a.ShipType][a.BucketIndex] = true
+		}
+		return true
+	}
+	prop := func(rs randomStack) bool {
+		s := rs.Stack
+		s.CompactBuckets()
+		if !valid(s, s.Formation) {
+			return false
+		}
+		for _, f := range s.SavedFormations {
+			if !valid(s, &f) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// An assignment that survives compaction keeps pointing at a bucket with the
+// same HP it pointed at before. Assignments whose bucket was dropped, or
+// merged into a bucket claimed by an earlier assignment, may have their slot
+// reused for another bucket.
+func TestCompactBucketsAssignmentsFollowBuckets(t *testing.T) {
+	type slot struct {
+		t    ShipType
+		pos  FormationPosition
+		slot int
+	}
+	prop := func(rs randomStack) bool {
+		s := rs.Stack
+		type pre struct {
+			key slot
+			idx int
+			hp  int
+		}
+		var pres []pre
+		for _, a := range s.Formation.SlotAssignments {
+			buckets := s.Ships[a.ShipType]
+			if a.BucketIndex < 0 || a.BucketIndex >= len(buckets) {
+				continue
+			}
+			pres = append(pres, pre{slot{a.ShipType, a.Position, a.SlotIndex}, a.BucketIndex, buckets[a.BucketIndex].HP})
+		}
+		remap := s.CompactBuckets()
+
+		survivors := make(map[slot]int)
+		claimed := make(map[ShipType]map[int]bool)
+		for _, p := range pres {
+			idx := p.idx
+			if m, ok := remap[p.key.t]; ok {
+				idx = m[p.idx]
+			}
+			if idx < 0 {
+				continue
+			}
+			if claimed[p.key.t] == nil {
+				claimed[p.key.t] = make(map[int]bool)
+			}
+			if claimed[p.key.t][idx] {
+				continue
+			}
+			claimed[p.key.t][idx] = true
+			survivors[p.key] = p.hp
+		}
+		for _, a := range s.Formation.SlotAssignments {
+			hp, ok := survivors[slot{a.ShipType, a.Position, a.SlotIndex}]
+			if ok && s.Ships[a.ShipType][a.BucketIndex].HP != hp {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHealStackCapsAtBlueprint(t *testing.T) {
+	prop := func(rs randomStack, amount uint16) bool {
+		s := rs.Stack
+		s.CompactBuckets()
+		_, beforeHP := liveTotals(s.Ships)
+		applied := HealStack(s, Fighter, int(amount))
+		_, afterHP := liveTotals(s.Ships)
+		if afterHP[Fighter]-beforeHP[Fighter] != applied || applied > int(amount) {
+			return false
+		}
+		for _, b := range s.Ships[Fighter] {
+			if b.HP > ShipBlueprints[Fighter].HP {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(prop, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func cloneBuckets(ships map[ShipType][]HPBucket) map[ShipType][]HPBucket {
+	out := make(map[ShipType][]HPBucket, len(ships))
+	for t, b := range ships {
+		out[t] = append([]HPBucket(nil), b...)
+	}
+	return out
+}
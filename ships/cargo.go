@@ -0,0 +1,170 @@
+package ships
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Cargo holds
+//
+// Hulls listed in CargoPerShip carry resources in a hold pooled across the
+// stack. Capacity is per ship, scaled by TransportCapacityPct like hangar
+// space. Mining loads the hold until it is full (see orbitables/mining.go);
+// arriving at a system the player controls unloads it into the planet.
+//
+// When haulers die and the hold no longer fits, SettleCargo spills the
+// overflow, taken from each resource in proportion. A destroyed stack spills
+// everything. What spills is returned so the caller can drop it as salvage.
+
+// CargoPerShip is each hull's base cargo capacity.
+var CargoPerShip = map[ShipType]int64{
+	Drone:     200,
+	Carrier:   1000,
+	Scout:     40,
+	Fighter:   40,
+	Destroyer: 120,
+	Bomber:    120,
+}
+
+var ErrCargoFull = errors.New("not enough cargo capacity")
+
+// GatheringStageCargoFull marks a mining stack whose hold is full; it mines
+// nothing more until it unloads.
+const GatheringStageCargoFull = "cargo_full"
+
+// Cargo is an amount of hauled resources.
+type Cargo struct {
+	Metals   int64 `bson:"metals,omitempty" json:"metals,omitempty"`
+	Crystals int64 `bson:"crystals,omitempty" json:"crystals,omitempty"`
+	Hydrogen int64 `bson:"hydrogen,omitempty" json:"hydrogen,omitempty"`
+}
+
+// Total returns the units held across resources.
+func (c Cargo) Total() int64 {
+	return c.Metals + c.Crystals + c.Hydrogen
+}
+
+// Add returns c with amount of resource ("metal", "crystal" or "hydrogen")
+// added. Unknown resources are ignored.
+func (c Cargo) Add(resource string, amount int64) Cargo {
+	switch resource {
+	case "metal":
+		c.Metals += amount
+	case "crystal":
+		c.Crystals += amount
+	case "hydrogen":
+		c.Hydrogen += amount
+	}
+	return c
+}
+
+// Plus returns c + other.
+func (c Cargo) Plus(other Cargo) Cargo {
+	return Cargo{Metals: c.Metals + other.Metals, Crystals: c.Crystals + other.Crystals, Hydrogen: c.Hydrogen + other.Hydrogen}
+}
+
+// CargoCapacity returns the stack's pooled cargo capacity.
+func (s *ShipStack) CargoCapacity(now time.Time) int64 {
+	var total int64
+	for t, n := range countShips(s.Ships) {
+		total += int64(n) * s.cargoPerShip(t, now)
+	}
+	return total
+}
+
+// CargoUsed returns the units in the hold.
+func (s *ShipStack) CargoUsed() int64 {
+	if s.Cargo == nil {
+		return 0
+	}
+	return s.Cargo.Total()
+}
+
+// CargoFree returns the room left in the hold.
+func (s *ShipStack) CargoFree(now time.Time) int64 {
+	free := s.CargoCapacity(now) - s.CargoUsed()
+	if free < 0 {
+		return 0
+	}
+	return free
+}
+
+// LoadCargo stores up to amount of resource and returns what fit.
+func (s *ShipStack) LoadCargo(resource string, amount int64, now time.Time) int64 {
+	if free := s.CargoFree(now); amount > free {
+		amount = free
+	}
+	if amount <= 0 {
+		return 0
+	}
+	c := s.Cargo
+	if c == nil {
+		c = &Cargo{}
+	}
+	*c = c.Add(resource, amount)
+	s.Cargo = c
+	return amount
+}
+
+// UnloadCargo empties the hold and returns its contents. A gathering stack
+// stopped by a full hold resumes.
+func (s *ShipStack) UnloadCargo() Cargo {
+	if s.Gathering != nil && s.Gathering.Stage == GatheringStageCargoFull {
+		s.Gathering.Stage = ""
+	}
+	if s.Cargo == nil {
+		return Cargo{}
+	}
+	out := *s.Cargo
+	s.Cargo = nil
+	return out
+}
+
+// SettleCargo spills whatever no longer fits the hold after losses and
+// returns it.
+func (s *ShipStack) SettleCargo(now time.Time) Cargo {
+	used := s.CargoUsed()
+	over := used - s.CargoCapacity(now)
+	if over <= 0 {
+		return Cargo{}
+	}
+	if over >= used {
+		return s.UnloadCargo()
+	}
+	held := [3]int64{s.Cargo.Metals, s.Cargo.Crystals, s.Cargo.Hydrogen}
+	var spill [3]int64
+	var taken int64
+	for i, n := range held {
+		spill[i] = n * over / used
+		taken += spill[i]
+	}
+	// Rounding leftovers come off the largest resources first.
+	order := []int{0, 1, 2}
+	sort.SliceStable(order, func(x, y int) bool { return held[order[x]] > held[order[y]] })
+	for k := 0; taken < over; k = (k + 1) % len(order) {
+		if i := order[k]; spill[i] < held[i] {
+			spill[i]++
+			taken++
+		}
+	}
+	s.Cargo = &Cargo{Metals: held[0] - spill[0], Crystals: held[1] - spill[1], Hydrogen: held[2] - spill[2]}
+	return Cargo{Metals: spill[0], Crystals: spill[1], Hydrogen: spill[2]}
+}
+
+func (s *ShipStack) cargoPerShip(t ShipType, now time.Time) int64 {
+	base := CargoPerShip[t]
+	if base <= 0 {
+		return 0
+	}
+	_, _, mods := s.EffectiveShipV2(t, 0, now)
+	pct := 0.0
+	if mods != nil {
+		ctx := ResolveContext{Now: now, HasFormation: s.Formation != nil}
+		if s.Formation != nil {
+			ctx.FormationType = s.Formation.Type
+		}
+		pct = mods.Resolve(ctx).TransportCapacityPct
+	}
+	return int64(float64(base) * (1 + pct))
+}
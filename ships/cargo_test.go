@@ -0,0 +1,38 @@
+package ships
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCargoLoadAndSpill(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &ShipStack{Ships: map[ShipType][]HPBucket{
+		Drone:   {{HP: ShipBlueprints[Drone].HP, Count: 2}},
+		Cruiser: {{HP: ShipBlueprints[Cruiser].HP, Count: 1}}, // no hold
+	}}
+	s.EnsureFormationInitialized(now)
+
+	if c := s.CargoCapacity(now); c != 2*CargoPerShip[Drone] {
+		t.Fatalf("capacity = %d, want %d", c, 2*CargoPerShip[Drone])
+	}
+	if got := s.LoadCargo("metal", 300, now); got != 300 {
+		t.Fatalf("loaded %d metal", got)
+	}
+	if got := s.LoadCargo("crystal", 200, now); got != 100 {
+		t.Fatalf("loaded %d crystal, want 100 (hold full)", got)
+	}
+
+	// Losing a drone halves the hold; the overflow spills 3:1 like the cargo.
+	s.Ships[Drone][0].Count = 1
+	spilled := s.SettleCargo(now)
+	if spilled != (Cargo{Metals: 150, Crystals: 50}) || *s.Cargo != (Cargo{Metals: 150, Crystals: 50}) {
+		t.Fatalf("spilled %+v, kept %+v", spilled, *s.Cargo)
+	}
+
+	// A destroyed stack drops everything.
+	delete(s.Ships, Drone)
+	if spilled := s.SettleCargo(now); spilled.Total() != 200 || s.Cargo != nil {
+		t.Fatalf("on destruction spilled %+v, kept %+v", spilled, s.Cargo)
+	}
+}
@@ -16,11 +16,15 @@ func ComputeLoadoutV2(
 	formation *Formation,
 	position FormationPosition,
 	ships map[ShipType][]HPBucket,
+	research []string,
 	now time.Time,
 	inCombat bool,
 ) (*ModifierStack, StatMods, []AbilityID) {
 	builder := NewModifierBuilder(now)
 
+	// 0. Research: completed blueprint upgrades for this ship type
+	builder.AddResearch(research, ShipType(ship.ShipType))
+
 	// 1. Gems: provide their own StatMods from gem properties
 	builder.AddGemsFromLoadout(loadout)
 
@@ -69,6 +73,9 @@ func ComputeStackModifiers(
 
 	builder := NewModifierBuilder(now)
 
+	// 0. Research: the owner's completed blueprint upgrades
+	builder.AddResearch(stack.Research, shipType)
+
 	// 1. Gems: provide their own StatMods
 	builder.AddGemsFromLoadout(loadout)
 
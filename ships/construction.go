@@ -0,0 +1,53 @@
+package ships
+
+import "time"
+
+// Ship construction costs
+//
+// Blueprints carry the base price and build time of one ship. ConstructionCostPct
+// (Logistics gems, Nebula origin, fleet-wide tree nodes) scales the resource
+// price only; build time is per ship and does not stack in parallel.
+
+// MaxConstructionDiscount caps how much ConstructionCostPct can cut costs.
+const MaxConstructionDiscount = 0.5
+
+// BuildCost is the total price and duration of building Count ships of a type.
+type BuildCost struct {
+	Metal    int64
+	Crystal  int64
+	Plasma   int64
+	Duration time.Duration
+}
+
+// ShipBuildCost prices count ships of type t with mods applied. ok is false
+// for unknown ship types or a non-positive count.
+func ShipBuildCost(t ShipType, count int, mods StatMods) (cost BuildCost, ok bool) {
+	bp, found := ShipBlueprints[t]
+	if !found || count <= 0 {
+		return BuildCost{}, false
+	}
+	mult := 1.0 + clamp(mods.ConstructionCostPct, -MaxConstructionDiscount, 1.0)
+	scale := func(base int) int64 {
+		return int64(float64(base*count)*mult + 0.5)
+	}
+	return BuildCost{
+		Metal:    scale(bp.MetalCost),
+		Crystal:  scale(bp.CrystalCost),
+		Plasma:   scale(bp.PlasmaCost),
+		Duration: time.Duration(bp.BuildTimeSeconds*count) * time.Second,
+	}, true
+}
+
+// GlobalMods sums the GlobalMods of every unlocked Fleet Command Mastery node.
+// These are the player-wide bonuses that apply outside of a stack, e.g. to
+// shipyard prices.
+func (ts *FormationTreeState) GlobalMods() StatMods {
+	var out StatMods
+	if ts == nil {
+		return out
+	}
+	for _, node := range ts.GetUnlockedNodesInTree("") {
+		out = CombineMods(out, node.Effects.GlobalMods)
+	}
+	return out
+}
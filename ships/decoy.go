@@ -0,0 +1,139 @@
+package ships
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Decoy beacons
+//
+// DecoyBeacon projects a PhantomContact: a fake stack that shows up in enemy
+// fog-of-war with a made-up composition. Phantoms expire after the ability's
+// DurationSeconds. Observers with CloakDetect in range, or a Ping on the
+// phantom, reveal it as fake to that player. Phantoms are not ShipStacks and
+// must never be accepted as combat targets; use PhantomSet.CheckAttackTarget
+// when resolving target IDs coming from clients.
+
+const DecoyBeaconRange = 800.0 // max projection distance from the caster
+
+var (
+	ErrDecoyOutOfRange = errors.New("decoy beacon target is out of range")
+	ErrPhantomTarget   = errors.New("target is a phantom contact")
+)
+
+// PhantomContact is a decoy visible in fog-of-war.
+type PhantomContact struct {
+	ID            bson.ObjectID           `bson:"_id,omitempty" json:"id"`
+	MapID         bson.ObjectID           `bson:"mapId" json:"mapId"`
+	PlayerID      bson.ObjectID           `bson:"playerId" json:"playerId"` // Owner; enemies see it as this player's stack
+	SourceStackID bson.ObjectID           `bson:"sourceStackId" json:"sourceStackId"`
+	X             float64                 `bson:"x" json:"x"`
+	Y             float64                 `bson:"y" json:"y"`
+	Ships         map[ShipType][]HPBucket `bson:"ships" json:"ships"` // Fake composition shown to enemies
+	CreatedAt     time.Time               `bson:"createdAt" json:"createdAt"`
+	ExpiresAt     time.Time               `bson:"expiresAt" json:"expiresAt"`
+	RevealedTo    []bson.ObjectID         `bson:"revealedTo,omitempty" json:"revealedTo,omitempty"` // Players who know it is fake
+}
+
+// DeployDecoyBeacon casts DecoyBeacon from this stack and returns the phantom
+// to persist. fake is the composition shown to enemies; nil mirrors the
+// caster's own ships.
+func (s *ShipStack) DeployDecoyBeacon(x, y float64, fake map[ShipType][]HPBucket, now time.Time) (*PhantomContact, error) {
+	if math.Hypot(x-s.PositionX, y-s.PositionY) > DecoyBeaconRange {
+		return nil, ErrDecoyOutOfRange
+	}
+	var caster ShipType
+	for t, n := range countShips(s.Ships) {
+		if n > 0 && s.HasAbility(t, AbilityDecoyBeacon) {
+			caster = t
+			break
+		}
+	}
+	if caster == "" {
+		return nil, ErrAbilityUnavailable
+	}
+	st, err := s.ActivateAbility(caster, AbilityDecoyBeacon, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if fake == nil {
+		fake = make(map[ShipType][]HPBucket, len(s.Ships))
+		for t, buckets := range s.Ships {
+			fake[t] = append([]HPBucket(nil), buckets...)
+		}
+	}
+	return &PhantomContact{
+		ID:            bson.NewObjectID(),
+		MapID:         s.MapID,
+		PlayerID:      s.PlayerID,
+		SourceStackID: s.ID,
+		X:             x,
+		Y:             y,
+		Ships:         fake,
+		CreatedAt:     now,
+		ExpiresAt:     st.EndTime,
+	}, nil
+}
+
+// IsExpired reports whether the phantom has run its course.
+func (p *PhantomContact) IsExpired(now time.Time) bool {
+	return !now.Before(p.ExpiresAt)
+}
+
+// IsRevealedTo reports whether player knows the contact is fake. The owner always does.
+func (p *PhantomContact) IsRevealedTo(player bson.ObjectID) bool {
+	if player == p.PlayerID {
+		return true
+	}
+	for _, id := range p.RevealedTo {
+		if id == player {
+			return true
+		}
+	}
+	return false
+}
+
+// RevealTo exposes the phantom as fake to player (Ping, CloakDetect).
+func (p *PhantomContact) RevealTo(player bson.ObjectID) {
+	if !p.IsRevealedTo(player) {
+		p.RevealedTo = append(p.RevealedTo, player)
+	}
+}
+
+// PhantomSet indexes a map's live phantoms by ID.
+type PhantomSet map[bson.ObjectID]*PhantomContact
+
+// Prune removes expired phantoms and returns their IDs.
+func (ps PhantomSet) Prune(now time.Time) []bson.ObjectID {
+	var removed []bson.ObjectID
+	for id, p := range ps {
+		if p.IsExpired(now) {
+			delete(ps, id)
+			removed = append(removed, id)
+		}
+	}
+	return removed
+}
+
+// CheckAttackTarget rejects phantom IDs before combat resolution loads a target.
+func (ps PhantomSet) CheckAttackTarget(id bson.ObjectID) error {
+	if _, ok := ps[id]; ok {
+		return ErrPhantomTarget
+	}
+	return nil
+}
+
+// ApplyPing reveals the contact as fake if id is a phantom. Returns true when
+// it was one, so the caller can skip the regular stack reveal.
+func (ps PhantomSet) ApplyPing(id, byPlayer bson.ObjectID) bool {
+	p, ok := ps[id]
+	if !ok {
+		return false
+	}
+	p.RevealTo(byPlayer)
+	return true
+}
@@ -0,0 +1,73 @@
+package ships
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestDeployDecoyBeacon(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	scout := stealthTestStack(bson.NewObjectID(), 0, 0, Scout)
+	fighters := stealthTestStack(scout.PlayerID, 0, 0, Fighter)
+
+	if _, err := scout.DeployDecoyBeacon(DecoyBeaconRange+1, 0, nil, now); !errors.Is(err, ErrDecoyOutOfRange) {
+		t.Fatalf("out of range: err = %v", err)
+	}
+	if _, err := fighters.DeployDecoyBeacon(100, 0, nil, now); !errors.Is(err, ErrAbilityUnavailable) {
+		t.Fatalf("without DecoyBeacon: err = %v", err)
+	}
+	p, err := scout.DeployDecoyBeacon(300, 400, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEnd := now.Add(time.Duration(AbilitiesCatalog[AbilityDecoyBeacon].DurationSeconds) * time.Second)
+	if p.PlayerID != scout.PlayerID || p.SourceStackID != scout.ID || p.X != 300 || !p.ExpiresAt.Equal(wantEnd) {
+		t.Fatalf("phantom = %+v", p)
+	}
+	if p.Ships[Scout][0].Count != 2 {
+		t.Fatalf("phantom did not mirror the caster: %+v", p.Ships)
+	}
+	p.Ships[Scout][0].Count = 50
+	if scout.Ships[Scout][0].Count != 2 {
+		t.Fatal("phantom composition aliases the caster's buckets")
+	}
+	if _, err := scout.DeployDecoyBeacon(300, 400, nil, now.Add(time.Minute)); !errors.Is(err, ErrAbilityOnCooldown) {
+		t.Fatalf("recast during cooldown: err = %v", err)
+	}
+
+	fake := map[ShipType][]HPBucket{Cruiser: {{HP: 1, Count: 40}}}
+	later := now.Add(time.Duration(AbilitiesCatalog[AbilityDecoyBeacon].CooldownSeconds) * time.Second)
+	q, err := scout.DeployDecoyBeacon(0, 100, fake, later)
+	if err != nil || q.Ships[Cruiser][0].Count != 40 {
+		t.Fatalf("fake composition: %+v, %v", q, err)
+	}
+}
+
+func TestPhantomSet(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	owner, enemy := bson.NewObjectID(), bson.NewObjectID()
+	p := &PhantomContact{ID: bson.NewObjectID(), PlayerID: owner, ExpiresAt: now.Add(time.Minute)}
+	ps := PhantomSet{p.ID: p}
+
+	if !p.IsRevealedTo(owner) || p.IsRevealedTo(enemy) {
+		t.Fatal("only the owner should know the contact is fake")
+	}
+	if err := ps.CheckAttackTarget(p.ID); !errors.Is(err, ErrPhantomTarget) {
+		t.Fatalf("attacking a phantom: err = %v", err)
+	}
+	if err := ps.CheckAttackTarget(bson.NewObjectID()); err != nil {
+		t.Fatalf("attacking a real stack: err = %v", err)
+	}
+	if ps.ApplyPing(bson.NewObjectID(), enemy) || !ps.ApplyPing(p.ID, enemy) || !p.IsRevealedTo(enemy) {
+		t.Fatal("ping did not reveal the phantom")
+	}
+	if removed := ps.Prune(now); len(removed) != 0 {
+		t.Fatalf("pruned a live phantom: %v", removed)
+	}
+	if removed := ps.Prune(p.ExpiresAt); len(removed) != 1 || len(ps) != 0 {
+		t.Fatalf("expired phantom kept: %v", removed)
+	}
+}
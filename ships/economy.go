@@ -1,5 +1,10 @@
 package ships
 
+import (
+	"sort"
+	"time"
+)
+
 // Economy helpers for mining/salvage throughput.
 // These are simple, declarative baselines intended to be used by the gathering
 // system (e.g., when resolving income ticks for a stack anchored on an asteroid/nebula).
@@ -36,3 +41,39 @@ func (s *ShipStack) EconomicThroughputMultiplier(t ShipType) float64 {
 	}
 	return cap
 }
+
+// MiningPower is the stack's anchored throughput in Drone equivalents: each
+// anchored ship counts for its hull's EconomicCap. Types are summed in name
+// order so the result is reproducible.
+func (s *ShipStack) MiningPower() float64 {
+	types := make([]ShipType, 0, len(s.Ships))
+	for t := range s.Ships {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	power := 0.0
+	for _, t := range types {
+		mult := s.EconomicThroughputMultiplier(t)
+		if mult == 0 {
+			continue
+		}
+		for _, b := range s.Ships[t] {
+			power += float64(b.Count) * mult
+		}
+	}
+	return power
+}
+
+// Record adds amount of resource ("metal", "crystal" or "hydrogen") to the
+// gathered totals.
+func (g *GatheringState) Record(resource string, amount int64, now time.Time) {
+	switch resource {
+	case "metal":
+		g.MetalsGathered += amount
+	case "crystal":
+		g.CrystalsGathered += amount
+	case "hydrogen":
+		g.HydrogenGathered += amount
+	}
+	g.ProcessedAt = now
+}
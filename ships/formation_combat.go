@@ -285,8 +285,25 @@ func (ctx *CombatContext) applyWeightedShieldMitigation(
 	return finalDamage
 }
 
+// DamageOutcome reports what ApplyDamageToStack destroyed and the salvage
+// the wrecks leave behind.
+type DamageOutcome struct {
+	ShipsLost map[ShipType]int
+	Salvage   Cargo
+}
+
+// addLosses counts further losses, e.g. embarked ships lost with their
+// carrier, and their wreckage.
+func (o *DamageOutcome) addLosses(lost map[ShipType]int) {
+	for t, n := range lost {
+		o.ShipsLost[t] += n
+	}
+	o.Salvage = o.Salvage.Plus(SalvageFor(lost))
+}
+
 // ApplyDamageToStack applies the calculated damage to the defender's HP buckets.
-func ApplyDamageToStack(defender *ShipStack, damageMap map[ShipType]map[int]int) {
+func ApplyDamageToStack(defender *ShipStack, damageMap map[ShipType]map[int]int) DamageOutcome {
+	before := countShips(defender.Ships)
 	for shipType, bucketDamages := range damageMap {
 		buckets, ok := defender.Ships[shipType]
 		if !ok {
@@ -333,8 +350,18 @@ func ApplyDamageToStack(defender *ShipStack, damageMap map[ShipType]map[int]int)
 		defender.Ships[shipType] = buckets
 	}
 
-	// Update formation assignments to reflect new HP values
-	defender.UpdateFormationAssignments()
+	// Drop destroyed buckets, merge equal-HP ones and remap formation assignments
+	defender.CompactBuckets()
+
+	out := DamageOutcome{ShipsLost: make(map[ShipType]int)}
+	after := countShips(defender.Ships)
+	for shipType, n := range before {
+		if lost := n - after[shipType]; lost > 0 {
+			out.ShipsLost[shipType] = lost
+		}
+	}
+	out.Salvage = SalvageFor(out.ShipsLost)
+	return out
 }
 
 // FormationBattleResult summarizes the outcome of a formation-aware battle round.
@@ -345,6 +372,7 @@ type FormationBattleResult struct {
 	DefenderShipsLost     map[ShipType]int
 	FormationAdvantage    float64                       // Attacker's formation counter multiplier
 	PositionEffectiveness map[FormationPosition]float64 // How effective each position was
+	Salvage               Cargo                         // Recoverable wreckage from both sides' losses
 }
 
 // ExecuteFormationBattleRound performs one round of turn-based combat with formations.
@@ -379,6 +407,10 @@ func ExecuteFormationBattleRound(attacker, defender *ShipStack, now time.Time) F
 	attacker.Battle.Counters.AttackCount++
 	defender.Battle.Counters.DefenseCount++
 
+	// Both sides fire this round, which breaks cloak
+	attacker.RecordAttack(now)
+	defender.RecordAttack(now)
+
 	ctx := NewCombatContext(attacker, defender, now)
 	result.FormationAdvantage = ctx.FormationCounter
 
@@ -392,17 +424,11 @@ func ExecuteFormationBattleRound(attacker, defender *ShipStack, now time.Time) F
 	// Apply cross-stack modifiers: accuracy vs evasion (flat damage reduction)
 	applyAccuracyVsEvasion(defenderDamageMap, attacker, defender, now)
 
-	defenderShipsBeforeDamage := countShips(defender.Ships)
-	ApplyDamageToStack(defender, defenderDamageMap)
-	defenderShipsAfterDamage := countShips(defender.Ships)
-
-	// Calculate ships lost
-	for shipType := range defenderShipsBeforeDamage {
-		lost := defenderShipsBeforeDamage[shipType] - defenderShipsAfterDamage[shipType]
-		if lost > 0 {
-			result.DefenderShipsLost[shipType] = lost
-		}
-	}
+	defenderOutcome := ApplyDamageToStack(defender, defenderDamageMap)
+	// Carriers lost this round take their overflowing hangar with them.
+	defenderOutcome.addLosses(defender.SettleHangar(now))
+	result.DefenderShipsLost = defenderOutcome.ShipsLost
+	result.Salvage = defenderOutcome.Salvage
 
 	// Phase 2: Defender returns fire (if still alive)
 	if !isStackDestroyed(defender) {
@@ -419,16 +445,12 @@ func ExecuteFormationBattleRound(attacker, defender *ShipStack, now time.Time) F
 		// Apply cross-stack modifiers: accuracy vs evasion
 		applyAccuracyVsEvasion(attackerDamageMap, defender, attacker, now)
 
-		attackerShipsBeforeDamage := countShips(attacker.Ships)
-		ApplyDamageToStack(attacker, attackerDamageMap)
-		attackerShipsAfterDamage := countShips(attacker.Ships)
-
-		for shipType := range attackerShipsBeforeDamage {
-			lost := attackerShipsBeforeDamage[shipType] - attackerShipsAfterDamage[shipType]
-			if lost > 0 {
-				result.AttackerShipsLost[shipType] = lost
-			}
-		}
+		attackerOutcome := ApplyDamageToStack(attacker, attackerDamageMap)
+		// Carriers lost this round take their overflowing hangar with them.
+		attackerOutcome.addLosses(attacker.SettleHangar(now))
+		result.AttackerShipsLost = attackerOutcome.ShipsLost
+		// Wrecks of everything lost this round, embarked ships included.
+		result.Salvage = result.Salvage.Plus(attackerOutcome.Salvage)
 	}
 
 	// Phase 3: Apply bio debuffs post-combat for next round
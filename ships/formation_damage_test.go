@@ -159,3 +159,30 @@ func abs(x float64) float64 {
 	}
 	return x
 }
+
+func TestDamageOutcomeSalvage(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	hp := ShipBlueprints[Fighter].HP
+	s := &ShipStack{Ships: map[ShipType][]HPBucket{Fighter: {{HP: hp, Count: 5}}}}
+	s.EnsureFormationInitialized(now)
+
+	out := ApplyDamageToStack(s, map[ShipType]map[int]int{Fighter: {0: 2 * hp}})
+	if out.ShipsLost[Fighter] != 2 || out.Salvage != SalvageFor(map[ShipType]int{Fighter: 2}) {
+		t.Fatalf("outcome = %+v", out)
+	}
+	out.addLosses(map[ShipType]int{Fighter: 1, Carrier: 1})
+	want := SalvageFor(map[ShipType]int{Fighter: 2}).Plus(SalvageFor(map[ShipType]int{Fighter: 1, Carrier: 1}))
+	if out.ShipsLost[Fighter] != 3 || out.ShipsLost[Carrier] != 1 || out.Salvage != want {
+		t.Fatalf("after hangar losses = %+v, want salvage %+v", out, want)
+	}
+
+	attacker := &ShipStack{Ships: map[ShipType][]HPBucket{Cruiser: {{HP: ShipBlueprints[Cruiser].HP, Count: 50}}}}
+	attacker.EnsureFormationInitialized(now)
+	defender := &ShipStack{Ships: map[ShipType][]HPBucket{Fighter: {{HP: hp, Count: 5}}}}
+	defender.EnsureFormationInitialized(now)
+	result := ExecuteFormationBattleRound(attacker, defender, now)
+	want = SalvageFor(result.DefenderShipsLost).Plus(SalvageFor(result.AttackerShipsLost))
+	if result.DefenderShipsLost[Fighter] == 0 || result.Salvage != want {
+		t.Fatalf("round lost %v, salvage %+v, want %+v", result.DefenderShipsLost, result.Salvage, want)
+	}
+}
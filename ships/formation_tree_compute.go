@@ -87,11 +87,15 @@ func ComputeLoadoutV2WithTree(
 	position FormationPosition,
 	ships map[ShipType][]HPBucket,
 	treeState *FormationTreeState,
+	research []string,
 	now time.Time,
 	inCombat bool,
 ) (*ModifierStack, StatMods, []AbilityID) {
 	builder := NewModifierBuilder(now)
 
+	// 0. Research: completed blueprint upgrades for this ship type
+	builder.AddResearch(research, ShipType(ship.ShipType))
+
 	// 1. Gems: provide their own StatMods
 	builder.AddGemsFromLoadout(loadout)
 
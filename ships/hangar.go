@@ -0,0 +1,242 @@
+package ships
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// Carrier hangars
+//
+// Ship types with TransportCapacity (Carriers) can embark the types listed in
+// CanTransport. Embarked ships live in ShipStack.Hangar instead of Ships, so
+// they take no damage, hold no formation slot and do not count towards the
+// stack's speed. Capacity is per carrier, scaled by TransportCapacityPct
+// (Catalyst Bay Commander, Fleet Logistics), and pooled across the stack.
+//
+// HangarLaunch moves everything in the hangar back into Ships and gives each
+// launched ship type an active HangarLaunch ability state, whose buffs come
+// from AbilityEffectsCatalog for the ability's duration. The carrier's own
+// state only tracks the cooldown.
+//
+// If carriers die and capacity falls below what is embarked, SettleHangar
+// destroys the overflow: those ships went down with their carrier.
+
+var (
+	ErrNoHangar        = errors.New("stack has no carrier capacity")
+	ErrCannotTransport = errors.New("ship type cannot be embarked")
+	ErrHangarFull      = errors.New("not enough hangar capacity")
+	ErrHangarEmpty     = errors.New("hangar is empty")
+	ErrNotEnoughShips  = errors.New("not enough ships of that type")
+)
+
+// HangarCapacity returns the stack's effective pooled transport capacity.
+func (s *ShipStack) HangarCapacity(now time.Time) int {
+	total := 0
+	for t, n := range countShips(s.Ships) {
+		if n <= 0 || ShipBlueprints[t].TransportCapacity <= 0 {
+			continue
+		}
+		eff, _, _ := s.EffectiveShipV2(t, 0, now)
+		total += n * eff.TransportCapacity
+	}
+	return total
+}
+
+// HangarUsed returns how many ships are embarked.
+func (s *ShipStack) HangarUsed() int {
+	return totalShips(s.Hangar)
+}
+
+// CanEmbark reports whether any carrier in the stack can carry ship type t.
+func (s *ShipStack) CanEmbark(t ShipType) bool {
+	for ct, n := range countShips(s.Ships) {
+		if n <= 0 {
+			continue
+		}
+		for _, allowed := range ShipBlueprints[ct].CanTransport {
+			if ShipType(allowed) == t {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckEmbark reports why count ships of type t cannot be embarked, if they
+// cannot.
+func (s *ShipStack) CheckEmbark(t ShipType, count int, now time.Time) error {
+	if count <= 0 || countShips(s.Ships)[t] < count {
+		return ErrNotEnoughShips
+	}
+	capacity := s.HangarCapacity(now)
+	if capacity <= 0 {
+		return ErrNoHangar
+	}
+	if !s.CanEmbark(t) {
+		return ErrCannotTransport
+	}
+	if s.HangarUsed()+count > capacity {
+		return ErrHangarFull
+	}
+	return nil
+}
+
+// Embark moves count ships of type t into the hangar, healthiest first.
+func (s *ShipStack) Embark(t ShipType, count int, now time.Time) error {
+	if err := s.CheckEmbark(t, count, now); err != nil {
+		return err
+	}
+
+	order := bucketOrderByHP(s.Ships[t], true)
+	left := count
+	for _, i := range order {
+		if left == 0 {
+			break
+		}
+		b := &s.Ships[t][i]
+		take := b.Count
+		if take > left {
+			take = left
+		}
+		b.Count -= take
+		left -= take
+		if s.Hangar == nil {
+			s.Hangar = make(map[ShipType][]HPBucket)
+		}
+		s.Hangar[t] = addAtHP(s.Hangar[t], b.HP, take)
+	}
+	s.CompactBuckets()
+	s.RetimeMovement(now)
+	return nil
+}
+
+// HangarLaunch deploys every embarked ship and applies launch buffs to the
+// launched ship types. Returns how many ships of each type were launched.
+func (s *ShipStack) HangarLaunch(now time.Time) (map[ShipType]int, error) {
+	if len(s.Hangar) == 0 {
+		return nil, ErrHangarEmpty
+	}
+	carrier, ok := s.hangarCaster()
+	if !ok {
+		return nil, ErrAbilityUnavailable
+	}
+	cast, err := s.ActivateAbility(carrier, AbilityHangarLaunch, now)
+	if err != nil {
+		return nil, err
+	}
+	// The carrier only holds the cooldown; the buffs belong to the escorts.
+	cast.IsActive = false
+	end := cast.EndTime
+
+	launched := make(map[ShipType]int, len(s.Hangar))
+	for t, buckets := range s.Hangar {
+		for _, b := range buckets {
+			if b.Count > 0 {
+				s.Ships = addToShips(s.Ships, t, b.HP, b.Count)
+				launched[t] += b.Count
+			}
+		}
+		s.upsertLaunchBuff(t, now, end)
+	}
+	s.Hangar = nil
+	s.CompactBuckets()
+	s.RetimeMovement(now)
+	return launched, nil
+}
+
+// SettleHangar destroys embarked ships that no longer fit after carrier
+// losses, most damaged first, and returns the losses per type.
+func (s *ShipStack) SettleHangar(now time.Time) map[ShipType]int {
+	over := s.HangarUsed() - s.HangarCapacity(now)
+	if over <= 0 {
+		return nil
+	}
+	lost := make(map[ShipType]int)
+	types := make([]ShipType, 0, len(s.Hangar))
+	for t := range s.Hangar {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	for _, t := range types {
+		for _, i := range bucketOrderByHP(s.Hangar[t], false) {
+			if over == 0 {
+				break
+			}
+			b := &s.Hangar[t][i]
+			take := b.Count
+			if take > over {
+				take = over
+			}
+			b.Count -= take
+			over -= take
+			lost[t] += take
+		}
+		compacted, _, _ := compactBucketList(s.Hangar[t])
+		if len(compacted) == 0 {
+			delete(s.Hangar, t)
+		} else {
+			s.Hangar[t] = compacted
+		}
+	}
+	if len(s.Hangar) == 0 {
+		s.Hangar = nil
+	}
+	return lost
+}
+
+func (s *ShipStack) hangarCaster() (ShipType, bool) {
+	counts := countShips(s.Ships)
+	types := make([]ShipType, 0, len(counts))
+	for t, n := range counts {
+		if n > 0 && s.HasAbility(t, AbilityHangarLaunch) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return "", false
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types[0], true
+}
+
+func (s *ShipStack) upsertLaunchBuff(t ShipType, now, end time.Time) {
+	spec := AbilitiesCatalog[AbilityHangarLaunch]
+	st := AbilityState{
+		IsActive:    true,
+		Description: spec.Description,
+		ShipType:    t,
+		Ability:     string(AbilityHangarLaunch),
+		StartTime:   now,
+		EndTime:     end,
+		Duration:    int64(spec.DurationSeconds),
+		LastUpdated: now,
+	}
+	if s.Ability == nil {
+		s.Ability = &[]AbilityState{}
+	}
+	states := *s.Ability
+	for i := range states {
+		if states[i].Ability == st.Ability && states[i].ShipType == t {
+			states[i] = st
+			return
+		}
+	}
+	*s.Ability = append(states, st)
+}
+
+// bucketOrderByHP returns bucket indexes sorted by HP, highest first when
+// desc is set, ties broken by index.
+func bucketOrderByHP(buckets []HPBucket, desc bool) []int {
+	order := make([]int, len(buckets))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if desc {
+			return buckets[order[a]].HP > buckets[order[b]].HP
+		}
+		return buckets[order[a]].HP < buckets[order[b]].HP
+	})
+	return order
+}
@@ -0,0 +1,71 @@
+package ships
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHangarEmbarkAndLaunch(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &ShipStack{Ships: map[ShipType][]HPBucket{
+		Carrier: {{HP: ShipBlueprints[Carrier].HP, Count: 1}},
+		Fighter: {{HP: ShipBlueprints[Fighter].HP, Count: 30}},
+		Cruiser: {{HP: ShipBlueprints[Cruiser].HP, Count: 2}},
+	}}
+	s.EnsureFormationInitialized(now)
+
+	capacity := s.HangarCapacity(now)
+	if capacity != ShipBlueprints[Carrier].TransportCapacity {
+		t.Fatalf("capacity = %d, want %d", capacity, ShipBlueprints[Carrier].TransportCapacity)
+	}
+	if err := s.Embark(Cruiser, 1, now); !errors.Is(err, ErrCannotTransport) {
+		t.Fatalf("embark cruiser: got %v, want ErrCannotTransport", err)
+	}
+	if err := s.Embark(Fighter, 30, now); err != nil {
+		t.Fatalf("embark fighters: %v", err)
+	}
+	if _, ok := s.Ships[Fighter]; ok {
+		t.Fatal("embarked fighters still in Ships")
+	}
+	if s.HangarUsed() != 30 {
+		t.Fatalf("hangar used = %d, want 30", s.HangarUsed())
+	}
+
+	launched, err := s.HangarLaunch(now)
+	if err != nil {
+		t.Fatalf("launch: %v", err)
+	}
+	if launched[Fighter] != 30 || countShips(s.Ships)[Fighter] != 30 || s.Hangar != nil {
+		t.Fatalf("launch moved %v, ships %v, hangar %v", launched, countShips(s.Ships), s.Hangar)
+	}
+	buffed := false
+	for _, st := range *s.Ability {
+		if st.Ability != string(AbilityHangarLaunch) {
+			continue
+		}
+		if st.ShipType == Carrier && st.IsActive {
+			t.Fatal("carrier itself should not be buffed")
+		}
+		if st.ShipType == Fighter && st.IsActive {
+			buffed = true
+		}
+	}
+	if !buffed {
+		t.Fatal("launched fighters have no HangarLaunch state")
+	}
+
+	if err := s.Embark(Fighter, 10, now); err != nil {
+		t.Fatalf("re-embark: %v", err)
+	}
+	if _, err := s.HangarLaunch(now.Add(time.Second)); !errors.Is(err, ErrAbilityOnCooldown) {
+		t.Fatalf("second launch: got %v, want ErrAbilityOnCooldown", err)
+	}
+
+	// Losing the carrier sinks whatever no longer fits.
+	delete(s.Ships, Carrier)
+	lost := s.SettleHangar(now)
+	if lost[Fighter] != 10 || s.Hangar != nil {
+		t.Fatalf("settle lost %v, hangar %v", lost, s.Hangar)
+	}
+}
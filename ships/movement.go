@@ -0,0 +1,182 @@
+package ships
+
+import (
+	"math"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Movement
+//
+// Stack.Movement is an ordered list of legs; Movement[0] is the active one.
+// Each leg is a straight line from StartX/StartY at StartTime to
+// TargetX/TargetY at EndTime. Later legs are chained to start where and when
+// the previous one ends; when a leg finishes early or off-target (e.g. an
+// interdicted warp) the next leg is rebased onto the actual end point.
+// Legs of any other Type ("mining", "idle", ...) are stationary and stop
+// the advance until something removes them.
+
+// CompletedLeg reports a leg that finished during AdvanceMovement.
+type CompletedLeg struct {
+	Leg *MovementState
+	X   float64
+	Y   float64
+	At  time.Time
+}
+
+// IsTravelLeg reports whether the leg moves the stack.
+func (m *MovementState) IsTravelLeg() bool {
+	return m.Type == MovementTypeTravel || m.Type == MovementTypeWarp
+}
+
+// PositionAt interpolates the leg's position at now.
+func (m *MovementState) PositionAt(now time.Time) (float64, float64) {
+	return legPointAt(m, now)
+}
+
+// PositionAt returns the stack's interpolated position at now without
+// mutating it.
+func (s *ShipStack) PositionAt(now time.Time) (float64, float64) {
+	if len(s.Movement) == 0 || s.Movement[0] == nil || !s.Movement[0].IsTravelLeg() {
+		return s.PositionX, s.PositionY
+	}
+	return s.Movement[0].PositionAt(now)
+}
+
+// QueueMove appends a sublight leg to (x, y). The leg starts where and when
+// the last queued leg ends, or at the current position and now if the stack
+// is idle. targetType is "coordinate", "system", "asteroid" or "nebula".
+func (s *ShipStack) QueueMove(x, y float64, targetID bson.ObjectID, targetType string, now time.Time) (*MovementState, error) {
+	if s.IsAnchored() {
+		return nil, ErrStackAnchored
+	}
+	speed := s.GetEffectiveStackSpeed()
+	if speed <= 0 {
+		return nil, ErrStackImmobile
+	}
+	fromX, fromY, from := s.PositionX, s.PositionY, now
+	if n := len(s.Movement); n > 0 && s.Movement[n-1] != nil && s.Movement[n-1].IsTravelLeg() {
+		last := s.Movement[n-1]
+		fromX, fromY = last.TargetX, last.TargetY
+		from = maxTime(last.EndTime, now)
+	}
+	if targetType == "" {
+		targetType = "coordinate"
+	}
+	leg := &MovementState{
+		Type:        MovementTypeTravel,
+		State:       MovementTypeTravel,
+		TargetID:    targetID,
+		TargetType:  targetType,
+		StartX:      fromX,
+		StartY:      fromY,
+		TargetX:     x,
+		TargetY:     y,
+		Speed:       speed,
+		StartTime:   from,
+		EndTime:     from.Add(travelDuration(math.Hypot(x-fromX, y-fromY), float64(speed))),
+		LastUpdated: now,
+	}
+	s.Movement = append(s.Movement, leg)
+	return leg, nil
+}
+
+// RetimeMovement recomputes sublight legs from the current
+// GetEffectiveStackSpeed, e.g. after ship losses or a formation change. The
+// active leg is split at now so progress so far is kept.
+func (s *ShipStack) RetimeMovement(now time.Time) {
+	speed := s.GetEffectiveStackSpeed()
+	if speed <= 0 {
+		return
+	}
+	prevX, prevY, prevEnd := s.PositionX, s.PositionY, now
+	for i, leg := range s.Movement {
+		if leg == nil || !leg.IsTravelLeg() {
+			break
+		}
+		switch {
+		case leg.Type == MovementTypeWarp && i == 0:
+			// An active warp keeps its committed timing.
+		case leg.Type == MovementTypeWarp:
+			leg.rebase(prevX, prevY, prevEnd)
+		default:
+			if i == 0 {
+				if now.After(leg.StartTime) {
+					prevX, prevY = leg.PositionAt(now)
+				} else {
+					prevX, prevY, prevEnd = leg.StartX, leg.StartY, leg.StartTime
+				}
+			}
+			leg.Speed = speed
+			leg.StartX, leg.StartY, leg.StartTime = prevX, prevY, prevEnd
+			leg.EndTime = prevEnd.Add(travelDuration(math.Hypot(leg.TargetX-prevX, leg.TargetY-prevY), float64(speed)))
+		}
+		leg.LastUpdated = now
+		prevX, prevY, prevEnd = leg.TargetX, leg.TargetY, leg.EndTime
+	}
+}
+
+// AdvanceMovement moves the stack to its position at now, completing and
+// removing finished legs in order. PositionX/Y are updated.
+func (s *ShipStack) AdvanceMovement(now time.Time) []CompletedLeg {
+	var done []CompletedLeg
+	for len(s.Movement) > 0 {
+		leg := s.Movement[0]
+		if leg == nil {
+			s.Movement = s.Movement[1:]
+			continue
+		}
+		if !leg.IsTravelLeg() {
+			break
+		}
+		leg.UpdateWarpStage(now)
+		if now.Before(leg.EndTime) {
+			s.PositionX, s.PositionY = leg.PositionAt(now)
+			leg.ProcessedAt = now
+			break
+		}
+
+		s.PositionX, s.PositionY = leg.TargetX, leg.TargetY
+		leg.ProcessedAt = now
+		if leg.Warp == nil {
+			leg.State = "arrived"
+		}
+		done = append(done, CompletedLeg{Leg: leg, X: leg.TargetX, Y: leg.TargetY, At: leg.EndTime})
+		s.Movement = s.Movement[1:]
+
+		if len(s.Movement) > 0 && s.Movement[0] != nil && s.Movement[0].IsTravelLeg() {
+			next := s.Movement[0]
+			if next.StartX != leg.TargetX || next.StartY != leg.TargetY || next.StartTime.Before(leg.EndTime) {
+				next.rebase(leg.TargetX, leg.TargetY, leg.EndTime)
+			}
+		}
+	}
+	if len(s.Movement) == 0 {
+		s.Movement = nil
+	}
+	return done
+}
+
+// ClearMovement cancels all queued legs, leaving the stack where it is at now.
+func (s *ShipStack) ClearMovement(now time.Time) {
+	s.PositionX, s.PositionY = s.PositionAt(now)
+	s.Movement = nil
+}
+
+// rebase moves a leg's origin to (x, y) starting at t, keeping its target and
+// speed. Warp legs keep their charge length; the scattered arrival point is
+// kept as-is.
+func (m *MovementState) rebase(x, y float64, t time.Time) {
+	speed := float64(m.Speed)
+	if m.Warp != nil {
+		charge := m.Warp.ChargeEndsAt.Sub(m.Warp.ChargeStartedAt)
+		if t.After(m.Warp.ChargeStartedAt) {
+			m.Warp.ChargeStartedAt = t
+			m.Warp.ChargeEndsAt = t.Add(charge)
+		}
+		t = m.Warp.ChargeEndsAt
+	}
+	m.StartX, m.StartY, m.StartTime = x, y, t
+	m.EndTime = t.Add(travelDuration(math.Hypot(m.TargetX-x, m.TargetY-y), speed))
+}
@@ -0,0 +1,108 @@
+package ships
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-6 }
+
+func TestQueueMoveChainsAndAdvances(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := warpTestStack(Fighter)
+	first, err := s.QueueMove(600, 0, bson.NilObjectID, "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := s.QueueMove(600, 800, bson.NilObjectID, "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.StartX != 600 || second.StartY != 0 || !second.StartTime.Equal(first.EndTime) {
+		t.Fatalf("second leg not chained: %+v", second)
+	}
+
+	half := first.StartTime.Add(first.EndTime.Sub(first.StartTime) / 2)
+	if x, y := s.PositionAt(half); !near(x, 300) || y != 0 {
+		t.Fatalf("PositionAt halfway = (%.2f, %.2f)", x, y)
+	}
+	if s.PositionX != 0 {
+		t.Fatal("PositionAt mutated the stack")
+	}
+
+	mid := second.StartTime.Add(second.EndTime.Sub(second.StartTime) / 2)
+	done := s.AdvanceMovement(mid)
+	if len(done) != 1 || done[0].Leg != first || !done[0].At.Equal(first.EndTime) {
+		t.Fatalf("completed = %+v", done)
+	}
+	if !near(s.PositionX, 600) || !near(s.PositionY, 400) || len(s.Movement) != 1 {
+		t.Fatalf("stack at (%.2f, %.2f) with %d legs", s.PositionX, s.PositionY, len(s.Movement))
+	}
+	if done := s.AdvanceMovement(second.EndTime.Add(time.Hour)); len(done) != 1 || s.Movement != nil || s.PositionY != 800 {
+		t.Fatalf("final advance: %+v, stack at (%.2f, %.2f)", done, s.PositionX, s.PositionY)
+	}
+}
+
+func TestRetimeMovementKeepsProgress(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := warpTestStack(Fighter)
+	first, _ := s.QueueMove(6000, 0, bson.NilObjectID, "", now)
+	second, _ := s.QueueMove(6000, 6000, bson.NilObjectID, "", now)
+	fast := first.Speed
+
+	at := now.Add(first.EndTime.Sub(now) / 3)
+	px, py := s.PositionAt(at)
+	s.Ships[Ballista] = []HPBucket{{HP: ShipBlueprints[Ballista].HP, Count: 1}}
+	s.RetimeMovement(at)
+
+	if first.Speed >= fast || first.Speed != s.GetEffectiveStackSpeed() {
+		t.Fatalf("speed %d after adding a slower hull (was %d)", first.Speed, fast)
+	}
+	if !near(first.StartX, px) || !near(first.StartY, py) || !first.StartTime.Equal(at) {
+		t.Fatalf("active leg restarted at (%.1f, %.1f) %v, want (%.1f, %.1f) %v", first.StartX, first.StartY, first.StartTime, px, py, at)
+	}
+	if want := at.Add(travelDuration(6000-px, float64(first.Speed))); !first.EndTime.Equal(want) {
+		t.Fatalf("active leg ends %v, want %v", first.EndTime, want)
+	}
+	if second.StartX != 6000 || !second.StartTime.Equal(first.EndTime) || second.Speed != first.Speed {
+		t.Fatalf("queued leg not retimed: %+v", second)
+	}
+}
+
+func TestAdvanceRebasesAfterInterdictedWarp(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := warpTestStack(Cruiser)
+	warp, err := PlanWarp(s, 10000, 0, WarpContext{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Movement = append(s.Movement, warp)
+	next, err := s.QueueMove(10000, 5000, bson.NilObjectID, "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.StartX != warp.TargetX || next.StartY != warp.TargetY {
+		t.Fatal("follow-up leg does not start at the scattered landing point")
+	}
+
+	midX, midY := (warp.StartX+warp.TargetX)/2, (warp.StartY+warp.TargetY)/2
+	field := InterdictionField{PlayerID: bson.NewObjectID(), X: midX, Y: midY, Radius: 100, ActiveFrom: now}
+	if !ResolveInterdiction(s, warp, WarpContext{Interdictions: []InterdictionField{field}}, warp.EndTime) {
+		t.Fatal("warp not interdicted")
+	}
+
+	done := s.AdvanceMovement(warp.EndTime.Add(time.Second))
+	if len(done) != 1 || done[0].X != warp.TargetX {
+		t.Fatalf("completed = %+v", done)
+	}
+	if next.StartX != warp.TargetX || next.StartY != warp.TargetY || !next.StartTime.Equal(warp.EndTime) {
+		t.Fatalf("follow-up leg not rebased onto the stop point: %+v", next)
+	}
+	dist := math.Hypot(next.TargetX-next.StartX, next.TargetY-next.StartY)
+	if want := next.StartTime.Add(travelDuration(dist, float64(next.Speed))); !next.EndTime.Equal(want) {
+		t.Fatalf("follow-up leg ends %v, want %v", next.EndTime, want)
+	}
+}
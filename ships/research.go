@@ -0,0 +1,179 @@
+package ships
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Research
+//
+// Particle accelerators run research projects. A finished project either
+// upgrades a ship blueprint (Mods apply to every ship of ShipType the player
+// owns) or raises the highest gem tier the player may socket. Projects need an
+// accelerator of at least MinLevel and every project listed in Requires.
+//
+// The player's finished project IDs are the only state; everything else is
+// derived from ResearchCatalog. Stacks carry a copy in ShipStack.Research so
+// the modifier builders can add research layers (AddResearch), and socketing
+// checks ValidateGemTiers.
+
+// BaseGemTierCap is the highest gem tier socketable without research.
+const BaseGemTierCap = 3
+
+// SourceResearch layers carry researched blueprint upgrades. They apply with
+// the blueprint, before any gem.
+const (
+	SourceResearch   ModifierSource = "research"
+	PriorityResearch                = 50
+)
+
+var (
+	ErrUnknownResearch = errors.New("unknown research project")
+	ErrResearchDone    = errors.New("research project already completed")
+	ErrResearchLocked  = errors.New("research prerequisites not met")
+	ErrGemTierLocked   = errors.New("gem tier not yet researched")
+)
+
+// ResearchProject is one entry of ResearchCatalog.
+type ResearchProject struct {
+	ID              string
+	Name            string
+	Description     string
+	MinLevel        int      // Particle accelerator level required
+	Requires        []string // Projects that must be completed first
+	Metal           int64
+	Crystal         int64
+	Plasma          int64
+	DurationSeconds int
+
+	// Blueprint upgrade.
+	ShipType ShipType
+	Mods     StatMods
+
+	// Gem tier unlock: the new highest socketable tier.
+	GemTierCap int
+}
+
+// ResearchCatalog lists every research project by ID.
+var ResearchCatalog = map[string]ResearchProject{
+	"fighter_afterburners": {
+		ID: "fighter_afterburners", Name: "Afterburners", Description: "Fighters gain +1 speed.",
+		MinLevel: 1, Metal: 400, Crystal: 300, Plasma: 50, DurationSeconds: 1800,
+		ShipType: Fighter, Mods: StatMods{SpeedDelta: 1},
+	},
+	"bomber_payload": {
+		ID: "bomber_payload", Name: "Heavy Payload", Description: "Bombers deal +10% nuclear damage and +10% damage to structures.",
+		MinLevel: 2, Metal: 800, Crystal: 600, Plasma: 120, DurationSeconds: 3600,
+		ShipType: Bomber, Mods: StatMods{Damage: DamageMods{NuclearPct: 0.10}, StructureDamagePct: 0.10},
+	},
+	"cruiser_plating": {
+		ID: "cruiser_plating", Name: "Composite Plating", Description: "Cruisers gain +10% bucket HP.",
+		MinLevel: 3, Metal: 1500, Crystal: 900, Plasma: 200, DurationSeconds: 5400,
+		ShipType: Cruiser, Mods: StatMods{BucketHPPct: 0.10},
+	},
+	"carrier_bays": {
+		ID: "carrier_bays", Name: "Expanded Bays", Description: "Carriers gain +20% transport capacity.",
+		MinLevel: 3, Requires: []string{"fighter_afterburners"}, Metal: 1200, Crystal: 1200, Plasma: 200, DurationSeconds: 5400,
+		ShipType: Carrier, Mods: StatMods{TransportCapacityPct: 0.20},
+	},
+	"gem_lattice_4": {
+		ID: "gem_lattice_4", Name: "Crystal Lattice IV", Description: "Tier IV gems may be socketed.",
+		MinLevel: 4, Metal: 2000, Crystal: 3000, Plasma: 400, DurationSeconds: 7200,
+		GemTierCap: 4,
+	},
+	"gem_lattice_5": {
+		ID: "gem_lattice_5", Name: "Crystal Lattice V", Description: "Tier V gems may be socketed.",
+		MinLevel: 6, Requires: []string{"gem_lattice_4"}, Metal: 4000, Crystal: 6000, Plasma: 900, DurationSeconds: 14400,
+		GemTierCap: 5,
+	},
+}
+
+// CanResearch checks whether projectID may be started given the player's
+// completed projects and the accelerator's level.
+func CanResearch(projectID string, completed []string, level int) (ResearchProject, error) {
+	p, ok := ResearchCatalog[projectID]
+	if !ok {
+		return ResearchProject{}, fmt.Errorf("%w: %s", ErrUnknownResearch, projectID)
+	}
+	if hasResearch(completed, projectID) {
+		return p, ErrResearchDone
+	}
+	if level < p.MinLevel {
+		return p, ErrResearchLocked
+	}
+	for _, req := range p.Requires {
+		if !hasResearch(completed, req) {
+			return p, ErrResearchLocked
+		}
+	}
+	return p, nil
+}
+
+// ResearchMods sums the blueprint upgrades completed for ship type t.
+func ResearchMods(completed []string, t ShipType) StatMods {
+	mods := ZeroMods()
+	for _, p := range researchedFor(completed, t) {
+		mods = CombineMods(mods, p.Mods)
+	}
+	return mods
+}
+
+// GemTierCap returns the highest gem tier the player may socket.
+func GemTierCap(completed []string) int {
+	limit := BaseGemTierCap
+	for _, id := range completed {
+		if p, ok := ResearchCatalog[id]; ok && p.GemTierCap > limit {
+			limit = p.GemTierCap
+		}
+	}
+	return limit
+}
+
+// ValidateGemTiers rejects loadouts socketing gems above the player's
+// researched tier.
+func ValidateGemTiers(loadout ShipLoadout, completed []string) error {
+	limit := GemTierCap(completed)
+	for _, g := range loadout.Sockets {
+		if g.Tier > limit {
+			return fmt.Errorf("%w: %s is tier %d, max %d", ErrGemTierLocked, g.ID, g.Tier, limit)
+		}
+	}
+	return nil
+}
+
+// AddResearch adds one layer per completed blueprint upgrade for ship type t.
+func (mb *ModifierBuilder) AddResearch(completed []string, t ShipType) *ModifierBuilder {
+	for _, p := range researchedFor(completed, t) {
+		mb.stack.AddPermanent(
+			SourceResearch,
+			p.ID,
+			fmt.Sprintf("Research: %s", p.Name),
+			p.Mods,
+			PriorityResearch,
+			mb.now,
+		)
+	}
+	return mb
+}
+
+// researchedFor returns the completed blueprint upgrades for t, sorted by ID.
+func researchedFor(completed []string, t ShipType) []ResearchProject {
+	var out []ResearchProject
+	for _, id := range completed {
+		if p, ok := ResearchCatalog[id]; ok && p.ShipType == t && p.ShipType != "" {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func hasResearch(completed []string, id string) bool {
+	for _, c := range completed {
+		if c == id {
+			return true
+		}
+	}
+	return false
+}
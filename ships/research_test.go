@@ -0,0 +1,47 @@
+package ships
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestResearchLayers(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &ShipStack{Ships: map[ShipType][]HPBucket{
+		Fighter: {{HP: ShipBlueprints[Fighter].HP, Count: 5}},
+		Bomber:  {{HP: ShipBlueprints[Bomber].HP, Count: 2}},
+	}}
+	s.EnsureFormationInitialized(now)
+
+	_, before := ComputeStackModifiers(s, Fighter, 0, now, false, "")
+	s.Research = []string{"fighter_afterburners"}
+	modStack, after := ComputeStackModifiers(s, Fighter, 0, now, false, "")
+	if after.SpeedDelta != before.SpeedDelta+1 {
+		t.Fatalf("fighter speed delta %d, want %d", after.SpeedDelta, before.SpeedDelta+1)
+	}
+	if len(modStack.GetLayersBySource(SourceResearch)) != 1 {
+		t.Fatal("no research layer on the fighter's stack")
+	}
+	if _, bomber := ComputeStackModifiers(s, Bomber, 0, now, false, ""); bomber.SpeedDelta != 0 {
+		t.Fatalf("afterburners applied to bombers: %+v", bomber)
+	}
+
+	ship := ShipBlueprints[Fighter]
+	loadout := ShipLoadout{}
+	_, plain, _ := ComputeLoadoutV2(ship, RoleTactical, loadout, nil, PositionFront, s.Ships, nil, now, false)
+	_, researched, _ := ComputeLoadoutV2WithTree(ship, RoleTactical, loadout, nil, PositionFront, s.Ships, nil, s.Research, now, false)
+	if researched.SpeedDelta != plain.SpeedDelta+1 {
+		t.Fatalf("loadout speed delta %d, want %d", researched.SpeedDelta, plain.SpeedDelta+1)
+	}
+}
+
+func TestValidateGemTiers(t *testing.T) {
+	tier4 := ShipLoadout{Sockets: []Gem{GemCatalog[GemID(familyID(GemLaser, 4))]}}
+	if err := ValidateGemTiers(tier4, nil); !errors.Is(err, ErrGemTierLocked) {
+		t.Fatalf("tier 4 without research: got %v, want ErrGemTierLocked", err)
+	}
+	if err := ValidateGemTiers(tier4, []string{"gem_lattice_4"}); err != nil {
+		t.Fatalf("tier 4 with research: %v", err)
+	}
+}
@@ -68,10 +68,10 @@ var RoleModesCatalog = map[RoleMode]RoleModeSpec{
 		Description: "Allows anchoring to gather resources at reduced efficiency; weaker combat while anchored.",
 		BaseMods: StatMods{
 			Damage: DamageMods{LaserPct: -0.25, NuclearPct: -0.25, AntimatterPct: -0.25}, // weaker offense
-			// While anchored, an additional -1 shield is recommended (enforce at runtime near asteroids/nebulas)
+			// While anchored, AddAnchoredPenalty adds -1 to every shield (see anchoring.go)
 		},
 		ReconfigureSeconds: 180,
-		WarpAllowed:        true, // becomes false if Anchored (runtime rule)
+		WarpAllowed:        true, // false while anchored (CanWarp)
 		RequiresAnchoring:  false,
 		// Example ability restrictions: siege/tactical tools are disabled while anchored mining
 		DisabledAbilities: []AbilityID{AbilityStandoffPattern},
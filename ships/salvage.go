@@ -0,0 +1,54 @@
+package ships
+
+import "sort"
+
+// Salvage
+//
+// Destroyed ships leave wreckage worth SalvageRecoveryPct of their blueprint
+// metal and crystal cost (plasma burns up). Anchored stacks recover it at a
+// rate weighted per hull by SalvageCap, where Destroyers and Bombers, poor
+// miners, do most of their economic work.
+
+// SalvageRecoveryPct is the share of a lost ship's cost left as salvage.
+const SalvageRecoveryPct = 0.25
+
+// SalvageCap is each hull's salvage throughput relative to a Drone.
+var SalvageCap = map[ShipType]float64{
+	Drone:     1.00,
+	Destroyer: 0.60,
+	Bomber:    0.50,
+	Carrier:   0.40,
+	Scout:     0.25,
+	Fighter:   0.25,
+}
+
+// SalvageFor returns the wreckage left by the given losses.
+func SalvageFor(lost map[ShipType]int) Cargo {
+	var metal, crystal float64
+	for t, n := range lost {
+		bp := ShipBlueprints[t]
+		metal += float64(n*bp.MetalCost) * SalvageRecoveryPct
+		crystal += float64(n*bp.CrystalCost) * SalvageRecoveryPct
+	}
+	return Cargo{Metals: int64(metal), Crystals: int64(crystal)}
+}
+
+// SalvagePower is the stack's anchored salvage throughput in Drone
+// equivalents.
+func (s *ShipStack) SalvagePower() float64 {
+	types := make([]ShipType, 0, len(s.Ships))
+	for t := range s.Ships {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	power := 0.0
+	for _, t := range types {
+		if !s.GetOrInitLoadout(t).Anchored {
+			continue
+		}
+		for _, b := range s.Ships[t] {
+			power += float64(b.Count) * SalvageCap[t]
+		}
+	}
+	return power
+}
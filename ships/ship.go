@@ -30,6 +30,8 @@ type Ship struct {
 	MetalCost   int
 	CrystalCost int
 	PlasmaCost  int
+	// BuildTimeSeconds is how long a shipyard takes to build one ship.
+	BuildTimeSeconds int
 	// Carrier specific
 	TransportCapacity int
 	CanTransport      []string
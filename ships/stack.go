@@ -56,13 +56,21 @@ type ShipStack struct {
 	CreatedAt time.Time               `bson:"createdAt"` // tick timestamp
 
 	// Role represents the tactical intent of the entire stack (tactical/economic/recon/scientific)
-	// Role             RoleMode  `bson:"role" json:"role"`
-	// ReconfigureUntil time.Time `bson:"reconfigureUntil,omitempty" json:"reconfigureUntil,omitempty"`
+	Role             RoleMode  `bson:"role,omitempty" json:"role,omitempty"`
+	ReconfigureUntil time.Time `bson:"reconfigureUntil,omitempty" json:"reconfigureUntil,omitempty"`
 
 	// Loadouts track per-ship-type socket configurations for this particular stack.
 	// This allows two stacks to field the same ship type with different gem setups.
 	Loadouts map[ShipType]ShipLoadout `bson:"loadouts,omitempty" json:"loadouts,omitempty"`
 
+	// Research is the owner's completed research (see research.go), stamped by
+	// the game layer so blueprint upgrades apply in ComputeStackModifiers.
+	Research []string `bson:"research,omitempty" json:"research,omitempty"`
+
+	// Hangar holds ships embarked on the stack's carriers (see hangar.go). They
+	// are outside Ships: untargetable, unslotted and ignored for speed.
+	Hangar map[ShipType][]HPBucket `bson:"hangar,omitempty" json:"hangar,omitempty"`
+
 	// Formation defines the tactical positioning of ships within the stack
 	Formation              *FormationWithSlots                  `bson:"formation,omitempty" json:"formation,omitempty"`
 	FormationReconfigUntil time.Time                            `bson:"formationReconfigUntil,omitempty" json:"formationReconfigUntil,omitempty"`
@@ -76,6 +84,9 @@ type ShipStack struct {
 	Battle      *BattleState     `bson:"battle,omitempty" json:"battle,omitempty"`       // Combat state for free space battles
 	Ability     *[]AbilityState  `bson:"ability,omitempty" json:"ability,omitempty"`     // Active ship ability state
 	Gathering   *GatheringState  `bson:"gathering,omitempty" json:"gathering,omitempty"` // Active gathering state
+	Cargo       *Cargo           `bson:"cargo,omitempty" json:"cargo,omitempty"`         // Hauled resources (see cargo.go)
+	Anchor      *AnchorState     `bson:"anchor,omitempty" json:"anchor,omitempty"`       // Anchoring runtime (see anchoring.go)
+	Stealth     *StealthState    `bson:"stealth,omitempty" json:"stealth,omitempty"`     // Cloak breaks and reveal marks (see stealth.go)
 	BioTreePath BioTreePath      `bson:"bioTreePath,omitempty" json:"bioTreePath,omitempty"`
 	Bio         *BioMachine      `bson:"bio,omitempty" json:"bio,omitempty"` // Biology node runtime state machine
 	Version     int64            `bson:"version" json:"version"`             // For optimistic locking
@@ -107,8 +118,14 @@ func (s *ShipStack) BuildBioFromPath(path BioTreePath, now time.Time) {
 }
 
 func (s *ShipStack) TickBio(now time.Time) {
-	if s.Bio != nil {
-		s.Bio.Tick(now)
+	if s.Bio == nil {
+		return
+	}
+	s.Bio.Tick(now)
+	for _, n := range s.Bio.Nodes {
+		if n.Invisible && n.Stage == BioStageTriggered && now.Before(n.EndTime) {
+			s.ApplyInvisibility(n.EndTime)
+		}
 	}
 }
 
@@ -144,8 +161,14 @@ func (s *ShipStack) StartModeSwitch(newRole RoleMode, now time.Time) time.Time {
 	if ok && spec.ReconfigureSeconds > 0 {
 		reconfig = spec.ReconfigureSeconds
 	}
-	reconf := now.Add(time.Duration(reconfig) * time.Second)
-	return reconf
+	s.Role = newRole
+	s.ReconfigureUntil = now.Add(time.Duration(reconfig) * time.Second)
+	return s.ReconfigureUntil
+}
+
+// InRole reports whether the stack has finished switching to role by now.
+func (s *ShipStack) InRole(role RoleMode, now time.Time) bool {
+	return s.Role == role && !now.Before(s.ReconfigureUntil)
 }
 
 // SetAnchored updates the anchored state for this ship type on the stack.
@@ -200,6 +223,7 @@ type MovementState struct {
 	Activity    string        `bson:"activity,omitempty" json:"activity"`               // "mining_metal", "mining_crystal", "mining_hydrogen"
 	LastUpdated time.Time     `bson:"lastUpdated,omitempty" json:"lastUpdated"`         // Last time this state was updated
 	ProcessedAt time.Time     `bson:"ProcessedAt,omitempty" json:"ProcessedAt"`         // Last time this state was processed
+	Warp        *WarpLeg      `bson:"warp,omitempty" json:"warp,omitempty"`             // Set when Type is "warp" (see warp.go)
 }
 
 type AbilityState struct {
@@ -219,7 +243,7 @@ type AbilityState struct {
 type GatheringState struct {
 	IsGathering         bool          `bson:"isGathering" json:"isGathering"`           // Whether the stack is currently mining
 	TargetID            bson.ObjectID `bson:"targetId" json:"targetId"`                 // ID of the target being gathered from
-	TargetType          string        `bson:"targetType" json:"targetType"`             // Type of target (e.g., "asteroid", "nebula")
+	TargetType          string        `bson:"targetType" json:"targetType"`             // Type of target (e.g., "asteroid", "nebula", "salvage")
 	ResourcePerTimeUnit int64         `bson:"resourcePerTime" json:"resourcePerTime"`   // Amount of resource gathered per time unit
 	TimeUnit            int64         `bson:"timeUnit" json:"timeUnit"`                 // Time unit in seconds for gathering
 	Stage               string        `bson:"stage,omitempty" json:"stage,omitempty"`   // Stage of the gathering process
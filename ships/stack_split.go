@@ -0,0 +1,503 @@
+package ships
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Split and merge
+//
+// SplitStack carves HP buckets out of a stack into a new real ShipStack at the
+// same position; MergeStacks folds one co-located stack into another. Both
+// require idle stacks (no movement, combat, gathering or anchor) that are not
+// in the middle of a formation reconfiguration, and both start a fresh
+// reconfiguration on the resulting stacks unless the player has the Swarm
+// "split_merge" node (Rapid Response), which makes them instant.
+//
+// Runtime state is carried so splitting or merging cannot be used to shed
+// cooldowns or debuffs: the new stack inherits a copy of the Bio machine,
+// ability states and stealth marks for the ship types it receives, and a
+// merge keeps the later expiry/cooldown of whatever both sides hold.
+//
+// Hangars and cargo holds never split: embarked ships and cargo stay with the
+// source, which must keep enough capacity for them. A merge pools both.
+
+// SplitMergeRadius is how close two stacks must be to merge.
+const SplitMergeRadius = 50.0
+
+// SplitMergeEffect is the formation tree custom effect that makes split and
+// merge instant.
+const SplitMergeEffect = "split_merge"
+
+var (
+	ErrSplitEmpty         = errors.New("split must move at least one ship")
+	ErrSplitBucket        = errors.New("split bucket not found in stack")
+	ErrSplitWholeStack    = errors.New("split would leave the source stack empty")
+	ErrStackBusy          = errors.New("stack is moving, fighting, gathering or anchored")
+	ErrStackReconfiguring = errors.New("stack formation is reconfiguring")
+	ErrMergeSelf          = errors.New("cannot merge a stack into itself")
+	ErrMergeOwner         = errors.New("stacks belong to different players or maps")
+	ErrMergeDistance      = errors.New("stacks are too far apart to merge")
+	ErrLoadoutConflict    = errors.New("stacks socket different gems on the same ship type")
+	ErrBioPathConflict    = errors.New("stacks follow different bio tree paths")
+)
+
+// LoadoutMergePolicy decides what happens when both stacks field the same
+// ship type with different gem sockets.
+type LoadoutMergePolicy string
+
+const (
+	LoadoutKeepTarget LoadoutMergePolicy = "keep_target" // absorbed ships adopt the target's sockets
+	LoadoutKeepSource LoadoutMergePolicy = "keep_source" // target ships adopt the absorbed stack's sockets
+	LoadoutReject     LoadoutMergePolicy = "reject"      // refuse the merge
+)
+
+// SplitStack moves the given buckets (matched by HP, up to Count ships each)
+// from src into a new stack and returns it. tree is the owner's formation
+// tree state and may be nil.
+func SplitStack(src *ShipStack, take map[ShipType][]HPBucket, tree *FormationTreeState, now time.Time) (*ShipStack, error) {
+	if err := checkSplitMergeReady(src, now); err != nil {
+		return nil, err
+	}
+	// Entries for the same type and HP are summed before checking them
+	// against the stack, so duplicates cannot ask for the same ships twice.
+	moved := 0
+	for t, buckets := range take {
+		want := make(map[int]int, len(buckets))
+		for _, b := range buckets {
+			if b.Count < 0 {
+				return nil, ErrSplitBucket
+			}
+			want[b.HP] += b.Count
+			moved += b.Count
+		}
+		for hp, n := range want {
+			if n > 0 && availableAtHP(src.Ships[t], hp) < n {
+				return nil, ErrSplitBucket
+			}
+		}
+	}
+	if moved == 0 {
+		return nil, ErrSplitEmpty
+	}
+	if moved >= totalShips(src.Ships) {
+		return nil, ErrSplitWholeStack
+	}
+	// The hangar stays with src, so its remaining carriers must still hold it.
+	if used := src.HangarUsed(); used > 0 {
+		capacity := src.HangarCapacity(now)
+		for t, buckets := range take {
+			if ShipBlueprints[t].TransportCapacity <= 0 {
+				continue
+			}
+			eff, _, _ := src.EffectiveShipV2(t, 0, now)
+			for _, b := range buckets {
+				capacity -= b.Count * eff.TransportCapacity
+			}
+		}
+		if used > capacity {
+			return nil, ErrHangarFull
+		}
+	}
+	// So does the cargo hold.
+	if used := src.CargoUsed(); used > 0 {
+		capacity := src.CargoCapacity(now)
+		for t, buckets := range take {
+			per := src.cargoPerShip(t, now)
+			for _, b := range buckets {
+				capacity -= int64(b.Count) * per
+			}
+		}
+		if used > capacity {
+			return nil, ErrCargoFull
+		}
+	}
+
+	dst := &ShipStack{
+		ID:          bson.NewObjectID(),
+		PlayerID:    src.PlayerID,
+		MapID:       src.MapID,
+		PositionX:   src.PositionX,
+		PositionY:   src.PositionY,
+		Ships:       make(map[ShipType][]HPBucket),
+		CreatedAt:   now,
+		Research:    append([]string(nil), src.Research...),
+		BioTreePath: src.BioTreePath,
+	}
+	for t, buckets := range take {
+		for _, b := range buckets {
+			if b.Count == 0 {
+				continue
+			}
+			left, err := removeAtHP(src.Ships[t], b.HP, b.Count)
+			if err != nil {
+				return nil, err
+			}
+			src.Ships[t] = left
+			dst.Ships[t] = addAtHP(dst.Ships[t], b.HP, b.Count)
+		}
+	}
+	src.CompactBuckets()
+
+	for t := range dst.Ships {
+		if l, ok := src.Loadouts[t]; ok {
+			if dst.Loadouts == nil {
+				dst.Loadouts = make(map[ShipType]ShipLoadout)
+			}
+			dst.Loadouts[t] = ShipLoadout{Sockets: append([]Gem(nil), l.Sockets...), Anchored: l.Anchored}
+		}
+		if src.Ability != nil {
+			for _, st := range *src.Ability {
+				if st.ShipType == t {
+					appendAbilityState(dst, st)
+				}
+			}
+		}
+	}
+	for t := range src.Loadouts {
+		if _, ok := src.Ships[t]; !ok {
+			delete(src.Loadouts, t)
+		}
+	}
+	if src.Bio != nil {
+		dst.Bio = src.Bio.Clone()
+	}
+	if src.Stealth != nil {
+		st := *src.Stealth
+		st.Reveals = append([]RevealMark(nil), src.Stealth.Reveals...)
+		dst.Stealth = &st
+	}
+
+	formation := FormationLine
+	if src.Formation != nil {
+		formation = src.Formation.Type
+	}
+	fws := dst.BuildAndSaveFormationLayout(formation, now)
+	dst.Formation = &fws
+	startSplitMergeReconfig(tree, formation, now, src, dst)
+	src.UpdateStackAttackRange(now)
+	dst.UpdateStackAttackRange(now)
+	return dst, nil
+}
+
+// MergeStacks folds src into dst. On success src is emptied and should be
+// deleted by the caller. Loadout conflicts are settled by policy; differing
+// bio tree paths are always rejected.
+func MergeStacks(dst, src *ShipStack, policy LoadoutMergePolicy, tree *FormationTreeState, now time.Time) error {
+	if dst.ID == src.ID {
+		return ErrMergeSelf
+	}
+	if dst.PlayerID != src.PlayerID || dst.MapID != src.MapID {
+		return ErrMergeOwner
+	}
+	if err := checkSplitMergeReady(dst, now); err != nil {
+		return err
+	}
+	if err := checkSplitMergeReady(src, now); err != nil {
+		return err
+	}
+	if math.Hypot(dst.PositionX-src.PositionX, dst.PositionY-src.PositionY) > SplitMergeRadius {
+		return ErrMergeDistance
+	}
+	if dst.BioTreePath != "" && src.BioTreePath != "" && dst.BioTreePath != src.BioTreePath {
+		return ErrBioPathConflict
+	}
+
+	loadouts, err := mergeLoadouts(dst, src, policy)
+	if err != nil {
+		return err
+	}
+
+	for t, buckets := range src.Ships {
+		for _, b := range buckets {
+			if b.Count > 0 {
+				dst.Ships = addToShips(dst.Ships, t, b.HP, b.Count)
+			}
+		}
+	}
+	for t, buckets := range src.Hangar {
+		for _, b := range buckets {
+			if b.Count > 0 {
+				if dst.Hangar == nil {
+					dst.Hangar = make(map[ShipType][]HPBucket)
+				}
+				dst.Hangar[t] = addAtHP(dst.Hangar[t], b.HP, b.Count)
+			}
+		}
+	}
+	if src.Cargo != nil {
+		pooled := *src.Cargo
+		if dst.Cargo != nil {
+			pooled = pooled.Plus(*dst.Cargo)
+		}
+		dst.Cargo = &pooled
+	}
+	dst.Loadouts = loadouts
+	if dst.BioTreePath == "" {
+		dst.BioTreePath = src.BioTreePath
+	}
+	switch {
+	case dst.Bio == nil && src.Bio != nil:
+		dst.Bio = src.Bio.Clone()
+	case dst.Bio != nil && src.Bio != nil:
+		dst.Bio.absorb(src.Bio)
+	}
+	if src.Ability != nil {
+		for _, st := range *src.Ability {
+			mergeAbilityState(dst, st)
+		}
+	}
+	if src.Stealth != nil {
+		ds := dst.ensureStealth()
+		ds.LastAttackAt = maxTime(ds.LastAttackAt, src.Stealth.LastAttackAt)
+		// Invisibility only survives if both halves had it.
+		ds.InvisibleUntil = minTime(ds.InvisibleUntil, src.Stealth.InvisibleUntil)
+		for _, r := range src.Stealth.Reveals {
+			mergeRevealMark(ds, r)
+		}
+	} else if dst.Stealth != nil {
+		dst.Stealth.InvisibleUntil = time.Time{}
+	}
+
+	src.Ships = map[ShipType][]HPBucket{}
+	src.Hangar = nil
+	src.Cargo = nil
+	src.Loadouts = nil
+	src.Formation = nil
+	src.SavedFormations = nil
+
+	dst.EnsureFormationInitialized(now)
+	dst.CompactBuckets()
+	startSplitMergeReconfig(tree, dst.Formation.Type, now, dst)
+	dst.UpdateStackAttackRange(now)
+	return nil
+}
+
+func checkSplitMergeReady(s *ShipStack, now time.Time) error {
+	if len(s.Movement) > 0 || (s.Battle != nil && s.Battle.IsInCombat) || (s.Gathering != nil && s.Gathering.IsGathering) || s.IsAnchored() {
+		return ErrStackBusy
+	}
+	if s.IsFormationReconfiguring(now) {
+		return ErrStackReconfiguring
+	}
+	return nil
+}
+
+// startSplitMergeReconfig puts the stacks into a formation reconfiguration,
+// skipped entirely with the split_merge tree effect.
+func startSplitMergeReconfig(tree *FormationTreeState, formation FormationType, now time.Time, stacks ...*ShipStack) {
+	if HasTreeCustomEffect(tree, formation, SplitMergeEffect) {
+		for _, s := range stacks {
+			s.FormationReconfigUntil = now
+		}
+		return
+	}
+	base := 0
+	if spec, ok := FormationCatalog[formation]; ok {
+		base = spec.ReconfigureTime
+	}
+	secs := CalculateEffectiveReconfigTime(base, tree, formation)
+	for _, s := range stacks {
+		s.FormationReconfigUntil = now.Add(time.Duration(secs) * time.Second)
+	}
+}
+
+func mergeLoadouts(dst, src *ShipStack, policy LoadoutMergePolicy) (map[ShipType]ShipLoadout, error) {
+	out := make(map[ShipType]ShipLoadout, len(dst.Loadouts)+len(src.Loadouts))
+	for t, l := range dst.Loadouts {
+		out[t] = l
+	}
+	for t, sl := range src.Loadouts {
+		if len(src.Ships[t]) == 0 {
+			continue
+		}
+		dl := out[t]
+		if len(dst.Ships[t]) == 0 {
+			out[t] = sl
+			continue
+		}
+		if sameSockets(dl.Sockets, sl.Sockets) {
+			continue
+		}
+		switch policy {
+		case LoadoutKeepSource:
+			out[t] = ShipLoadout{Sockets: sl.Sockets, Anchored: dl.Anchored}
+		case LoadoutReject:
+			return nil, ErrLoadoutConflict
+		default:
+			// keep the target's sockets
+		}
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+func sameSockets(a, b []Gem) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID {
+			return false
+		}
+	}
+	return true
+}
+
+func appendAbilityState(s *ShipStack, st AbilityState) {
+	if s.Ability == nil {
+		s.Ability = &[]AbilityState{}
+	}
+	*s.Ability = append(*s.Ability, st)
+}
+
+// mergeAbilityState keeps the most recent activation per ability and ship type
+// so cooldowns carry over.
+func mergeAbilityState(s *ShipStack, st AbilityState) {
+	if s.Ability != nil {
+		states := *s.Ability
+		for i := range states {
+			if states[i].Ability == st.Ability && states[i].ShipType == st.ShipType {
+				if st.StartTime.After(states[i].StartTime) {
+					states[i] = st
+				}
+				return
+			}
+		}
+	}
+	appendAbilityState(s, st)
+}
+
+// Clone returns a deep copy of the machine with its indexes rebuilt.
+func (bm *BioMachine) Clone() *BioMachine {
+	raw, err := bson.Marshal(bm)
+	if err != nil {
+		return nil
+	}
+	var out BioMachine
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return nil
+	}
+	if out.Nodes == nil {
+		out.Nodes = make(map[string]*BioNodeRuntimeState)
+	}
+	if out.InboundDebuffs == nil {
+		out.InboundDebuffs = make(map[string]*BioDebuffState)
+	}
+	if out.InboundBuffs == nil {
+		out.InboundBuffs = make(map[string]*BioBuffState)
+	}
+	out.ByShipType = make(map[ShipType]map[string]*BioNodeRuntimeState)
+	for _, n := range out.Nodes {
+		n.parent = &out
+		out.IndexNode(n)
+	}
+	return &out
+}
+
+// absorb folds another machine's state into this one, keeping the later
+// expiry for inbound effects and the later cooldown for shared nodes.
+func (bm *BioMachine) absorb(o *BioMachine) {
+	// Decoded machines have nil maps when they were stored empty.
+	if bm.InboundDebuffs == nil {
+		bm.InboundDebuffs = make(map[string]*BioDebuffState)
+	}
+	if bm.InboundBuffs == nil {
+		bm.InboundBuffs = make(map[string]*BioBuffState)
+	}
+	for id, d := range o.InboundDebuffs {
+		if cur, ok := bm.InboundDebuffs[id]; !ok || d.ExpiresAt.After(cur.ExpiresAt) {
+			c := *d
+			bm.InboundDebuffs[id] = &c
+		}
+	}
+	for id, b := range o.InboundBuffs {
+		if cur, ok := bm.InboundBuffs[id]; !ok || b.ExpiresAt.After(cur.ExpiresAt) {
+			c := *b
+			bm.InboundBuffs[id] = &c
+		}
+	}
+	for id, n := range o.Nodes {
+		cur, ok := bm.Nodes[id]
+		if !ok {
+			continue
+		}
+		if n.CooldownEndsAt.After(cur.CooldownEndsAt) {
+			cur.Stage = n.Stage
+			cur.CooldownEndsAt = n.CooldownEndsAt
+		}
+	}
+}
+
+func availableAtHP(buckets []HPBucket, hp int) int {
+	n := 0
+	for _, b := range buckets {
+		if b.HP == hp {
+			n += b.Count
+		}
+	}
+	return n
+}
+
+// removeAtHP takes count ships at the given HP and fails with ErrSplitBucket
+// if fewer are there. Emptied buckets are left in place for CompactBuckets so
+// formation indexes can be remapped.
+func removeAtHP(buckets []HPBucket, hp, count int) ([]HPBucket, error) {
+	if availableAtHP(buckets, hp) < count {
+		return buckets, ErrSplitBucket
+	}
+	for i := range buckets {
+		if buckets[i].HP == hp && count > 0 {
+			take := buckets[i].Count
+			if take > count {
+				take = count
+			}
+			buckets[i].Count -= take
+			count -= take
+		}
+	}
+	return buckets, nil
+}
+
+func addAtHP(buckets []HPBucket, hp, count int) []HPBucket {
+	for i := range buckets {
+		if buckets[i].HP == hp {
+			buckets[i].Count += count
+			return buckets
+		}
+	}
+	return append(buckets, HPBucket{HP: hp, Count: count})
+}
+
+func addToShips(ships map[ShipType][]HPBucket, t ShipType, hp, count int) map[ShipType][]HPBucket {
+	if ships == nil {
+		ships = make(map[ShipType][]HPBucket)
+	}
+	ships[t] = addAtHP(ships[t], hp, count)
+	return ships
+}
+
+func totalShips(ships map[ShipType][]HPBucket) int {
+	n := 0
+	for _, c := range countShips(ships) {
+		n += c
+	}
+	return n
+}
+
+func mergeRevealMark(st *StealthState, r RevealMark) {
+	for i := range st.Reveals {
+		if st.Reveals[i].PlayerID == r.PlayerID {
+			if r.ExpiresAt.After(st.Reveals[i].ExpiresAt) {
+				st.Reveals[i] = r
+			}
+			return
+		}
+	}
+	st.Reveals = append(st.Reveals, r)
+}
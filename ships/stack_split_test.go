@@ -0,0 +1,180 @@
+package ships
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func splitTestStack(ships map[ShipType][]HPBucket, now time.Time) *ShipStack {
+	s := &ShipStack{ID: bson.NewObjectID(), PlayerID: bson.NewObjectID(), MapID: bson.NewObjectID(), Ships: ships}
+	s.EnsureFormationInitialized(now)
+	s.FormationReconfigUntil = time.Time{}
+	return s
+}
+
+func TestSplitStack(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	hp := ShipBlueprints[Fighter].HP
+	src := splitTestStack(map[ShipType][]HPBucket{
+		Fighter: {{HP: hp, Count: 10}, {HP: hp / 2, Count: 4}},
+		Cruiser: {{HP: ShipBlueprints[Cruiser].HP, Count: 2}},
+	}, now)
+
+	// Two entries for the same bucket together ask for more than it holds.
+	dup := map[ShipType][]HPBucket{Fighter: {{HP: hp / 2, Count: 3}, {HP: hp / 2, Count: 3}}}
+	if _, err := SplitStack(src, dup, nil, now); !errors.Is(err, ErrSplitBucket) {
+		t.Fatalf("duplicate entries: got %v, want ErrSplitBucket", err)
+	}
+	if n := availableAtHP(src.Ships[Fighter], hp/2); n != 4 {
+		t.Fatalf("rejected split changed the source: %d damaged fighters, want 4", n)
+	}
+	if _, err := SplitStack(src, map[ShipType][]HPBucket{Fighter: {{HP: hp / 3, Count: 1}}}, nil, now); !errors.Is(err, ErrSplitBucket) {
+		t.Fatalf("missing bucket: got %v, want ErrSplitBucket", err)
+	}
+	if _, err := SplitStack(src, map[ShipType][]HPBucket{Fighter: {{HP: hp, Count: 0}}}, nil, now); !errors.Is(err, ErrSplitEmpty) {
+		t.Fatalf("empty split: got %v, want ErrSplitEmpty", err)
+	}
+	if _, err := SplitStack(src, copyBuckets(src.Ships), nil, now); !errors.Is(err, ErrSplitWholeStack) {
+		t.Fatalf("whole stack: got %v, want ErrSplitWholeStack", err)
+	}
+
+	ok := map[ShipType][]HPBucket{Fighter: {{HP: hp / 2, Count: 2}, {HP: hp / 2, Count: 2}, {HP: hp, Count: 3}}}
+	dst, err := SplitStack(src, ok, nil, now)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	if got := countShips(dst.Ships)[Fighter]; got != 7 {
+		t.Fatalf("new stack has %d fighters, want 7", got)
+	}
+	if got := countShips(src.Ships)[Fighter]; got != 7 {
+		t.Fatalf("source kept %d fighters, want 7", got)
+	}
+	if availableAtHP(src.Ships[Fighter], hp/2) != 0 || availableAtHP(dst.Ships[Fighter], hp/2) != 4 {
+		t.Fatalf("damaged fighters not moved: src %v, dst %v", src.Ships[Fighter], dst.Ships[Fighter])
+	}
+	if _, ok := dst.Ships[Cruiser]; ok {
+		t.Fatal("cruisers moved without being asked for")
+	}
+	if dst.PlayerID != src.PlayerID || dst.PositionX != src.PositionX || dst.PositionY != src.PositionY {
+		t.Fatal("new stack not placed with the source")
+	}
+	if !dst.IsFormationReconfiguring(now) || !src.IsFormationReconfiguring(now) {
+		t.Fatal("split should start a reconfiguration on both stacks")
+	}
+}
+
+func TestRemoveAtHP(t *testing.T) {
+	buckets := []HPBucket{{HP: 10, Count: 2}, {HP: 10, Count: 1}, {HP: 5, Count: 4}}
+	left, err := removeAtHP(buckets, 10, 3)
+	if err != nil || availableAtHP(left, 10) != 0 || availableAtHP(left, 5) != 4 {
+		t.Fatalf("remove 3: %v, %v", left, err)
+	}
+	if _, err := removeAtHP(left, 5, 5); !errors.Is(err, ErrSplitBucket) {
+		t.Fatalf("remove too many: got %v, want ErrSplitBucket", err)
+	}
+	if availableAtHP(left, 5) != 4 {
+		t.Fatal("failed remove changed the buckets")
+	}
+}
+
+func TestMergeStacksLoadouts(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	laser := []Gem{GemCatalog[GemID(familyID(GemLaser, 1))]}
+	sensor := []Gem{GemCatalog[GemID(familyID(GemSensor, 1))]}
+	hp := ShipBlueprints[Fighter].HP
+
+	pair := func() (dst, src *ShipStack) {
+		dst = splitTestStack(map[ShipType][]HPBucket{Fighter: {{HP: hp, Count: 5}}}, now)
+		src = splitTestStack(map[ShipType][]HPBucket{
+			Fighter: {{HP: hp, Count: 3}},
+			Cruiser: {{HP: ShipBlueprints[Cruiser].HP, Count: 1}},
+		}, now)
+		src.PlayerID, src.MapID = dst.PlayerID, dst.MapID
+		dst.Loadouts = map[ShipType]ShipLoadout{Fighter: {Sockets: laser}}
+		src.Loadouts = map[ShipType]ShipLoadout{Fighter: {Sockets: sensor}, Cruiser: {Sockets: sensor}}
+		return dst, src
+	}
+
+	tests := []struct {
+		name    string
+		policy  LoadoutMergePolicy
+		want    []Gem
+		wantErr error
+	}{
+		{"keep target", LoadoutKeepTarget, laser, nil},
+		{"default keeps target", "", laser, nil},
+		{"keep source", LoadoutKeepSource, sensor, nil},
+		{"reject", LoadoutReject, nil, ErrLoadoutConflict},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dst, src := pair()
+			err := MergeStacks(dst, src, tc.policy, nil, now)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("merge: got %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if countShips(src.Ships)[Fighter] != 3 || !sameSockets(dst.Loadouts[Fighter].Sockets, laser) {
+					t.Fatal("rejected merge changed the stacks")
+				}
+				return
+			}
+			if got := countShips(dst.Ships); got[Fighter] != 8 || got[Cruiser] != 1 || totalShips(src.Ships) != 0 {
+				t.Fatalf("merged ships %v, source left %v", got, src.Ships)
+			}
+			if !sameSockets(dst.Loadouts[Fighter].Sockets, tc.want) {
+				t.Fatalf("fighter sockets %v, want %v", dst.Loadouts[Fighter].Sockets, tc.want)
+			}
+			// A type only the source fields keeps its own sockets.
+			if !sameSockets(dst.Loadouts[Cruiser].Sockets, sensor) {
+				t.Fatalf("cruiser sockets %v, want the source's", dst.Loadouts[Cruiser].Sockets)
+			}
+		})
+	}
+
+	dst, src := pair()
+	src.PositionX = dst.PositionX + SplitMergeRadius + 1
+	if err := MergeStacks(dst, src, LoadoutKeepTarget, nil, now); !errors.Is(err, ErrMergeDistance) {
+		t.Fatalf("distant merge: got %v, want ErrMergeDistance", err)
+	}
+}
+
+func copyBuckets(ships map[ShipType][]HPBucket) map[ShipType][]HPBucket {
+	out := make(map[ShipType][]HPBucket, len(ships))
+	for t, buckets := range ships {
+		out[t] = append([]HPBucket(nil), buckets...)
+	}
+	return out
+}
+
+func TestMergeStacksDecodedBio(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	hp := ShipBlueprints[Fighter].HP
+	dst := splitTestStack(map[ShipType][]HPBucket{Fighter: {{HP: hp, Count: 5}}}, now)
+	src := splitTestStack(map[ShipType][]HPBucket{Fighter: {{HP: hp, Count: 3}}}, now)
+	src.PlayerID, src.MapID = dst.PlayerID, dst.MapID
+
+	// A machine stored with no inbound effects comes back with nil maps.
+	raw, err := bson.Marshal(NewBioMachine(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded BioMachine
+	if err := bson.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	dst.Bio = &decoded
+	src.Bio = NewBioMachine(now)
+	src.Bio.InboundDebuffs["slow"] = &BioDebuffState{ID: "slow", ExpiresAt: now.Add(time.Minute)}
+	src.Bio.InboundBuffs["haste"] = &BioBuffState{ID: "haste", ExpiresAt: now.Add(time.Minute)}
+
+	if err := MergeStacks(dst, src, LoadoutKeepTarget, nil, now); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if dst.Bio.InboundDebuffs["slow"] == nil || dst.Bio.InboundBuffs["haste"] == nil {
+		t.Fatalf("inbound effects not carried over: %+v / %+v", dst.Bio.InboundDebuffs, dst.Bio.InboundBuffs)
+	}
+}
@@ -0,0 +1,274 @@
+package ships
+
+import (
+	"math"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Stealth and detection
+//
+// A stack is cloaked only when every ship type in it is cloaked; one visible
+// hull gives the whole stack away. Cloak sources:
+//   - ActiveCamo toggled on for that ship type
+//   - CloakWhileAnchored on an anchored ship type
+//   - an allied SmokeScreen covering the stack's position
+//   - an invisibility status applied by the bio layer: a triggered node with
+//     Invisible set (StatusInvisible) cloaks the stack until the node ends
+//
+// Attacking breaks cloak for AttackRevealWindow. A cloaked stack can still be
+// seen by observers with CloakDetect at reduced range, or by any stack of a
+// player holding an active Ping mark on it.
+
+const (
+	// VisibilityUnitsPerPoint converts blueprint VisibilityRange points into map units.
+	VisibilityUnitsPerPoint = 100.0
+	// CloakDetectRangePct is the fraction of visibility range within which CloakDetect sees cloaked stacks.
+	CloakDetectRangePct = 0.5
+	SmokeScreenRadius   = 400.0
+	AttackRevealWindow  = 5 * time.Minute
+)
+
+// StealthState is the per-stack runtime for cloak breaks and reveals.
+type StealthState struct {
+	LastAttackAt   time.Time    `bson:"lastAttackAt,omitempty" json:"lastAttackAt,omitempty"`
+	InvisibleUntil time.Time    `bson:"invisibleUntil,omitempty" json:"invisibleUntil,omitempty"`
+	Reveals        []RevealMark `bson:"reveals,omitempty" json:"reveals,omitempty"`
+}
+
+// RevealMark lets every stack of PlayerID see this stack while it lasts,
+// regardless of cloak or range.
+type RevealMark struct {
+	PlayerID      bson.ObjectID `bson:"playerId" json:"playerId"`
+	SourceStackID bson.ObjectID `bson:"sourceStackId" json:"sourceStackId"`
+	ExpiresAt     time.Time     `bson:"expiresAt" json:"expiresAt"`
+}
+
+// SensorProfile caches the per-stack values needed for detection checks so a
+// map-wide pass does not resolve modifiers for every pair.
+type SensorProfile struct {
+	Range       float64 // map units
+	CloakDetect bool
+	Cloaked     bool
+}
+
+func (s *ShipStack) ensureStealth() *StealthState {
+	if s.Stealth == nil {
+		s.Stealth = &StealthState{}
+	}
+	return s.Stealth
+}
+
+// RecordAttack breaks cloak for AttackRevealWindow.
+func (s *ShipStack) RecordAttack(now time.Time) {
+	s.ensureStealth().LastAttackAt = now
+}
+
+// ApplyInvisibility cloaks the whole stack until the given time (StatusInvisible).
+func (s *ShipStack) ApplyInvisibility(until time.Time) {
+	st := s.ensureStealth()
+	if until.After(st.InvisibleUntil) {
+		st.InvisibleUntil = until
+	}
+}
+
+// ApplyPingReveal marks this stack for the pinging player for Ping's duration.
+func (s *ShipStack) ApplyPingReveal(byPlayer, byStack bson.ObjectID, now time.Time) {
+	st := s.ensureStealth()
+	expires := now.Add(time.Duration(AbilitiesCatalog[AbilityPing].DurationSeconds) * time.Second)
+	for i := range st.Reveals {
+		if st.Reveals[i].PlayerID == byPlayer {
+			if expires.After(st.Reveals[i].ExpiresAt) {
+				st.Reveals[i].ExpiresAt = expires
+				st.Reveals[i].SourceStackID = byStack
+			}
+			return
+		}
+	}
+	st.Reveals = append(st.Reveals, RevealMark{PlayerID: byPlayer, SourceStackID: byStack, ExpiresAt: expires})
+}
+
+// IsRevealedTo reports whether player holds an active reveal mark on this stack.
+func (s *ShipStack) IsRevealedTo(player bson.ObjectID, now time.Time) bool {
+	if s.Stealth == nil {
+		return false
+	}
+	for _, r := range s.Stealth.Reveals {
+		if r.PlayerID == player && now.Before(r.ExpiresAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// PruneReveals drops expired reveal marks.
+func (s *ShipStack) PruneReveals(now time.Time) {
+	if s.Stealth == nil {
+		return
+	}
+	kept := s.Stealth.Reveals[:0]
+	for _, r := range s.Stealth.Reveals {
+		if now.Before(r.ExpiresAt) {
+			kept = append(kept, r)
+		}
+	}
+	s.Stealth.Reveals = kept
+}
+
+// IsCloaked reports whether the stack is hidden from ordinary sensors. nearby
+// should contain the map's stacks so allied smoke screens can be found; it may
+// include the stack itself.
+func (s *ShipStack) IsCloaked(nearby []*ShipStack, now time.Time) bool {
+	if s.Stealth != nil && !s.Stealth.LastAttackAt.IsZero() && now.Sub(s.Stealth.LastAttackAt) < AttackRevealWindow {
+		return false
+	}
+	if s.Stealth != nil && now.Before(s.Stealth.InvisibleUntil) {
+		return true
+	}
+	smoked := s.ActiveAbility(AbilitySmokeScreen, now) != nil
+	x, y := s.PositionAt(now)
+	for _, a := range nearby {
+		if smoked {
+			break
+		}
+		if a == nil || a == s || a.PlayerID != s.PlayerID {
+			continue
+		}
+		ax, ay := a.PositionAt(now)
+		if math.Hypot(ax-x, ay-y) <= SmokeScreenRadius && a.ActiveAbility(AbilitySmokeScreen, now) != nil {
+			smoked = true
+		}
+	}
+	if smoked {
+		return true
+	}
+
+	hulls := false
+	for t, n := range countShips(s.Ships) {
+		if n <= 0 {
+			continue
+		}
+		hulls = true
+		if s.abilityActiveFor(t, AbilityActiveCamo, now) {
+			continue
+		}
+		if s.Loadouts[t].Anchored && s.HasAbility(t, AbilityCloakWhileAnchored) {
+			continue
+		}
+		return false
+	}
+	return hulls
+}
+
+// ComputeStackVisibilityRange returns the stack's sensor range in map units:
+// the best effective VisibilityRange among its ship types.
+func ComputeStackVisibilityRange(s *ShipStack, now time.Time) float64 {
+	best := 0
+	for t, n := range countShips(s.Ships) {
+		if n <= 0 {
+			continue
+		}
+		bp, ok := ShipBlueprints[t]
+		if !ok {
+			continue
+		}
+		_, mods := ComputeStackModifiers(s, t, 0, now, false, "")
+		if v := ApplyStatModsToShip(bp, mods).VisibilityRange; v > best {
+			best = v
+		}
+	}
+	return float64(best) * VisibilityUnitsPerPoint
+}
+
+// HasCloakDetect reports whether any ship type in the stack resolves CloakDetect.
+func HasCloakDetect(s *ShipStack, now time.Time) bool {
+	for t, n := range countShips(s.Ships) {
+		if n <= 0 {
+			continue
+		}
+		if _, mods := ComputeStackModifiers(s, t, 0, now, false, ""); mods.CloakDetect {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildSensorProfiles resolves a SensorProfile for every stack.
+func BuildSensorProfiles(stacks []*ShipStack, now time.Time) map[bson.ObjectID]SensorProfile {
+	out := make(map[bson.ObjectID]SensorProfile, len(stacks))
+	for _, s := range stacks {
+		out[s.ID] = SensorProfile{
+			Range:       ComputeStackVisibilityRange(s, now),
+			CloakDetect: HasCloakDetect(s, now),
+			Cloaked:     s.IsCloaked(stacks, now),
+		}
+	}
+	return out
+}
+
+// CanDetect reports whether observer sees target given both profiles.
+// Stacks of the same player always see each other.
+func CanDetect(observer *ShipStack, op SensorProfile, target *ShipStack, tp SensorProfile, now time.Time) bool {
+	if observer.PlayerID == target.PlayerID {
+		return true
+	}
+	if target.IsRevealedTo(observer.PlayerID, now) {
+		return true
+	}
+	ox, oy := observer.PositionAt(now)
+	tx, ty := target.PositionAt(now)
+	dist := math.Hypot(ox-tx, oy-ty)
+	if !tp.Cloaked {
+		return dist <= op.Range
+	}
+	return op.CloakDetect && dist <= op.Range*CloakDetectRangePct
+}
+
+// DetectStacks returns the IDs of stacks (other than the observer) that the
+// observer can see. profiles must cover the observer and all candidates.
+func DetectStacks(observer *ShipStack, candidates []*ShipStack, profiles map[bson.ObjectID]SensorProfile, now time.Time) []bson.ObjectID {
+	var out []bson.ObjectID
+	op := profiles[observer.ID]
+	for _, t := range candidates {
+		if t == nil || t.ID == observer.ID {
+			continue
+		}
+		if CanDetect(observer, op, t, profiles[t.ID], now) {
+			out = append(out, t.ID)
+		}
+	}
+	return out
+}
+
+// ComputePlayerStackVisibility returns, for each player owning a stack, the set
+// of stack IDs that player can see.
+func ComputePlayerStackVisibility(stacks []*ShipStack, now time.Time) map[bson.ObjectID]map[bson.ObjectID]bool {
+	profiles := BuildSensorProfiles(stacks, now)
+	out := make(map[bson.ObjectID]map[bson.ObjectID]bool)
+	for _, obs := range stacks {
+		seen := out[obs.PlayerID]
+		if seen == nil {
+			seen = make(map[bson.ObjectID]bool)
+			out[obs.PlayerID] = seen
+		}
+		seen[obs.ID] = true
+		for _, id := range DetectStacks(obs, stacks, profiles, now) {
+			seen[id] = true
+		}
+	}
+	return out
+}
+
+// abilityActiveFor is ActiveAbility narrowed to a single ship type.
+func (s *ShipStack) abilityActiveFor(t ShipType, id AbilityID, now time.Time) bool {
+	if s.Ability == nil {
+		return false
+	}
+	for _, st := range *s.Ability {
+		if st.Ability == string(id) && st.ShipType == t && st.IsActive && (st.EndTime.IsZero() || now.Before(st.EndTime)) {
+			return true
+		}
+	}
+	return false
+}
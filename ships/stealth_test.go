@@ -0,0 +1,156 @@
+package ships
+
+import (
+	"testing"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func stealthTestStack(player bson.ObjectID, x, y float64, types ...ShipType) *ShipStack {
+	s := &ShipStack{ID: bson.NewObjectID(), PlayerID: player, PositionX: x, PositionY: y, Ships: map[ShipType][]HPBucket{}}
+	for _, t := range types {
+		s.Ships[t] = []HPBucket{{HP: ShipBlueprints[t].HP, Count: 2}}
+	}
+	return s
+}
+
+func TestIsCloaked(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	player := bson.NewObjectID()
+
+	ghost := stealthTestStack(player, 0, 0, Ghost)
+	if ghost.IsCloaked(nil, now) {
+		t.Fatal("ghost cloaked before ActiveCamo")
+	}
+	if _, err := ghost.ActivateAbility(Ghost, AbilityActiveCamo, now); err != nil {
+		t.Fatal(err)
+	}
+	if !ghost.IsCloaked(nil, now) {
+		t.Fatal("ActiveCamo did not cloak a pure ghost stack")
+	}
+	ghost.RecordAttack(now)
+	if ghost.IsCloaked(nil, now.Add(time.Minute)) {
+		t.Fatal("cloak held right after attacking")
+	}
+	if !ghost.IsCloaked(nil, now.Add(AttackRevealWindow)) {
+		t.Fatal("cloak did not return after the reveal window")
+	}
+
+	mixed := stealthTestStack(player, 0, 0, Ghost, Fighter)
+	if _, err := mixed.ActivateAbility(Ghost, AbilityActiveCamo, now); err != nil {
+		t.Fatal(err)
+	}
+	if mixed.IsCloaked(nil, now) {
+		t.Fatal("a visible fighter hull did not give the stack away")
+	}
+	if mixed.Loadouts != nil {
+		t.Fatalf("IsCloaked initialised loadouts: %+v", mixed.Loadouts)
+	}
+
+	drone := stealthTestStack(player, 0, 0, Drone)
+	drone.SetAnchored(Drone, true)
+	if !drone.IsCloaked(nil, now) {
+		t.Fatal("anchored drone with CloakWhileAnchored is not cloaked")
+	}
+}
+
+func TestSmokeScreenFollowsMovingAlly(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	player := bson.NewObjectID()
+	smoker := stealthTestStack(player, 0, 0, Ghost)
+	if _, err := smoker.ActivateAbility(Ghost, AbilitySmokeScreen, now); err != nil {
+		t.Fatal(err)
+	}
+	target := stealthTestStack(player, SmokeScreenRadius+80, 0, Fighter)
+	if _, err := target.QueueMove(0, 0, bson.NilObjectID, "coordinate", now); err != nil {
+		t.Fatal(err)
+	}
+	// The stored position never changes mid-leg; only PositionAt tracks the
+	// stack into the smoke before it expires.
+	mid := now.Add(19 * time.Second)
+	if x, _ := target.PositionAt(mid); x > SmokeScreenRadius || smoker.ActiveAbility(AbilitySmokeScreen, mid) == nil {
+		t.Fatalf("setup: stack at %.0f when the smoke is checked", x)
+	}
+	nearby := []*ShipStack{smoker, target}
+	if target.IsCloaked(nearby, now) {
+		t.Fatal("stack cloaked outside the smoke screen")
+	}
+	if !target.IsCloaked(nearby, mid) {
+		t.Fatal("stack flying into the smoke screen is not cloaked at its interpolated position")
+	}
+}
+
+func TestCanDetect(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	observer := stealthTestStack(bson.NewObjectID(), 0, 0, Scout)
+	target := stealthTestStack(bson.NewObjectID(), 400, 0, Ghost)
+
+	cases := []struct {
+		name string
+		op   SensorProfile
+		tp   SensorProfile
+		dist float64
+		want bool
+	}{
+		{"visible in range", SensorProfile{Range: 1000}, SensorProfile{}, 400, true},
+		{"visible out of range", SensorProfile{Range: 1000}, SensorProfile{}, 1200, false},
+		{"cloaked without detector", SensorProfile{Range: 1000}, SensorProfile{Cloaked: true}, 400, false},
+		{"cloaked within detect range", SensorProfile{Range: 1000, CloakDetect: true}, SensorProfile{Cloaked: true}, 400, true},
+		{"cloaked beyond detect range", SensorProfile{Range: 1000, CloakDetect: true}, SensorProfile{Cloaked: true}, 600, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			target.PositionX = tc.dist
+			if got := CanDetect(observer, tc.op, target, tc.tp, now); got != tc.want {
+				t.Fatalf("CanDetect = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	target.PositionX = 5000
+	target.ApplyPingReveal(observer.PlayerID, observer.ID, now)
+	if !CanDetect(observer, SensorProfile{Range: 1000}, target, SensorProfile{Cloaked: true}, now) {
+		t.Fatal("ping reveal did not expose the stack")
+	}
+}
+
+func TestCanDetectMovingStacks(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	observer := stealthTestStack(bson.NewObjectID(), 0, 0, Scout)
+	target := stealthTestStack(bson.NewObjectID(), 1500, 0, Fighter)
+	if _, err := target.QueueMove(0, 0, bson.NilObjectID, "coordinate", now); err != nil {
+		t.Fatal(err)
+	}
+	op := SensorProfile{Range: 1000}
+	if CanDetect(observer, op, target, SensorProfile{}, now) {
+		t.Fatal("stack detected before it came into range")
+	}
+	later := now.Add(3 * time.Minute)
+	if x, _ := target.PositionAt(later); x > op.Range || target.PositionX != 1500 {
+		t.Fatalf("setup: stack at %.0f (stored %.0f)", x, target.PositionX)
+	}
+	if !CanDetect(observer, op, target, SensorProfile{}, later) {
+		t.Fatal("stack flying into range is not detected at its interpolated position")
+	}
+}
+
+func TestBioInvisibility(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := stealthTestStack(bson.NewObjectID(), 0, 0, Fighter)
+	s.EnsureBio(now).Node("fade").ForAllShips().
+		WithTriggered(StatMods{}, time.Minute, time.Hour).
+		WithInvisibility().
+		WithTrigger("on_ambush")
+
+	if n := s.Bio.OnTrigger("on_ambush", now); n != 1 {
+		t.Fatalf("%d nodes triggered, want 1", n)
+	}
+	s.TickBio(now.Add(time.Second))
+	if !s.IsCloaked(nil, now.Add(time.Second)) {
+		t.Fatal("triggered invisibility did not cloak the stack")
+	}
+	if s.IsCloaked(nil, now.Add(time.Minute)) {
+		t.Fatal("invisibility outlasted the node")
+	}
+}
@@ -0,0 +1,423 @@
+package ships
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Warp travel
+//
+// A warp order is stored as a MovementState with Type "warp" and a WarpLeg
+// describing the charge window and scatter. The leg is laid out so that plain
+// linear interpolation works: the stack sits at StartX/StartY until StartTime
+// (end of charge), then moves in a straight line to TargetX/TargetY (the
+// scattered arrival point) at EndTime.
+//
+// Everything here is deterministic: scatter is derived from the stack ID and
+// the order time, and interdiction resistance shrinks the effective field
+// radius instead of rolling a chance.
+//
+// A WarpStabilizer aura helps at both ends of a jump, and each effect is
+// checked where it acts. Scatter is about where the stack lands, so PlanWarp
+// looks for an aura over the destination. Interdiction resistance is about
+// holding the jump together from the moment the charge starts, so
+// ResolveInterdiction looks for an aura over the leg's origin.
+
+const (
+	MovementTypeTravel = "traveling"
+	MovementTypeWarp   = "warp"
+
+	// SublightUnitsPerSecond is the map distance covered per second per point of Speed.
+	SublightUnitsPerSecond = 1.0
+
+	WarpBaseChargeSeconds = 90
+	WarpMinChargeSeconds  = 10
+	WarpSpeedMultiplier   = 8.0  // warp speed relative to the stack's sublight speed
+	WarpBaseScatterPct    = 0.05 // scatter radius as a fraction of jump distance
+
+	InterdictionRadius    = 600.0
+	InterdictionMaxResist = 0.75 // resist can shrink a field by at most 75%
+
+	WarpStabilizerRadius     = 500.0
+	WarpStabilizerScatterPct = -0.50
+	WarpStabilizerResistPct  = 0.25
+)
+
+type WarpStage string
+
+const (
+	WarpStageCharging    WarpStage = "charging"
+	WarpStageJumping     WarpStage = "jumping"
+	WarpStageArrived     WarpStage = "arrived"
+	WarpStageInterdicted WarpStage = "interdicted"
+)
+
+var (
+	ErrWarpAnchored     = errors.New("warp unavailable: stack has anchored ships")
+	ErrWarpNoLightSpeed = errors.New("warp unavailable: not every ship type has LightSpeed")
+	ErrWarpInCombat     = errors.New("warp unavailable: stack is in combat")
+	ErrStackImmobile    = errors.New("stack has no ships able to move")
+)
+
+// WarpLeg holds the warp-specific part of a MovementState.
+type WarpLeg struct {
+	Stage           WarpStage     `bson:"stage" json:"stage"`
+	ChargeStartedAt time.Time     `bson:"chargeStartedAt" json:"chargeStartedAt"`
+	ChargeEndsAt    time.Time     `bson:"chargeEndsAt" json:"chargeEndsAt"`
+	DestinationX    float64       `bson:"destinationX" json:"destinationX"` // Requested destination (before scatter)
+	DestinationY    float64       `bson:"destinationY" json:"destinationY"`
+	ScatterRadius   float64       `bson:"scatterRadius" json:"scatterRadius"`
+	InterdictedBy   bson.ObjectID `bson:"interdictedBy,omitempty" json:"interdictedBy,omitempty"`
+	InterdictedAt   time.Time     `bson:"interdictedAt,omitempty" json:"interdictedAt,omitempty"`
+}
+
+// WarpProfile is the stack-wide aggregate of warp modifiers. A stack warps as
+// one unit, so the weakest ship type sets each value.
+type WarpProfile struct {
+	ChargePct  float64
+	ScatterPct float64
+	ResistPct  float64
+}
+
+// InterdictionField is an area where enemy warps are blocked.
+type InterdictionField struct {
+	SourceStackID bson.ObjectID
+	PlayerID      bson.ObjectID
+	X, Y          float64
+	Radius        float64
+	ActiveFrom    time.Time
+	ExpiresAt     time.Time
+}
+
+// WarpContext carries the nearby map state that affects a warp.
+type WarpContext struct {
+	Stabilizers   []*ShipStack        // allied stacks that may project a WarpStabilizer aura
+	Interdictions []InterdictionField // active interdiction fields on the map
+}
+
+// CanWarp reports whether the stack may enter warp right now.
+func CanWarp(stack *ShipStack) error {
+	if stack.Battle != nil && stack.Battle.IsInCombat {
+		return ErrWarpInCombat
+	}
+	if stack.IsAnchored() {
+		return ErrWarpAnchored
+	}
+	for t, n := range countShips(stack.Ships) {
+		if n > 0 && !stack.HasAbility(t, AbilityLightSpeed) {
+			return ErrWarpNoLightSpeed
+		}
+	}
+	return nil
+}
+
+// ComputeStackWarpProfile aggregates warp mods across every ship type present.
+func ComputeStackWarpProfile(stack *ShipStack, now time.Time) WarpProfile {
+	var p WarpProfile
+	first := true
+	for t, n := range countShips(stack.Ships) {
+		if n <= 0 {
+			continue
+		}
+		_, mods := ComputeStackModifiers(stack, t, 0, now, false, "")
+		if first {
+			p = WarpProfile{ChargePct: mods.WarpChargePct, ScatterPct: mods.WarpScatterPct, ResistPct: mods.InterdictionResistPct}
+			first = false
+			continue
+		}
+		p.ChargePct = math.Max(p.ChargePct, mods.WarpChargePct)
+		p.ScatterPct = math.Max(p.ScatterPct, mods.WarpScatterPct)
+		p.ResistPct = math.Min(p.ResistPct, mods.InterdictionResistPct)
+	}
+	return p
+}
+
+// PlanSublight builds a straight-line sublight leg to (x, y) starting at the
+// stack's current position.
+func PlanSublight(stack *ShipStack, x, y float64, now time.Time) (*MovementState, error) {
+	if stack.IsAnchored() {
+		return nil, ErrStackAnchored
+	}
+	speed := stack.GetEffectiveStackSpeed()
+	if speed <= 0 {
+		return nil, ErrStackImmobile
+	}
+	dist := math.Hypot(x-stack.PositionX, y-stack.PositionY)
+	return &MovementState{
+		Type:        MovementTypeTravel,
+		State:       MovementTypeTravel,
+		TargetType:  "coordinate",
+		StartX:      stack.PositionX,
+		StartY:      stack.PositionY,
+		TargetX:     x,
+		TargetY:     y,
+		Speed:       speed,
+		StartTime:   now,
+		EndTime:     now.Add(travelDuration(dist, float64(speed))),
+		LastUpdated: now,
+	}, nil
+}
+
+// PlanWarp builds a warp leg to (x, y). Stacks containing ship types without
+// LightSpeed fall back to a sublight leg; anchored or engaged stacks cannot
+// leave at all.
+func PlanWarp(stack *ShipStack, x, y float64, ctx WarpContext, now time.Time) (*MovementState, error) {
+	if err := CanWarp(stack); err != nil {
+		if errors.Is(err, ErrWarpNoLightSpeed) {
+			return PlanSublight(stack, x, y, now)
+		}
+		return nil, err
+	}
+	speed := stack.GetEffectiveStackSpeed()
+	if speed <= 0 {
+		return nil, ErrStackImmobile
+	}
+
+	profile := ComputeStackWarpProfile(stack, now)
+
+	charge := float64(WarpBaseChargeSeconds) * (1 + profile.ChargePct)
+	charge = math.Max(charge, WarpMinChargeSeconds)
+	chargeEnds := now.Add(time.Duration(charge * float64(time.Second)))
+
+	scatterPct := profile.ScatterPct
+	// Scatter is steadied by an aura at the destination (see the package doc).
+	if stabilizedNear(stack, ctx.Stabilizers, x, y) {
+		scatterPct += WarpStabilizerScatterPct
+	}
+	dist := math.Hypot(x-stack.PositionX, y-stack.PositionY)
+	scatter := math.Max(0, dist*WarpBaseScatterPct*(1+scatterPct))
+
+	ox, oy := warpScatterOffset(stack.ID, now, scatter)
+	ax, ay := x+ox, y+oy
+	jump := math.Hypot(ax-stack.PositionX, ay-stack.PositionY)
+
+	return &MovementState{
+		Type:        MovementTypeWarp,
+		State:       string(WarpStageCharging),
+		TargetType:  "coordinate",
+		StartX:      stack.PositionX,
+		StartY:      stack.PositionY,
+		TargetX:     ax,
+		TargetY:     ay,
+		Speed:       int(math.Round(float64(speed) * WarpSpeedMultiplier)),
+		StartTime:   chargeEnds,
+		EndTime:     chargeEnds.Add(travelDuration(jump, float64(speed)*WarpSpeedMultiplier)),
+		LastUpdated: now,
+		Warp: &WarpLeg{
+			Stage:           WarpStageCharging,
+			ChargeStartedAt: now,
+			ChargeEndsAt:    chargeEnds,
+			DestinationX:    x,
+			DestinationY:    y,
+			ScatterRadius:   scatter,
+		},
+	}, nil
+}
+
+// UpdateWarpStage advances a warp leg's stage to match the clock. Interdicted
+// legs are final.
+func (m *MovementState) UpdateWarpStage(now time.Time) {
+	if m.Warp == nil || m.Warp.Stage == WarpStageInterdicted {
+		return
+	}
+	switch {
+	case now.Before(m.Warp.ChargeEndsAt):
+		m.Warp.Stage = WarpStageCharging
+	case now.Before(m.EndTime):
+		m.Warp.Stage = WarpStageJumping
+	default:
+		m.Warp.Stage = WarpStageArrived
+	}
+	m.State = string(m.Warp.Stage)
+	m.LastUpdated = now
+}
+
+// InterdictionFieldsFrom collects fields projected by stacks with an active
+// InterdictorPulse.
+func InterdictionFieldsFrom(stacks []*ShipStack, now time.Time) []InterdictionField {
+	var out []InterdictionField
+	for _, s := range stacks {
+		st := s.ActiveAbility(AbilityInterdictorPulse, now)
+		if st == nil {
+			continue
+		}
+		out = append(out, InterdictionField{
+			SourceStackID: s.ID,
+			PlayerID:      s.PlayerID,
+			X:             s.PositionX,
+			Y:             s.PositionY,
+			Radius:        InterdictionRadius,
+			ActiveFrom:    st.StartTime,
+			ExpiresAt:     st.EndTime,
+		})
+	}
+	return out
+}
+
+// ResolveInterdiction checks a warp leg against enemy interdiction fields up
+// to now. A stack caught while charging has its warp cancelled in place; a
+// stack caught mid-jump is pulled out at the point it crossed the field edge.
+// Returns true if the leg was interdicted by this call.
+func ResolveInterdiction(stack *ShipStack, leg *MovementState, ctx WarpContext, now time.Time) bool {
+	if leg == nil || leg.Warp == nil || leg.Warp.Stage == WarpStageInterdicted || leg.Warp.Stage == WarpStageArrived {
+		return false
+	}
+	resist := ComputeStackWarpProfile(stack, now).ResistPct
+	// Resistance comes from an aura at the origin (see the package doc).
+	if stabilizedNear(stack, ctx.Stabilizers, leg.StartX, leg.StartY) {
+		resist += WarpStabilizerResistPct
+	}
+	shrink := 1 - clamp(resist, 0, InterdictionMaxResist)
+
+	var hitAt time.Time
+	var hitBy bson.ObjectID
+	for _, f := range ctx.Interdictions {
+		if f.PlayerID == stack.PlayerID {
+			continue
+		}
+		r := f.Radius * shrink
+		if t, ok := fieldHitTime(leg, f, r, now); ok && (hitAt.IsZero() || t.Before(hitAt)) {
+			hitAt, hitBy = t, f.SourceStackID
+		}
+	}
+	if hitAt.IsZero() {
+		return false
+	}
+
+	x, y := legPointAt(leg, hitAt)
+	leg.TargetX, leg.TargetY = x, y
+	if hitAt.Before(leg.StartTime) {
+		leg.StartTime = hitAt
+	}
+	leg.EndTime = hitAt
+	leg.State = string(WarpStageInterdicted)
+	leg.Warp.Stage = WarpStageInterdicted
+	leg.Warp.InterdictedBy = hitBy
+	leg.Warp.InterdictedAt = hitAt
+	leg.LastUpdated = now
+	return true
+}
+
+// fieldHitTime returns the earliest moment in [ChargeStartedAt, now] at which
+// the leg is inside the field while the field is active.
+func fieldHitTime(leg *MovementState, f InterdictionField, r float64, now time.Time) (time.Time, bool) {
+	from := maxTime(leg.Warp.ChargeStartedAt, f.ActiveFrom)
+	until := minTime(now, leg.EndTime)
+	if !f.ExpiresAt.IsZero() {
+		until = minTime(until, f.ExpiresAt)
+	}
+	if until.Before(from) {
+		return time.Time{}, false
+	}
+
+	// Charging: the stack sits at its origin.
+	if from.Before(leg.StartTime) && math.Hypot(leg.StartX-f.X, leg.StartY-f.Y) <= r {
+		return from, true
+	}
+
+	// Jumping: intersect the segment with the circle, then clip to the window.
+	dx, dy := leg.TargetX-leg.StartX, leg.TargetY-leg.StartY
+	fx, fy := leg.StartX-f.X, leg.StartY-f.Y
+	a := dx*dx + dy*dy
+	c := fx*fx + fy*fy - r*r
+	if a == 0 {
+		return time.Time{}, false
+	}
+	b := 2 * (fx*dx + fy*dy)
+	disc := b*b - 4*a*c
+	if disc < 0 {
+		return time.Time{}, false
+	}
+	sq := math.Sqrt(disc)
+	u1, u2 := (-b-sq)/(2*a), (-b+sq)/(2*a)
+	if u2 < 0 || u1 > 1 {
+		return time.Time{}, false
+	}
+	enter := legTimeAt(leg, math.Max(u1, 0))
+	exit := legTimeAt(leg, math.Min(u2, 1))
+	start := maxTime(enter, from)
+	if start.After(exit) || start.After(until) {
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+func legTimeAt(leg *MovementState, u float64) time.Time {
+	span := leg.EndTime.Sub(leg.StartTime)
+	return leg.StartTime.Add(time.Duration(u * float64(span)))
+}
+
+func legPointAt(leg *MovementState, t time.Time) (float64, float64) {
+	if !t.After(leg.StartTime) {
+		return leg.StartX, leg.StartY
+	}
+	span := leg.EndTime.Sub(leg.StartTime)
+	if span <= 0 || !t.Before(leg.EndTime) {
+		return leg.TargetX, leg.TargetY
+	}
+	u := float64(t.Sub(leg.StartTime)) / float64(span)
+	return leg.StartX + (leg.TargetX-leg.StartX)*u, leg.StartY + (leg.TargetY-leg.StartY)*u
+}
+
+// stabilizedNear reports whether an allied stabilizer aura covers (x, y).
+func stabilizedNear(stack *ShipStack, stabilizers []*ShipStack, x, y float64) bool {
+	for _, s := range stabilizers {
+		if s == nil || s.PlayerID != stack.PlayerID {
+			continue
+		}
+		if math.Hypot(s.PositionX-x, s.PositionY-y) > WarpStabilizerRadius {
+			continue
+		}
+		for t, n := range countShips(s.Ships) {
+			if n > 0 && s.HasAbility(t, AbilityWarpStabilizer) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// warpScatterOffset derives a stable offset inside a disc of the given radius
+// from the stack ID and order time, so replays land in the same spot.
+func warpScatterOffset(id bson.ObjectID, at time.Time, radius float64) (float64, float64) {
+	if radius <= 0 {
+		return 0, 0
+	}
+	h := fnv.New64a()
+	h.Write(id[:])
+	var ts [8]byte
+	n := uint64(at.UnixNano())
+	for i := range ts {
+		ts[i] = byte(n >> (8 * i))
+	}
+	h.Write(ts[:])
+	sum := h.Sum64()
+	angle := float64(uint32(sum)) / float64(math.MaxUint32) * 2 * math.Pi
+	dist := math.Sqrt(float64(uint32(sum>>32))/float64(math.MaxUint32)) * radius
+	return math.Cos(angle) * dist, math.Sin(angle) * dist
+}
+
+func travelDuration(dist, speed float64) time.Duration {
+	if speed <= 0 || dist <= 0 {
+		return 0
+	}
+	return time.Duration(dist / (speed * SublightUnitsPerSecond) * float64(time.Second))
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
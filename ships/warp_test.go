@@ -0,0 +1,135 @@
+package ships
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func warpTestStack(t ShipType) *ShipStack {
+	s := &ShipStack{
+		ID:       bson.NewObjectID(),
+		PlayerID: bson.NewObjectID(),
+		Ships:    map[ShipType][]HPBucket{t: {{HP: ShipBlueprints[t].HP, Count: 2}}},
+	}
+	s.EnsureFormationInitialized(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	return s
+}
+
+func TestPlanWarpChargeAndScatter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := warpTestStack(Cruiser)
+
+	leg, err := PlanWarp(s, 10000, 0, WarpContext{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	charge := math.Max(WarpBaseChargeSeconds*(1+ComputeStackWarpProfile(s, now).ChargePct), WarpMinChargeSeconds)
+	if got := leg.StartTime.Sub(now); got != time.Duration(charge*float64(time.Second)) || !leg.Warp.ChargeEndsAt.Equal(leg.StartTime) {
+		t.Fatalf("charge lasted %v, want %.0fs", got, charge)
+	}
+	if off := math.Hypot(leg.TargetX-10000, leg.TargetY); off > leg.Warp.ScatterRadius || leg.Warp.ScatterRadius <= 0 {
+		t.Fatalf("landed %.1f from the destination, scatter radius %.1f", off, leg.Warp.ScatterRadius)
+	}
+
+	tests := []struct {
+		name string
+		id   bson.ObjectID
+		at   time.Time
+		same bool
+	}{
+		{"same stack and time", s.ID, now, true},
+		{"later order", s.ID, now.Add(time.Second), false},
+		{"other stack", bson.NewObjectID(), now, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			other := *s
+			other.ID = tc.id
+			again, err := PlanWarp(&other, 10000, 0, WarpContext{}, tc.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if same := again.TargetX == leg.TargetX && again.TargetY == leg.TargetY; same != tc.same {
+				t.Fatalf("landing (%.2f, %.2f) vs (%.2f, %.2f): same = %v, want %v", again.TargetX, again.TargetY, leg.TargetX, leg.TargetY, same, tc.same)
+			}
+		})
+	}
+
+	if leg, err := PlanWarp(warpTestStack(Fighter), 10000, 0, WarpContext{}, now); err != nil || leg.Type != MovementTypeTravel {
+		t.Fatalf("stack without LightSpeed: leg %+v, err %v", leg, err)
+	}
+}
+
+func TestResolveInterdiction(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := warpTestStack(Cruiser)
+	plan := func() *MovementState {
+		leg, err := PlanWarp(s, 10000, 0, WarpContext{}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leg
+	}
+	mid := plan()
+	midX, midY := (mid.StartX+mid.TargetX)/2, (mid.StartY+mid.TargetY)/2
+	enemy, ally := bson.NewObjectID(), s.PlayerID
+
+	tests := []struct {
+		name    string
+		field   InterdictionField
+		at      time.Time
+		caught  bool
+		atStart bool // caught while charging, at the origin
+	}{
+		{"enemy field on the origin", InterdictionField{PlayerID: enemy, X: 0, Y: 0, Radius: 100}, now.Add(time.Second), true, true},
+		{"allied field is ignored", InterdictionField{PlayerID: ally, X: 0, Y: 0, Radius: 100}, mid.EndTime, false, false},
+		{"enemy field mid-jump", InterdictionField{PlayerID: enemy, X: midX, Y: midY, Radius: 100}, mid.EndTime, true, false},
+		{"field not reached yet", InterdictionField{PlayerID: enemy, X: midX, Y: midY, Radius: 100}, mid.StartTime, false, false},
+		{"field expired before the stack passed", InterdictionField{PlayerID: enemy, X: midX, Y: midY, Radius: 100, ExpiresAt: mid.StartTime}, mid.EndTime, false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			leg := plan()
+			tc.field.ActiveFrom = now
+			caught := ResolveInterdiction(s, leg, WarpContext{Interdictions: []InterdictionField{tc.field}}, tc.at)
+			if caught != tc.caught {
+				t.Fatalf("caught = %v, want %v", caught, tc.caught)
+			}
+			if !caught {
+				return
+			}
+			if leg.Warp.Stage != WarpStageInterdicted || !leg.EndTime.Equal(leg.Warp.InterdictedAt) {
+				t.Fatalf("interdicted leg: %+v", leg.Warp)
+			}
+			if tc.atStart {
+				if leg.TargetX != leg.StartX || !leg.Warp.InterdictedAt.Equal(now) {
+					t.Fatalf("charging stack pulled to (%.1f, %.1f) at %v", leg.TargetX, leg.TargetY, leg.Warp.InterdictedAt)
+				}
+				return
+			}
+			if d := math.Hypot(leg.TargetX-midX, leg.TargetY-midY); math.Abs(d-tc.field.Radius) > 1 {
+				t.Fatalf("stopped %.1f from the field centre, want its edge at %.0f", d, tc.field.Radius)
+			}
+		})
+	}
+}
+
+func TestInterdictorPulseProjectsField(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := warpTestStack(Destroyer)
+	d.PositionX, d.PositionY = 300, 400
+	if fields := InterdictionFieldsFrom([]*ShipStack{d}, now); len(fields) != 0 {
+		t.Fatalf("inactive pulse projected %+v", fields)
+	}
+	st, err := d.ActivateAbility(Destroyer, AbilityInterdictorPulse, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := InterdictionFieldsFrom([]*ShipStack{d}, now)
+	if len(fields) != 1 || fields[0].X != 300 || fields[0].Radius != InterdictionRadius || !fields[0].ExpiresAt.Equal(st.EndTime) {
+		t.Fatalf("fields = %+v", fields)
+	}
+}
@@ -0,0 +1,252 @@
+// Package spatial provides a uniform-grid index for "what is near (x, y)"
+// queries over map entities (stacks, systems, asteroids, nebulas).
+package spatial
+
+import (
+	"bytes"
+	"math"
+	"sort"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type Kind string
+
+const (
+	KindStack    Kind = "stack"
+	KindSystem   Kind = "system"
+	KindAsteroid Kind = "asteroid"
+	KindNebula   Kind = "nebula"
+	KindPhantom  Kind = "phantom"
+)
+
+// DefaultCellSize suits attack/visibility ranges of a few hundred units.
+const DefaultCellSize = 500.0
+
+// Entry is an indexed entity. Radius is the entity's own extent (e.g.
+// CollisionRadius); radius queries match when the discs overlap.
+type Entry struct {
+	ID     bson.ObjectID
+	Kind   Kind
+	X, Y   float64
+	Radius float64
+}
+
+// Hit is a query result with the center distance from the query point.
+type Hit struct {
+	Entry
+	Distance float64
+}
+
+type cellKey struct{ cx, cy int }
+
+// Grid is a uniform grid over a single map. Entries are bucketed by center;
+// queries widen their search by the largest radius seen so big orbitables are
+// still found from neighbouring cells.
+type Grid struct {
+	cellSize  float64
+	cells     map[cellKey]map[bson.ObjectID]struct{}
+	entries   map[bson.ObjectID]Entry
+	maxRadius float64
+	minCell   cellKey
+	maxCell   cellKey
+}
+
+// NewGrid creates an empty grid. cellSize <= 0 uses DefaultCellSize.
+func NewGrid(cellSize float64) *Grid {
+	if cellSize <= 0 {
+		cellSize = DefaultCellSize
+	}
+	return &Grid{
+		cellSize: cellSize,
+		cells:    make(map[cellKey]map[bson.ObjectID]struct{}),
+		entries:  make(map[bson.ObjectID]Entry),
+	}
+}
+
+// Len returns the number of indexed entries.
+func (g *Grid) Len() int { return len(g.entries) }
+
+// Get returns the entry for id.
+func (g *Grid) Get(id bson.ObjectID) (Entry, bool) {
+	e, ok := g.entries[id]
+	return e, ok
+}
+
+// Insert adds or replaces an entry.
+func (g *Grid) Insert(e Entry) {
+	if _, ok := g.entries[e.ID]; ok {
+		g.Remove(e.ID)
+	}
+	k := g.key(e.X, e.Y)
+	cell := g.cells[k]
+	if cell == nil {
+		cell = make(map[bson.ObjectID]struct{})
+		g.cells[k] = cell
+	}
+	cell[e.ID] = struct{}{}
+	g.entries[e.ID] = e
+	if e.Radius > g.maxRadius {
+		g.maxRadius = e.Radius
+	}
+	if len(g.entries) == 1 {
+		g.minCell, g.maxCell = k, k
+	} else {
+		g.minCell = cellKey{min(g.minCell.cx, k.cx), min(g.minCell.cy, k.cy)}
+		g.maxCell = cellKey{max(g.maxCell.cx, k.cx), max(g.maxCell.cy, k.cy)}
+	}
+}
+
+// Move updates an entry's position. Returns false if id is unknown.
+func (g *Grid) Move(id bson.ObjectID, x, y float64) bool {
+	e, ok := g.entries[id]
+	if !ok {
+		return false
+	}
+	from, to := g.key(e.X, e.Y), g.key(x, y)
+	e.X, e.Y = x, y
+	if from != to {
+		g.removeFromCell(from, id)
+		cell := g.cells[to]
+		if cell == nil {
+			cell = make(map[bson.ObjectID]struct{})
+			g.cells[to] = cell
+		}
+		cell[id] = struct{}{}
+		g.minCell = cellKey{min(g.minCell.cx, to.cx), min(g.minCell.cy, to.cy)}
+		g.maxCell = cellKey{max(g.maxCell.cx, to.cx), max(g.maxCell.cy, to.cy)}
+	}
+	g.entries[id] = e
+	return true
+}
+
+// Remove deletes an entry. Returns false if id is unknown.
+func (g *Grid) Remove(id bson.ObjectID) bool {
+	e, ok := g.entries[id]
+	if !ok {
+		return false
+	}
+	g.removeFromCell(g.key(e.X, e.Y), id)
+	delete(g.entries, id)
+	return true
+}
+
+// QueryRadius returns entries whose disc overlaps the circle (x, y, r),
+// nearest first (ties broken by ID). kinds filters by Kind; none means all.
+func (g *Grid) QueryRadius(x, y, r float64, kinds ...Kind) []Hit {
+	reach := r + g.maxRadius
+	lo, hi := g.key(x-reach, y-reach), g.key(x+reach, y+reach)
+	lo = cellKey{max(lo.cx, g.minCell.cx), max(lo.cy, g.minCell.cy)}
+	hi = cellKey{min(hi.cx, g.maxCell.cx), min(hi.cy, g.maxCell.cy)}
+
+	var hits []Hit
+	for cx := lo.cx; cx <= hi.cx; cx++ {
+		for cy := lo.cy; cy <= hi.cy; cy++ {
+			for id := range g.cells[cellKey{cx, cy}] {
+				e := g.entries[id]
+				if !matchKind(e.Kind, kinds) {
+					continue
+				}
+				d := math.Hypot(e.X-x, e.Y-y)
+				if d <= r+e.Radius {
+					hits = append(hits, Hit{Entry: e, Distance: d})
+				}
+			}
+		}
+	}
+	sortHits(hits)
+	return hits
+}
+
+// Nearest returns the entry with the closest center to (x, y).
+func (g *Grid) Nearest(x, y float64, kinds ...Kind) (Hit, bool) {
+	if len(g.entries) == 0 {
+		return Hit{}, false
+	}
+	c := g.key(x, y)
+	maxRing := max(
+		intAbs(c.cx-g.minCell.cx), intAbs(c.cx-g.maxCell.cx),
+		intAbs(c.cy-g.minCell.cy), intAbs(c.cy-g.maxCell.cy),
+	)
+
+	var best Hit
+	found := false
+	for ring := 0; ring <= maxRing; ring++ {
+		// Anything in this ring or beyond is at least (ring-1) cells away.
+		if found && float64(ring-1)*g.cellSize > best.Distance {
+			break
+		}
+		g.forRing(c, ring, func(k cellKey) {
+			for id := range g.cells[k] {
+				e := g.entries[id]
+				if !matchKind(e.Kind, kinds) {
+					continue
+				}
+				d := math.Hypot(e.X-x, e.Y-y)
+				h := Hit{Entry: e, Distance: d}
+				if !found || lessHit(h, best) {
+					best, found = h, true
+				}
+			}
+		})
+	}
+	return best, found
+}
+
+func (g *Grid) forRing(c cellKey, ring int, fn func(cellKey)) {
+	if ring == 0 {
+		fn(c)
+		return
+	}
+	for dx := -ring; dx <= ring; dx++ {
+		fn(cellKey{c.cx + dx, c.cy - ring})
+		fn(cellKey{c.cx + dx, c.cy + ring})
+	}
+	for dy := -ring + 1; dy <= ring-1; dy++ {
+		fn(cellKey{c.cx - ring, c.cy + dy})
+		fn(cellKey{c.cx + ring, c.cy + dy})
+	}
+}
+
+func (g *Grid) key(x, y float64) cellKey {
+	return cellKey{int(math.Floor(x / g.cellSize)), int(math.Floor(y / g.cellSize))}
+}
+
+func (g *Grid) removeFromCell(k cellKey, id bson.ObjectID) {
+	if cell := g.cells[k]; cell != nil {
+		delete(cell, id)
+		if len(cell) == 0 {
+			delete(g.cells, k)
+		}
+	}
+}
+
+func matchKind(k Kind, kinds []Kind) bool {
+	if len(kinds) == 0 {
+		return true
+	}
+	for _, want := range kinds {
+		if k == want {
+			return true
+		}
+	}
+	return false
+}
+
+func lessHit(a, b Hit) bool {
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	return bytes.Compare(a.ID[:], b.ID[:]) < 0
+}
+
+func sortHits(h []Hit) {
+	sort.Slice(h, func(i, j int) bool { return lessHit(h[i], h[j]) })
+}
+
+func intAbs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
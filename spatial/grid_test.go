@@ -0,0 +1,135 @@
+package spatial
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const benchMapSize = 50000.0
+
+func randomEntries(rng *rand.Rand, n int) []Entry {
+	out := make([]Entry, n)
+	for i := range out {
+		out[i] = Entry{ID: bson.NewObjectID(), Kind: KindStack, X: rng.Float64() * benchMapSize, Y: rng.Float64() * benchMapSize}
+	}
+	return out
+}
+
+// TestQueryRadiusMatchesBruteForce tests that grid radius queries return
+// exactly the entries a linear scan would.
+func TestQueryRadiusMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	g := NewGrid(0)
+	entries := randomEntries(rng, 2000)
+	for i := range entries {
+		if i%10 == 0 {
+			entries[i].Kind = KindSystem
+			entries[i].Radius = 300
+		}
+		g.Insert(entries[i])
+	}
+
+	for q := 0; q < 100; q++ {
+		x, y, r := rng.Float64()*benchMapSize, rng.Float64()*benchMapSize, rng.Float64()*3000
+		want := 0
+		for _, e := range entries {
+			if math.Hypot(e.X-x, e.Y-y) <= r+e.Radius {
+				want++
+			}
+		}
+		if got := len(g.QueryRadius(x, y, r)); got != want {
+			t.Fatalf("query %d: got %d hits, want %d", q, got, want)
+		}
+	}
+}
+
+// TestNearestMatchesBruteForce tests Nearest against a linear scan, including
+// after moves and removals.
+func TestNearestMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	g := NewGrid(250)
+	entries := randomEntries(rng, 1000)
+	for _, e := range entries {
+		g.Insert(e)
+	}
+	for i := 0; i < 200; i++ {
+		e := &entries[i]
+		e.X, e.Y = rng.Float64()*benchMapSize, rng.Float64()*benchMapSize
+		g.Move(e.ID, e.X, e.Y)
+	}
+	for i := 200; i < 300; i++ {
+		g.Remove(entries[i].ID)
+	}
+	live := append(append([]Entry{}, entries[:200]...), entries[300:]...)
+
+	for q := 0; q < 100; q++ {
+		x, y := rng.Float64()*benchMapSize, rng.Float64()*benchMapSize
+		best := math.MaxFloat64
+		for _, e := range live {
+			best = math.Min(best, math.Hypot(e.X-x, e.Y-y))
+		}
+		hit, ok := g.Nearest(x, y)
+		if !ok || hit.Distance != best {
+			t.Fatalf("query %d: got %v (ok=%v), want distance %v", q, hit.Distance, ok, best)
+		}
+	}
+}
+
+// TestIndexKeyedByMap tests that entries on different maps do not see each other.
+func TestIndexKeyedByMap(t *testing.T) {
+	ix := NewIndex(0)
+	a, b := bson.NewObjectID(), bson.NewObjectID()
+	ix.Grid(a).Insert(Entry{ID: bson.NewObjectID(), Kind: KindStack})
+	if hits := ix.QueryRadius(b, 0, 0, 100); len(hits) != 0 {
+		t.Fatalf("expected no hits on other map, got %d", len(hits))
+	}
+	if hits := ix.QueryRadius(a, 0, 0, 100, KindSystem); len(hits) != 0 {
+		t.Fatalf("kind filter leaked %d hits", len(hits))
+	}
+}
+
+func benchGrid(n int) (*Grid, []Entry, *rand.Rand) {
+	rng := rand.New(rand.NewSource(42))
+	g := NewGrid(0)
+	entries := randomEntries(rng, n)
+	for _, e := range entries {
+		g.Insert(e)
+	}
+	return g, entries, rng
+}
+
+func BenchmarkQueryRadius5000(b *testing.B) {
+	g, _, rng := benchGrid(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.QueryRadius(rng.Float64()*benchMapSize, rng.Float64()*benchMapSize, 1000)
+	}
+}
+
+func BenchmarkNearest5000(b *testing.B) {
+	g, _, rng := benchGrid(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.Nearest(rng.Float64()*benchMapSize, rng.Float64()*benchMapSize)
+	}
+}
+
+func BenchmarkMove5000(b *testing.B) {
+	g, entries, rng := benchGrid(5000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := entries[i%len(entries)]
+		g.Move(e.ID, rng.Float64()*benchMapSize, rng.Float64()*benchMapSize)
+	}
+}
+
+func BenchmarkQueryRadius20000(b *testing.B) {
+	g, _, rng := benchGrid(20000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.QueryRadius(rng.Float64()*benchMapSize, rng.Float64()*benchMapSize, 1000)
+	}
+}
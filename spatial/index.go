@@ -0,0 +1,84 @@
+package spatial
+
+import (
+	"github.com/nicoberrocal/galaxyCore/orbitables"
+	"github.com/nicoberrocal/galaxyCore/ships"
+	bson "go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Index holds one Grid per map.
+type Index struct {
+	cellSize float64
+	maps     map[bson.ObjectID]*Grid
+}
+
+// NewIndex creates an empty index. cellSize <= 0 uses DefaultCellSize.
+func NewIndex(cellSize float64) *Index {
+	return &Index{cellSize: cellSize, maps: make(map[bson.ObjectID]*Grid)}
+}
+
+// Grid returns the grid for a map, creating it on first use.
+func (ix *Index) Grid(mapID bson.ObjectID) *Grid {
+	g := ix.maps[mapID]
+	if g == nil {
+		g = NewGrid(ix.cellSize)
+		ix.maps[mapID] = g
+	}
+	return g
+}
+
+// DropMap forgets a map entirely (e.g. when a game ends).
+func (ix *Index) DropMap(mapID bson.ObjectID) {
+	delete(ix.maps, mapID)
+}
+
+// InsertStack indexes a stack at its current position.
+func (ix *Index) InsertStack(s *ships.ShipStack) {
+	ix.Grid(s.MapID).Insert(Entry{ID: s.ID, Kind: KindStack, X: s.PositionX, Y: s.PositionY})
+}
+
+// MoveStack updates a stack's position, inserting it if missing.
+func (ix *Index) MoveStack(s *ships.ShipStack) {
+	if !ix.Grid(s.MapID).Move(s.ID, s.PositionX, s.PositionY) {
+		ix.InsertStack(s)
+	}
+}
+
+// InsertSystem indexes a system with its CollisionRadius.
+func (ix *Index) InsertSystem(s *orbitables.System) {
+	ix.Grid(s.MapID).Insert(Entry{ID: s.ID, Kind: KindSystem, X: s.X, Y: s.Y, Radius: s.CollisionRadius})
+}
+
+// InsertAsteroid indexes an asteroid with its CollisionRadius.
+func (ix *Index) InsertAsteroid(a *orbitables.Asteroid) {
+	ix.Grid(a.MapID).Insert(Entry{ID: a.ID, Kind: KindAsteroid, X: a.X, Y: a.Y, Radius: a.CollisionRadius})
+}
+
+// InsertNebula indexes a nebula with its CollisionRadius.
+func (ix *Index) InsertNebula(n *orbitables.Nebula) {
+	ix.Grid(n.MapID).Insert(Entry{ID: n.ID, Kind: KindNebula, X: n.X, Y: n.Y, Radius: n.CollisionRadius})
+}
+
+// Remove deletes an entity from a map's grid.
+func (ix *Index) Remove(mapID, id bson.ObjectID) bool {
+	if g := ix.maps[mapID]; g != nil {
+		return g.Remove(id)
+	}
+	return false
+}
+
+// QueryRadius runs a radius query on a map.
+func (ix *Index) QueryRadius(mapID bson.ObjectID, x, y, r float64, kinds ...Kind) []Hit {
+	if g := ix.maps[mapID]; g != nil {
+		return g.QueryRadius(x, y, r, kinds...)
+	}
+	return nil
+}
+
+// Nearest runs a nearest-entity query on a map.
+func (ix *Index) Nearest(mapID bson.ObjectID, x, y float64, kinds ...Kind) (Hit, bool) {
+	if g := ix.maps[mapID]; g != nil {
+		return g.Nearest(x, y, kinds...)
+	}
+	return Hit{}, false
+}